@@ -0,0 +1,85 @@
+package lookup
+
+import (
+	"context"
+	"reflect"
+	"sort"
+)
+
+// PathMatch is a value found by LookupAll, with its concrete lookup path.
+type PathMatch struct {
+	// The path of the value, with the keys of the lookup path, and the index
+	// or the key of each element aggregated over, e.g.
+	// "StructSlice[1].StructSlice[0].String" for "StructSlice.StructSlice.String".
+	Path  string
+	Value interface{}
+}
+
+// LookupAll performs a lookup like Lookup, but returns the values the
+// aggregations fan out to separately, with the path each of them was found
+// at, instead of merging them. The elements of slices are in order, and the
+// values of maps in the order of their keys. A lookup which doesn't
+// aggregate returns a single match. Options.KeepMapKeys and PreservePositions,
+// which shape the merged value, and Parallelism are ignored.
+func LookupAll(i interface{}, path string, options ...Option) ([]PathMatch, error) {
+	opts := NewOptions(options...)
+	opts.KeepMapKeys, opts.PreservePositions, opts.Parallelism = false, false, 0
+
+	var matches []PathMatch
+	t := newTraversal(context.Background(), opts)
+	t.matches = &matches
+	if _, err := t.lookup(reflect.ValueOf(i), splitPath(path, &opts), nil, 0); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// addMatch records v, found at the end of path, for LookupAll.
+func (t *traversal) addMatch(prefix, path []string, v reflect.Value) {
+	*t.matches = append(*t.matches, PathMatch{
+		Path:  joinSegments(joinPath(prefix, path), t.opts),
+		Value: resultInterface(v, &t.opts),
+	})
+}
+
+// aggregateMatches looks up path on every element of v for LookupAll, with the
+// index or the key of the element appended to prefix.
+func (t *traversal) aggregateMatches(v reflect.Value, path, prefix []string, depth int) error {
+	// The element addressed replaces the wildcard of key[*], the key filter,
+	// or the index ignored by getSegment.
+	if n := len(prefix); n > 0 {
+		if _, index, err := parseIndex(prefix[n-1]); err == nil && index != noIndex {
+			_, suffix := splitIndex(prefix[n-1])
+			last := prefix[n-1][:len(prefix[n-1])-len(suffix)]
+			prefix = append(prefix[:n-1:n-1], last)
+		}
+	}
+
+	if v.Kind() == reflect.Map {
+		keys := v.MapKeys()
+		names := make([][]string, len(keys))
+		for i, k := range keys {
+			names[i] = []string{keyName(k)}
+		}
+		sort.Sort(byNames{keys, names})
+		for i, k := range keys {
+			if err := t.checkContext(); err != nil {
+				return err
+			}
+			if _, err := t.lookup(elemValue(v.MapIndex(k)), path, append(prefix[:len(prefix):len(prefix)], names[i][0]), depth); err != nil && !t.skipsElement(v, v.MapIndex(k), path, err) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		if err := t.checkContext(); err != nil {
+			return err
+		}
+		if _, err := t.lookup(elemValue(v.Index(i)), path, indexPath(prefix, i), depth); err != nil && !t.skipsElement(v, v.Index(i), path, err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package lookup
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestLookupAll(c *C) {
+	matches, err := LookupAll(structFixture, "StructSlice.StructSlice.String")
+	c.Assert(err, IsNil)
+	c.Assert(matches, DeepEquals, []PathMatch{
+		{Path: "StructSlice[0].StructSlice[0].String", Value: "bar"},
+		{Path: "StructSlice[0].StructSlice[1].String", Value: "foo"},
+		{Path: "StructSlice[1].StructSlice[0].String", Value: "qux"},
+		{Path: "StructSlice[1].StructSlice[1].String", Value: "baz"},
+	})
+
+	matches, err = LookupAll(structFixture, "StructSlice[*].Map")
+	c.Assert(err, IsNil)
+	c.Assert(matches, DeepEquals, []PathMatch{
+		{Path: "StructSlice[0].Map", Value: mapFixture},
+		{Path: "StructSlice[1].Map", Value: mapFixture},
+	})
+
+	matches, err = LookupAll(structFixture, "StructSlice[1].StructSlice[*]")
+	c.Assert(err, IsNil)
+	c.Assert(matches, DeepEquals, []PathMatch{
+		{Path: "StructSlice[1].StructSlice[0]", Value: structFixture.StructSlice[1].StructSlice[0]},
+		{Path: "StructSlice[1].StructSlice[1]", Value: structFixture.StructSlice[1].StructSlice[1]},
+	})
+
+	// Without aggregation.
+	matches, err = LookupAll(structFixture, "StructSlice", WithSplitToken("/"))
+	c.Assert(err, IsNil)
+	c.Assert(matches, DeepEquals, []PathMatch{{Path: "StructSlice", Value: structFixture.StructSlice}})
+
+	_, err = LookupAll(structFixture, "StructSlice.Missing")
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+}
+
+func (s *S) TestLookupAll_Maps(c *C) {
+	i := map[string]interface{}{
+		"users": map[string]interface{}{
+			"bob":   map[string]interface{}{"age": 42},
+			"alice": map[string]interface{}{"age": 31},
+		},
+	}
+	matches, err := LookupAll(i, "users.age")
+	c.Assert(err, IsNil)
+	c.Assert(matches, DeepEquals, []PathMatch{
+		{Path: "users.alice.age", Value: 31},
+		{Path: "users.bob.age", Value: 42},
+	})
+
+	matches, err = LookupAll(i, "users[*]/age", KeepMapKeys(), WithSplitToken("/"))
+	c.Assert(err, IsNil)
+	c.Assert(matches, DeepEquals, []PathMatch{
+		{Path: "users/alice/age", Value: 31},
+		{Path: "users/bob/age", Value: 42},
+	})
+
+	// The paths can be looked up again.
+	for _, m := range matches {
+		value, err := Lookup(i, m.Path, WithSplitToken("/"))
+		c.Assert(err, IsNil)
+		c.Assert(value, Equals, m.Value)
+	}
+}
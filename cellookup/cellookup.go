@@ -0,0 +1,65 @@
+/*
+Package cellookup resolves the names of CEL expressions as lookup paths, so
+the expressions can be evaluated on any Go value, e.g. "spec.replicas > 1" on
+a struct. It's kept apart so the users of lookup don't depend on cel-go.
+*/
+package cellookup
+
+import (
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/interpreter"
+	lookup "github.com/kevinxw/go-lookup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Activation is a CEL interpreter.Activation resolving the names of CEL
+// expressions as paths looked up on a value, e.g. "spec.replicas".
+//
+// CEL resolves the qualified names of parsed expressions, and of checked ones
+// declaring them, as a whole, so the path semantics of Lookup and its options
+// apply to all their segments. Otherwise CEL only resolves the variable and
+// selects its fields itself, which only works on maps, lists and messages.
+type Activation struct {
+	value  interface{}
+	opts   lookup.Options
+	parent interpreter.Activation
+}
+
+// NewActivation returns an Activation resolving names on i with options. The
+// names are always split on ".", whatever Options.SplitToken and Options.NoSplit.
+func NewActivation(i interface{}, options ...lookup.Option) *Activation {
+	opts := lookup.NewOptions(options...)
+	opts.SplitToken = "."
+	opts.NoSplit = false
+	return &Activation{value: i, opts: opts}
+}
+
+// WithParent returns a copy of a falling back to parent for the names it
+// doesn't resolve.
+func (a *Activation) WithParent(parent interpreter.Activation) *Activation {
+	out := *a
+	out.parent = parent
+	return &out
+}
+
+// ResolveName looks name up. A name which isn't found is resolved by the
+// parent, if any, and other lookup errors resolve to a CEL error.
+func (a *Activation) ResolveName(name string) (interface{}, bool) {
+	value, err := lookup.Lookup(a.value, name, a.opts)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return types.NewErr("%s", err), true
+	}
+	if err == nil && value != nil {
+		return value, true
+	}
+	if a.parent != nil {
+		return a.parent.ResolveName(name)
+	}
+	return nil, false
+}
+
+// Parent returns the parent set with WithParent, if any.
+func (a *Activation) Parent() interpreter.Activation {
+	return a.parent
+}
@@ -0,0 +1,54 @@
+package cellookup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/interpreter"
+	lookup "github.com/kevinxw/go-lookup"
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) { TestingT(t) }
+
+type S struct{}
+
+var _ = Suite(&S{})
+
+type Fixture struct {
+	Map        map[string]int
+	JSONString string
+}
+
+var fixture = Fixture{
+	Map:        map[string]int{"foo": 42},
+	JSONString: `{"String": "Abc", "Struct": {"Substring": "Abcd"}}`,
+}
+
+func (s *S) TestActivation(c *C) {
+	env, err := cel.NewEnv()
+	c.Assert(err, IsNil)
+	ast, iss := env.Parse(`map.foo == 42 && jsonString.struct.substring == "Abcd" && limit == 3`)
+	c.Assert(iss.Err(), IsNil)
+	prg, err := env.Program(ast)
+	c.Assert(err, IsNil)
+
+	parent, err := interpreter.NewActivation(map[string]interface{}{"limit": 3})
+	c.Assert(err, IsNil)
+	activation := NewActivation(fixture, lookup.Options{
+		MatchFunctions:     []lookup.MatchFunc{strings.ToLower},
+		ExpandStringAsJSON: true,
+	}).WithParent(parent)
+	out, _, err := prg.Eval(activation)
+	c.Assert(err, IsNil)
+	c.Assert(out.Value(), Equals, true)
+
+	ast, iss = env.Parse(`missing == 1`)
+	c.Assert(iss.Err(), IsNil)
+	prg, err = env.Program(ast)
+	c.Assert(err, IsNil)
+	_, _, err = prg.Eval(activation)
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readDocument reads and decodes the document of file, or of stdin if file is
+// "-", in format: "json", "yaml", or "auto" to guess it from the extension of
+// file, or else from the document, which is decoded as YAML if it isn't JSON.
+func readDocument(file, format string, stdin io.Reader) (interface{}, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = ioutil.ReadAll(stdin)
+	} else {
+		data, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "auto" {
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".json":
+			format = "json"
+		case ".yaml", ".yml":
+			format = "yaml"
+		}
+	}
+	switch format {
+	case "json":
+		return decodeJSON(data)
+	case "yaml":
+		return decodeYAML(data)
+	case "auto":
+		if doc, err := decodeJSON(data); err == nil {
+			return doc, nil
+		}
+		return decodeYAML(data)
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// decodeJSON decodes the JSON document data, keeping its numbers as
+// json.Number so they're printed as they are.
+func decodeJSON(data []byte) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var doc interface{}
+	if err := d.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON document: %s", err)
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid JSON document: invalid data after the document")
+	}
+	return doc, nil
+}
+
+// decodeYAML decodes the YAML document data.
+func decodeYAML(data []byte) (interface{}, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid YAML document: %s", err)
+	}
+	return doc, nil
+}
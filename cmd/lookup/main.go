@@ -0,0 +1,184 @@
+// Command lookup evaluates lookup paths on a JSON or YAML document, read from
+// a file or the standard input, and prints the value found for each of them,
+// by default as JSON, one per line:
+//
+//	kubectl get pod mypod -o json | lookup spec.containers.image
+//	lookup -f config.yaml -case-insensitive server.port server.host
+//
+// The -o flag sets the output format: json, yaml, raw, tsv or go, see
+// writeValue. raw and tsv write strings as they are and the elements of the
+// slices, e.g. aggregated, separately, for use in shell pipelines:
+//
+//	lookup -f pods.json -o raw items.metadata.name | xargs -n1 kubectl logs
+//
+// With -watch, the paths are evaluated again each time the file changes, and
+// the changes of their values are printed, see watcher.
+//
+// With -i, the paths are typed in a prompt, with tab completion of the keys,
+// to explore the document and find the paths to use, see repl:
+//
+//	lookup -i data.json
+//
+// The flags set the lookup.Options the paths are evaluated with, which makes
+// it a playground to learn the syntax of the paths: -trace prints how each
+// segment of a path is resolved.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	lookup "github.com/kevinxw/go-lookup"
+	"google.golang.org/grpc/status"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command with the arguments args, and returns its exit code: 2
+// for invalid arguments, 1 if the document can't be read or a path fails.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lookup", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: lookup [flags] path...\n       lookup -i [flags] [file]")
+		flags.PrintDefaults()
+	}
+	file := flags.String("f", "-", "file to read the document from; - for the standard input")
+	output := flags.String("o", "json", "output format: json, yaml, raw, tsv or go")
+	format := flags.String("format", "auto", "format of the document: json, yaml, or auto to guess it from the file extension or content")
+	caseInsensitive := flags.Bool("case-insensitive", false, "match keys case-insensitively")
+	split := flags.String("split", "", "token separating the keys of the paths; default .")
+	tag := flags.String("tag", "", "struct tag key naming the fields, see Options.TagKey")
+	expand := flags.Bool("expand", false, "expand strings holding JSON documents")
+	strict := flags.Bool("strict", false, "require key[*] to aggregate")
+	trace := flags.Bool("trace", false, "print how each segment of the paths is resolved to the standard error")
+	watch := flags.Bool("watch", false, "evaluate the paths again each time the file changes, and print the changes of their values")
+	interactive := flags.Bool("i", false, "evaluate the paths typed in a prompt, with tab completion of the keys")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *interactive {
+		if flags.NArg() > 1 || (flags.NArg() == 1 && *file != "-") {
+			flags.Usage()
+			return 2
+		}
+		if flags.NArg() == 1 {
+			*file = flags.Arg(0)
+		}
+		if *file == "-" || *watch {
+			fmt.Fprintln(stderr, "lookup: -i needs a file, and can't be used with -watch")
+			return 2
+		}
+	} else if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	if !formats[*output] {
+		fmt.Fprintf(stderr, "lookup: unknown output format %q\n", *output)
+		return 2
+	}
+	if *watch && *file == "-" {
+		fmt.Fprintln(stderr, "lookup: -watch needs a file")
+		return 2
+	}
+
+	var options []lookup.Option
+	if *caseInsensitive {
+		options = append(options, lookup.CaseInsensitive())
+	}
+	if *split != "" {
+		options = append(options, lookup.WithSplitToken(*split))
+	}
+	if *tag != "" {
+		options = append(options, lookup.WithTagKey(*tag))
+	}
+	if *expand {
+		options = append(options, lookup.ExpandJSON())
+	}
+	if *strict {
+		options = append(options, lookup.Strict())
+	}
+
+	doc, err := readDocument(*file, *format, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "lookup: %s\n", err)
+		return 1
+	}
+
+	l := lookup.NewLookuper(options...)
+	eval := func(doc interface{}, path string) (interface{}, error) {
+		if !*trace {
+			return l.Lookup(doc, path)
+		}
+		value, events, err := lookup.LookupTrace(doc, path, options...)
+		for _, e := range events {
+			fmt.Fprintf(stderr, "%s: %s\n", path, e)
+		}
+		return value, err
+	}
+
+	if *interactive {
+		r := &repl{
+			doc:             doc,
+			eval:            eval,
+			lookup:          l.Lookup,
+			output:          *output,
+			sep:             *split,
+			caseInsensitive: *caseInsensitive,
+			stdout:          stdout,
+			stderr:          stderr,
+		}
+		if err := r.run(); err != nil {
+			fmt.Fprintf(stderr, "lookup: %s\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	code, n := 0, 0
+	results := make([]result, flags.NArg())
+	for i, path := range flags.Args() {
+		value, err := eval(doc, path)
+		results[i] = newResult(value, err)
+		if err == nil {
+			err = writeValue(stdout, *output, value, n)
+			n++
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "lookup: %s: %s\n", path, errorMessage(err))
+			code = 1
+		}
+	}
+	if !*watch {
+		return code
+	}
+
+	w := &watcher{
+		file:    *file,
+		format:  *format,
+		paths:   flags.Args(),
+		eval:    eval,
+		options: options,
+		results: results,
+		stdout:  stdout,
+		stderr:  stderr,
+	}
+	if err := w.watch(nil); err != nil {
+		fmt.Fprintf(stderr, "lookup: watching %s: %s\n", *file, err)
+		return 1
+	}
+	return 0
+}
+
+// errorMessage returns the message of err, without its code if it's a status
+// error.
+func errorMessage(err error) string {
+	if s, ok := status.FromError(err); ok {
+		return s.Message()
+	}
+	return err.Error()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) { TestingT(t) }
+
+type S struct{}
+
+var _ = Suite(&S{})
+
+// runCommand runs the command with args on the document doc read from the
+// standard input, and returns its exit code and outputs.
+func runCommand(doc string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(doc), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+const jsonDoc = `{"server": {"Host": "localhost", "ports": [80, 443]}, "raw": "{\"a\": 1}"}`
+
+func (s *S) TestRun(c *C) {
+	code, stdout, stderr := runCommand(jsonDoc, "server.Host", "server.ports[1]", "server")
+	c.Assert(code, Equals, 0)
+	c.Assert(stderr, Equals, "")
+	c.Assert(stdout, Equals, "\"localhost\"\n443\n{\"Host\":\"localhost\",\"ports\":[80,443]}\n")
+
+	code, stdout, _ = runCommand(jsonDoc, "-case-insensitive", "-split", "/", "SERVER/host")
+	c.Assert(code, Equals, 0)
+	c.Assert(stdout, Equals, "\"localhost\"\n")
+
+	code, stdout, _ = runCommand(jsonDoc, "-expand", "raw.a")
+	c.Assert(code, Equals, 0)
+	c.Assert(stdout, Equals, "1\n")
+}
+
+func (s *S) TestRun_Errors(c *C) {
+	code, stdout, stderr := runCommand(jsonDoc, "server.missing", "server.Host")
+	c.Assert(code, Equals, 1)
+	c.Assert(stdout, Equals, "\"localhost\"\n")
+	c.Assert(stderr, Equals, "lookup: server.missing: key \"missing\" not found\n")
+
+	code, _, stderr = runCommand(jsonDoc, "-strict", "server.ports.foo")
+	c.Assert(code, Equals, 1)
+	c.Assert(stderr, Matches, "lookup: server.ports.foo: .*use foo\\[\\*\\] to aggregate.*\n")
+
+	code, _, _ = runCommand(jsonDoc)
+	c.Assert(code, Equals, 2)
+
+	code, _, stderr = runCommand("{", "-format", "json", "a")
+	c.Assert(code, Equals, 1)
+	c.Assert(stderr, Matches, "lookup: invalid JSON document: .*\n")
+}
+
+func (s *S) TestRun_YAML(c *C) {
+	doc := "server:\n  host: localhost\n  ports: [80, 443]\n"
+	code, stdout, _ := runCommand(doc, "server.ports")
+	c.Assert(code, Equals, 0)
+	c.Assert(stdout, Equals, "[80,443]\n")
+
+	file := filepath.Join(c.MkDir(), "config.yml")
+	c.Assert(ioutil.WriteFile(file, []byte(doc), 0644), IsNil)
+	code, stdout, _ = runCommand("", "-f", file, "server.host")
+	c.Assert(code, Equals, 0)
+	c.Assert(stdout, Equals, "\"localhost\"\n")
+}
+
+func (s *S) TestRun_Trace(c *C) {
+	code, stdout, stderr := runCommand(jsonDoc, "-trace", "-case-insensitive", "server.host")
+	c.Assert(code, Equals, 0)
+	c.Assert(stdout, Equals, "\"localhost\"\n")
+	c.Assert(stderr, Equals, "server.host: after server on \"\" (map): matched \"server\"\n"+
+		"server.host: after host on \"server\" (string): matched \"Host\"\n")
+}
+
+func (s *S) TestRun_OutputFormats(c *C) {
+	doc := `{"items": [{"name": "a\tb", "n": 1}, {"name": "c", "n": 2.5}], "meta": {"ids": [1, 2]}}`
+	paths := []string{"items.name", "items.n", "meta"}
+	for _, t := range []struct {
+		format string
+		output string
+	}{
+		{"json", "[\"a\\tb\",\"c\"]\n[1,2.5]\n{\"ids\":[1,2]}\n"},
+		{"yaml", "- \"a\\tb\"\n- c\n---\n- 1\n- 2.5\n---\nids:\n    - 1\n    - 2\n"},
+		{"raw", "a\tb\nc\n1\n2.5\n{\"ids\":[1,2]}\n"},
+		{"tsv", "a\\tb\tc\n1\t2.5\n{\"ids\":[1,2]}\n"},
+		{"go", "[]interface {}{\"a\\tb\", \"c\"}\n[]interface {}{1, 2.5}\nmap[string]interface {}{\"ids\":[]interface {}{1, 2}}\n"},
+	} {
+		code, stdout, stderr := runCommand(doc, append([]string{"-o", t.format}, paths...)...)
+		c.Assert(code, Equals, 0, Commentf("format %s", t.format))
+		c.Assert(stderr, Equals, "")
+		c.Assert(stdout, Equals, t.output, Commentf("format %s", t.format))
+	}
+
+	code, stdout, _ := runCommand(doc, "-o", "raw", "items[1].name")
+	c.Assert(code, Equals, 0)
+	c.Assert(stdout, Equals, "c\n")
+
+	code, _, stderr := runCommand(doc, "-o", "xml", "meta")
+	c.Assert(code, Equals, 2)
+	c.Assert(stderr, Equals, "lookup: unknown output format \"xml\"\n")
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// formats are the output formats of the -o flag.
+var formats = map[string]bool{"json": true, "yaml": true, "raw": true, "tsv": true, "go": true}
+
+// writeValue writes v, the value found for a path, to w in format:
+//
+//   - json: as JSON, on its own line.
+//   - yaml: as a YAML document, each value after the first preceded by "---".
+//   - raw: strings as they are and other scalars as JSON, on their own line,
+//     and the elements of a slice, e.g. aggregated, each on its own line.
+//   - tsv: on its own line, the elements of a slice, or the scalar v, as
+//     fields separated by tabs, with tabs, newlines and backslashes escaped.
+//   - go: as a Go value literal, on its own line.
+//
+// Maps and nested slices are written as JSON by raw and tsv. n is the number
+// of values written before.
+func writeValue(w io.Writer, format string, v interface{}, n int) error {
+	switch format {
+	case "yaml":
+		data, err := yaml.Marshal(normalize(v, true))
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+		_, err = w.Write(data)
+		return err
+
+	case "raw":
+		elems := elements(v)
+		for _, e := range elems {
+			s, err := rawString(e)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(w, s); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "tsv":
+		elems := elements(v)
+		fields := make([]string, len(elems))
+		for i, e := range elems {
+			s, err := rawString(e)
+			if err != nil {
+				return err
+			}
+			fields[i] = tsvEscaper.Replace(s)
+		}
+		_, err := fmt.Fprintln(w, strings.Join(fields, "\t"))
+		return err
+
+	case "go":
+		_, err := fmt.Fprintf(w, "%#v\n", normalize(v, true))
+		return err
+	}
+
+	data, err := json.Marshal(normalize(v, false))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// rawString returns v as it is if it's a string, and as JSON otherwise.
+func rawString(v interface{}) (string, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(normalize(v, false))
+	return string(data), err
+}
+
+// elements returns the elements of v if it's a slice, e.g. aggregated, other
+// than []byte, and v alone otherwise.
+func elements(v interface{}) []interface{} {
+	if v, ok := normalize(v, false).([]interface{}); ok {
+		return v
+	}
+	return []interface{}{v}
+}
+
+// normalize returns v with its slices, e.g. aggregated into typed slices, as
+// []interface{} and its maps as map[string]interface{}, with their keys
+// formatted by fmt, e.g. those decoded from YAML, so they're written in the
+// same way whatever their type. If numbers is true, the json.Number are
+// converted to int64 or float64, so they're written as numbers rather than
+// strings.
+func normalize(v interface{}, numbers bool) interface{} {
+	if n, ok := v.(json.Number); ok {
+		if !numbers {
+			return n
+		}
+		if i, err := n.Int64(); err == nil {
+			return i
+		}
+		if f, err := n.Float64(); err == nil {
+			return f
+		}
+		return n.String()
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return v
+		}
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			out[i] = normalize(rv.Index(i).Interface(), numbers)
+		}
+		return out
+	case reflect.Map:
+		out := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			out[fmt.Sprint(iter.Key().Interface())] = normalize(iter.Value().Interface(), numbers)
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/peterh/liner"
+)
+
+// repl evaluates the paths typed in a prompt on a document, and prints their
+// values. Tab completes the key being typed with the keys of the value it's
+// looked up on, or, after "[", with the indexes of the slice, so the document
+// can be explored to find the paths to use. An empty line is ignored, and
+// Ctrl-C or Ctrl-D quits.
+type repl struct {
+	doc    interface{}
+	eval   func(doc interface{}, path string) (interface{}, error)
+	lookup func(doc interface{}, path string) (interface{}, error)
+	output string
+	// The split token, "." if empty.
+	sep             string
+	caseInsensitive bool
+	stdout          io.Writer
+	stderr          io.Writer
+	// The number of values written.
+	n int
+}
+
+// run runs the prompt on the terminal until it's quit.
+func (r *repl) run() error {
+	line := liner.NewLiner()
+	defer line.Close()
+	line.SetCtrlCAborts(true)
+	line.SetCompleter(r.complete)
+
+	return r.loop(func() (string, error) {
+		s, err := line.Prompt("> ")
+		if err == nil && strings.TrimSpace(s) != "" {
+			line.AppendHistory(s)
+		}
+		return s, err
+	})
+}
+
+// loop evaluates the lines returned by prompt until it fails. io.EOF and
+// liner.ErrPromptAborted, returned when the prompt is quit, end it without
+// error.
+func (r *repl) loop(prompt func() (string, error)) error {
+	for {
+		s, err := prompt()
+		if err == io.EOF || errors.Is(err, liner.ErrPromptAborted) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		path := strings.TrimSpace(s)
+		if path == "" {
+			continue
+		}
+		value, err := r.eval(r.doc, path)
+		if err == nil {
+			err = writeValue(r.stdout, r.output, value, r.n)
+			r.n++
+		}
+		if err != nil {
+			fmt.Fprintf(r.stderr, "lookup: %s: %s\n", path, errorMessage(err))
+		}
+	}
+}
+
+// complete returns the completions of line, the path being typed: line
+// completed with each key of the value its last key is looked up on, or,
+// after "[", with each index of the slice and "*". The keys of a slice are
+// those of its elements, as they're aggregated. Nothing is returned if the
+// value can't be looked up.
+func (r *repl) complete(line string) []string {
+	sep := r.sep
+	if sep == "" {
+		sep = "."
+	}
+	parent, key := "", line
+	if i := strings.LastIndex(line, sep); i != -1 {
+		parent, key = line[:i], line[i+len(sep):]
+	}
+
+	var candidates []string
+	if i := strings.LastIndex(key, "["); i != -1 {
+		v, ok := r.value(join(parent, key[:i], sep))
+		if !ok {
+			return nil
+		}
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return nil
+		}
+		candidates = append(candidates, "[*]")
+		for j := 0; j < rv.Len(); j++ {
+			candidates = append(candidates, "["+strconv.Itoa(j)+"]")
+		}
+		parent, key = join(parent, key[:i], sep), key[i:]
+		sep = ""
+	} else {
+		v, ok := r.value(parent)
+		if !ok {
+			return nil
+		}
+		candidates = keys(v)
+	}
+
+	var completions []string
+	for _, c := range candidates {
+		if hasPrefix(c, key, r.caseInsensitive) {
+			completions = append(completions, join(parent, c, sep))
+		}
+	}
+	return completions
+}
+
+// value returns the value of path on the document, the document itself if
+// path is empty.
+func (r *repl) value(path string) (interface{}, bool) {
+	if path == "" {
+		return r.doc, true
+	}
+	v, err := r.lookup(r.doc, path)
+	return v, err == nil
+}
+
+// keys returns the keys of v, sorted, if it's a map, or the keys of its
+// elements if it's a slice.
+func keys(v interface{}) []string {
+	rv := reflect.ValueOf(v)
+	seen := make(map[string]bool)
+	var out []string
+	add := func(m reflect.Value) {
+		for m.Kind() == reflect.Interface || m.Kind() == reflect.Ptr {
+			m = m.Elem()
+		}
+		if m.Kind() != reflect.Map {
+			return
+		}
+		for _, k := range m.MapKeys() {
+			if s := fmt.Sprint(k.Interface()); !seen[s] {
+				seen[s] = true
+				out = append(out, s)
+			}
+		}
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		add(rv)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			add(rv.Index(i))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// join returns the path of key on the value of parent.
+func join(parent, key, sep string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + sep + key
+}
+
+func hasPrefix(s, prefix string, caseInsensitive bool) bool {
+	if caseInsensitive {
+		return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+	}
+	return strings.HasPrefix(s, prefix)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"io"
+
+	lookup "github.com/kevinxw/go-lookup"
+	. "gopkg.in/check.v1"
+)
+
+func newTestREPL(c *C, caseInsensitive bool) (*repl, *bytes.Buffer, *bytes.Buffer) {
+	doc, err := decodeJSON([]byte(`{"server": {"host": "localhost", "Port": 80}, "items": [{"name": "a"}, {"name": "b", "tags": []}]}`))
+	c.Assert(err, IsNil)
+	var options []lookup.Option
+	if caseInsensitive {
+		options = append(options, lookup.CaseInsensitive())
+	}
+	l := lookup.NewLookuper(options...)
+	var stdout, stderr bytes.Buffer
+	return &repl{
+		doc:             doc,
+		eval:            l.Lookup,
+		lookup:          l.Lookup,
+		output:          "json",
+		caseInsensitive: caseInsensitive,
+		stdout:          &stdout,
+		stderr:          &stderr,
+	}, &stdout, &stderr
+}
+
+func (s *S) TestREPL_Complete(c *C) {
+	r, _, _ := newTestREPL(c, false)
+	c.Assert(r.complete(""), DeepEquals, []string{"items", "server"})
+	c.Assert(r.complete("se"), DeepEquals, []string{"server"})
+	c.Assert(r.complete("server."), DeepEquals, []string{"server.Port", "server.host"})
+	c.Assert(r.complete("server.h"), DeepEquals, []string{"server.host"})
+	c.Assert(r.complete("server.p"), IsNil)
+	c.Assert(r.complete("items."), DeepEquals, []string{"items.name", "items.tags"})
+	c.Assert(r.complete("items["), DeepEquals, []string{"items[*]", "items[0]", "items[1]"})
+	c.Assert(r.complete("items[1"), DeepEquals, []string{"items[1]"})
+	c.Assert(r.complete("items[1]."), DeepEquals, []string{"items[1].name", "items[1].tags"})
+	c.Assert(r.complete("server.host."), IsNil)
+	c.Assert(r.complete("missing."), IsNil)
+
+	r, _, _ = newTestREPL(c, true)
+	c.Assert(r.complete("SERVER.p"), DeepEquals, []string{"SERVER.Port"})
+
+	r.sep = "/"
+	c.Assert(r.complete("server/"), DeepEquals, []string{"server/Port", "server/host"})
+}
+
+func (s *S) TestREPL_Loop(c *C) {
+	r, stdout, stderr := newTestREPL(c, false)
+	lines := []string{"server.host", "", "  items.name ", "server.missing"}
+	err := r.loop(func() (string, error) {
+		if len(lines) == 0 {
+			return "", io.EOF
+		}
+		line := lines[0]
+		lines = lines[1:]
+		return line, nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(stdout.String(), Equals, "\"localhost\"\n[\"a\",\"b\"]\n")
+	c.Assert(stderr.String(), Equals, "lookup: server.missing: key \"missing\" not found\n")
+}
+
+func (s *S) TestRun_InteractiveNeedsFile(c *C) {
+	code, _, stderr := runCommand(jsonDoc, "-i")
+	c.Assert(code, Equals, 2)
+	c.Assert(stderr, Equals, "lookup: -i needs a file, and can't be used with -watch\n")
+
+	code, _, _ = runCommand(jsonDoc, "-i", "a.json", "b.json")
+	c.Assert(code, Equals, 2)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	lookup "github.com/kevinxw/go-lookup"
+)
+
+// result is the value found for a path, or the message of the error it
+// failed with.
+type result struct {
+	value interface{}
+	err   string
+}
+
+func newResult(value interface{}, err error) result {
+	if err != nil {
+		return result{err: errorMessage(err)}
+	}
+	return result{value: value}
+}
+
+// watcher evaluates paths on the document of file each time it's written or
+// replaced, and prints the changes of their values since the previous
+// evaluation, one per line:
+//
+//	~ server.port: 80 -> 8080
+//	+ server.hosts[2]: "c"
+//	- server.hosts[1]: "b"
+//	! server.tls: key "tls" not found
+//
+// The changes are those of the leaves of the values, see lookup.Diff, written
+// as JSON. A path failing, or no longer failing, is printed with its error or
+// its value.
+type watcher struct {
+	file    string
+	format  string
+	paths   []string
+	eval    func(doc interface{}, path string) (interface{}, error)
+	options []lookup.Option
+	// The results of the previous evaluation, by path.
+	results []result
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+// watch watches the file until stop is closed, or the watch fails. The
+// directory of the file is watched rather than the file, so the file is still
+// watched after being replaced, as editors do when saving.
+func (w *watcher) watch(stop <-chan struct{}) error {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fw.Close()
+	if err := fw.Add(filepath.Dir(w.file)); err != nil {
+		return err
+	}
+
+	name := filepath.Clean(w.file)
+	for {
+		select {
+		case <-stop:
+			return nil
+		case err, ok := <-fw.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		case e, ok := <-fw.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(e.Name) == name && e.Has(fsnotify.Write|fsnotify.Create) {
+				w.update()
+			}
+		}
+	}
+}
+
+// update evaluates the paths on the document of the file, and prints the
+// changes of their values. An empty file, e.g. truncated to be written, is
+// skipped, and a document which can't be read is reported and skipped.
+func (w *watcher) update() {
+	if fi, err := os.Stat(w.file); err == nil && fi.Size() == 0 {
+		return
+	}
+	doc, err := readDocument(w.file, w.format, nil)
+	if err != nil {
+		fmt.Fprintf(w.stderr, "lookup: %s\n", err)
+		return
+	}
+	for i, path := range w.paths {
+		r := newResult(w.eval(doc, path))
+		if err := w.writeChanges(path, w.results[i], r); err != nil {
+			fmt.Fprintf(w.stderr, "lookup: %s: %s\n", path, err)
+		}
+		w.results[i] = r
+	}
+}
+
+// writeChanges prints the changes from old to r, the results of path.
+func (w *watcher) writeChanges(path string, old, r result) error {
+	switch {
+	case r.err != "":
+		if r.err != old.err {
+			_, err := fmt.Fprintf(w.stdout, "! %s: %s\n", path, r.err)
+			return err
+		}
+		return nil
+	case old.err != "":
+		return writeChange(w.stdout, "+", path, r.value)
+	}
+
+	// The values are wrapped, so they are leaves themselves if they're
+	// scalars, and the paths of the changes are "v" followed by the paths of
+	// the leaves in the values.
+	changes, err := lookup.Diff(map[string]interface{}{"v": old.value}, map[string]interface{}{"v": r.value}, w.options...)
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		p := path + strings.TrimPrefix(c.Path, "v")
+		switch c.Type {
+		case lookup.Added:
+			err = writeChange(w.stdout, "+", p, c.New)
+		case lookup.Removed:
+			err = writeChange(w.stdout, "-", p, c.Old)
+		default:
+			err = writeChange(w.stdout, "~", p, c.Old, c.New)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeChange prints the change of path, marked by mark, from the first to
+// the second of values, or to the only one.
+func writeChange(out io.Writer, mark, path string, values ...interface{}) error {
+	s := make([]string, len(values))
+	for i, v := range values {
+		data, err := json.Marshal(normalize(v, false))
+		if err != nil {
+			return err
+		}
+		s[i] = string(data)
+	}
+	_, err := fmt.Fprintf(out, "%s %s: %s\n", mark, path, strings.Join(s, " -> "))
+	return err
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	lookup "github.com/kevinxw/go-lookup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func (s *S) TestWatcher_WriteChanges(c *C) {
+	var stdout bytes.Buffer
+	w := &watcher{stdout: &stdout}
+	old := result{value: map[string]interface{}{"port": 80, "hosts": []interface{}{"a", "b"}}}
+	r := result{value: map[string]interface{}{"port": 8080, "hosts": []interface{}{"a"}, "tls": true}}
+	c.Assert(w.writeChanges("server", old, r), IsNil)
+	c.Assert(stdout.String(), Equals, "- server.hosts[1]: \"b\"\n~ server.port: 80 -> 8080\n+ server.tls: true\n")
+
+	stdout.Reset()
+	c.Assert(w.writeChanges("server.port", result{value: 80}, result{err: `key "port" not found`}), IsNil)
+	c.Assert(w.writeChanges("server.port", result{err: `key "port" not found`}, result{err: `key "port" not found`}), IsNil)
+	c.Assert(w.writeChanges("server.port", result{err: `key "port" not found`}, result{value: 80}), IsNil)
+	c.Assert(w.writeChanges("server.port", result{value: 80}, result{value: 80}), IsNil)
+	c.Assert(stdout.String(), Equals, "! server.port: key \"port\" not found\n+ server.port: 80\n")
+
+	stdout.Reset()
+	w.options = []lookup.Option{lookup.WithSplitToken("/")}
+	c.Assert(w.writeChanges("a/b", result{value: []interface{}{map[string]interface{}{"c": 1}}}, result{value: []interface{}{map[string]interface{}{"c": 2}}}), IsNil)
+	c.Assert(stdout.String(), Equals, "~ a/b[0]/c: 1 -> 2\n")
+}
+
+func (s *S) TestWatcher_Watch(c *C) {
+	file := filepath.Join(c.MkDir(), "config.json")
+	c.Assert(ioutil.WriteFile(file, []byte(`{"server": {"port": 80}}`), 0644), IsNil)
+
+	var stdout, stderr syncBuffer
+	w := &watcher{
+		file:   file,
+		format: "auto",
+		paths:  []string{"server.port", "server.host"},
+		eval: func(doc interface{}, path string) (interface{}, error) {
+			return lookup.Lookup(doc, path)
+		},
+		results: []result{{value: 80}, newResult(nil, status.Errorf(codes.NotFound, `key "host" not found`))},
+		stdout:  &stdout,
+		stderr:  &stderr,
+	}
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() { done <- w.watch(stop) }()
+
+	// Write until the watcher, which may not be watching yet, sees it.
+	want := "~ server.port: 80 -> 8080\n+ server.host: \"localhost\"\n"
+	for i := 0; i < 100 && !strings.Contains(stdout.String(), want); i++ {
+		c.Assert(ioutil.WriteFile(file, []byte(`{"server": {"port": 8080, "host": "localhost"}}`), 0644), IsNil)
+		time.Sleep(20 * time.Millisecond)
+	}
+	close(stop)
+	c.Assert(<-done, IsNil)
+	c.Assert(stdout.String(), Equals, want)
+	c.Assert(stderr.String(), Equals, "")
+}
+
+func (s *S) TestRun_WatchNeedsFile(c *C) {
+	code, _, stderr := runCommand(jsonDoc, "-watch", "server")
+	c.Assert(code, Equals, 2)
+	c.Assert(stderr, Equals, "lookup: -watch needs a file\n")
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/types"
+	pathpkg "path"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/iancoleman/strcase"
+	lookup "github.com/kevinxw/go-lookup"
+)
+
+const (
+	lookupPath = "github.com/kevinxw/go-lookup"
+	codesPath  = "google.golang.org/grpc/codes"
+	statusPath = "google.golang.org/grpc/status"
+)
+
+// Generate returns the source of the accessors for paths on the type named
+// typeName in pkg. tagKey names the fields of structs, as
+// lookup.Options.TagKey does.
+func Generate(pkg *types.Package, typeName string, paths []string, tagKey string) ([]byte, error) {
+	obj, ok := pkg.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("type %s not found in package %s", typeName, pkg.Name())
+	}
+
+	g := &generator{pkg: pkg, tagKey: tagKey, imports: make(map[string]string), names: make(map[string]string)}
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if err := g.accessor(obj, path); err != nil {
+			return nil, fmt.Errorf("path %q: %s", path, err)
+		}
+	}
+	return g.source()
+}
+
+type generator struct {
+	pkg    *types.Package
+	tagKey string
+	// imports maps the imported paths to their names.
+	imports map[string]string
+	// names maps the names of the accessors to their paths.
+	names map[string]string
+	body  bytes.Buffer
+}
+
+// step is a segment of a path resolved at generation time.
+type step struct {
+	// check is the code checking the segment can be resolved, if any.
+	check string
+	// expr is the expression of the value of the segment.
+	expr string
+	typ  types.Type
+}
+
+// accessor writes the accessor of path on the type obj.
+func (g *generator) accessor(obj *types.TypeName, path string) error {
+	name := accessorName(obj.Name(), path)
+	if other, ok := g.names[name]; ok {
+		return fmt.Errorf("accessor %s is already generated for path %q", name, other)
+	}
+	g.names[name] = path
+	param := "*" + obj.Name()
+
+	steps, static, err := g.resolve(obj.Type(), path)
+	if err != nil {
+		return err
+	}
+	if !static {
+		pkg := g.use(lookupPath, "lookup")
+		options := pkg + ".Options{}"
+		if g.tagKey != "" {
+			options = fmt.Sprintf("%s.Options{TagKey: %q}", pkg, g.tagKey)
+		}
+		fmt.Fprintf(&g.body, "\n// %s returns the value at %q of v, with %s.Lookup.\n", name, path, pkg)
+		fmt.Fprintf(&g.body, "func %s(v %s) (interface{}, error) {\n", name, param)
+		fmt.Fprintf(&g.body, "\treturn %s.Lookup(v, %q, %s)\n}\n", pkg, path, options)
+		return nil
+	}
+
+	last := steps[len(steps)-1]
+	fmt.Fprintf(&g.body, "\n// %s returns the value at %q of v.\n", name, path)
+	fmt.Fprintf(&g.body, "func %s(v %s) (out %s, err error) {\n", name, param, types.TypeString(last.typ, g.qualifier))
+	for _, s := range steps {
+		g.body.WriteString(s.check)
+	}
+	fmt.Fprintf(&g.body, "\treturn %s, nil\n}\n", last.expr)
+	return nil
+}
+
+// resolve returns the steps resolving path on a pointer to ty, or false if
+// path can't be resolved at generation time.
+func (g *generator) resolve(ty types.Type, path string) ([]step, bool, error) {
+	compiled, err := lookup.Compile(path, lookup.Options{TagKey: g.tagKey})
+	if err != nil {
+		return nil, false, err
+	}
+
+	var steps []step
+	expr, typ := "v", types.Type(types.NewPointer(ty))
+	for _, segment := range compiled.Segments() {
+		key, index := segment.Key, segment.Index
+		check := g.nilChecks(&expr, &typ, path)
+		if isPathResolver(typ) {
+			return nil, false, nil
+		}
+		switch t := typ.Underlying().(type) {
+		case *types.Struct:
+			f, ok, err := g.field(t, key)
+			if err != nil || !ok {
+				return nil, false, err
+			}
+			expr, typ = expr+"."+f.Name(), f.Type()
+		case *types.Map:
+			basic, ok := t.Key().Underlying().(*types.Basic)
+			if !ok || basic.Kind() != types.String {
+				return nil, false, nil
+			}
+			value := fmt.Sprintf("v%d", len(steps))
+			check += fmt.Sprintf("\t%s, ok := %s[%q]\n\tif !ok {\n\t\treturn out, %s\n\t}\n", value, expr, key, g.notFound("key %q not found", key))
+			expr, typ = value, t.Elem()
+		default:
+			return nil, false, nil
+		}
+
+		switch index {
+		case lookup.NoIndex:
+		case lookup.WildcardIndex, lookup.FilterIndex:
+			return nil, false, nil
+		default:
+			check += g.nilChecks(&expr, &typ, path)
+			switch t := typ.Underlying().(type) {
+			case *types.Slice:
+				typ = t.Elem()
+			case *types.Array:
+				typ = t.Elem()
+			default:
+				return nil, false, nil
+			}
+			check += fmt.Sprintf("\tif %d >= len(%s) {\n\t\treturn out, %s\n\t}\n", index, expr, g.notFound("index %d of key %q out of range", index, key))
+			expr = fmt.Sprintf("%s[%d]", expr, index)
+		}
+		steps = append(steps, step{check: check, expr: expr, typ: typ})
+	}
+	return steps, true, nil
+}
+
+// nilChecks dereferences the pointer types of *typ, returning the code
+// checking *expr isn't nil along the way.
+func (g *generator) nilChecks(expr *string, typ *types.Type, path string) string {
+	var check string
+	for {
+		ptr, ok := (*typ).Underlying().(*types.Pointer)
+		if !ok {
+			return check
+		}
+		check += fmt.Sprintf("\tif %s == nil {\n\t\treturn out, %s\n\t}\n", *expr, g.notFound("path %q not found", path))
+		// Selectors dereference a pointer to a struct by themselves.
+		if _, ok := ptr.Elem().Underlying().(*types.Struct); !ok {
+			*expr = "(*" + *expr + ")"
+		}
+		*typ = ptr.Elem()
+	}
+}
+
+// field returns the field of t named key, as lookup.Lookup resolves it: by
+// its Go name first, then by the tag of g. Promoted fields and unexported
+// fields are resolved by lookup.Lookup, which handles the nil embedded
+// pointers.
+func (g *generator) field(t *types.Struct, key string) (*types.Var, bool, error) {
+	var embedded bool
+	for i := 0; i < t.NumFields(); i++ {
+		f := t.Field(i)
+		if f.Name() == key {
+			return f, f.Exported(), nil
+		}
+		embedded = embedded || f.Embedded()
+	}
+	// The Go names of the promoted fields take precedence over the tags.
+	if embedded {
+		return nil, false, nil
+	}
+	for i := 0; i < t.NumFields(); i++ {
+		if name, ok := tagName(t.Tag(i), g.tagKey); ok && name == key {
+			return t.Field(i), t.Field(i).Exported(), nil
+		}
+	}
+	return nil, false, fmt.Errorf("field %q not found in %s", key, t)
+}
+
+// tagName returns the name given to a field by the tag tagKey of its struct
+// tag, as lookup does, or false if it has none.
+func tagName(tag, tagKey string) (string, bool) {
+	if tagKey == "" {
+		return "", false
+	}
+	value, ok := reflect.StructTag(tag).Lookup(tagKey)
+	if !ok {
+		return "", false
+	}
+	if tagKey == "protobuf" {
+		for _, opt := range strings.Split(value, ",") {
+			if strings.HasPrefix(opt, "name=") {
+				return strings.TrimPrefix(opt, "name="), true
+			}
+		}
+		return "", false
+	}
+	name, _, _ := strings.Cut(value, ",")
+	return name, name != "" && name != "-"
+}
+
+// isPathResolver returns whether the values of t, or their addresses, resolve
+// the keys looked up on them, as implementations of lookup.PathResolver.
+func isPathResolver(t types.Type) bool {
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, "LookupKey")
+	_, ok := obj.(*types.Func)
+	return ok
+}
+
+// notFound returns the expression of a NotFound error.
+func (g *generator) notFound(format string, args ...interface{}) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = fmt.Sprintf("%#v", arg)
+	}
+	return fmt.Sprintf("%s.Errorf(%s.NotFound, %q, %s)", g.use(statusPath, "status"), g.use(codesPath, "codes"), format, strings.Join(quoted, ", "))
+}
+
+// use records the import of path, and returns its name.
+func (g *generator) use(path, name string) string {
+	g.imports[path] = name
+	return name
+}
+
+func (g *generator) qualifier(pkg *types.Package) string {
+	if pkg == g.pkg {
+		return ""
+	}
+	return g.use(pkg.Path(), pkg.Name())
+}
+
+func (g *generator) source() ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by lookupgen; DO NOT EDIT.\n\npackage %s\n", g.pkg.Name())
+
+	// The standard library is imported first, as goimports does.
+	var std, others []string
+	for path := range g.imports {
+		if strings.Contains(strings.Split(path, "/")[0], ".") {
+			others = append(others, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(others)
+	if len(std)+len(others) > 0 {
+		buf.WriteString("\nimport (\n")
+		for i, paths := range [][]string{std, others} {
+			if i > 0 && len(std) > 0 && len(others) > 0 {
+				buf.WriteString("\n")
+			}
+			for _, path := range paths {
+				if name := g.imports[path]; name != pathpkg.Base(path) {
+					fmt.Fprintf(&buf, "\t%s %q\n", name, path)
+				} else {
+					fmt.Fprintf(&buf, "\t%q\n", path)
+				}
+			}
+		}
+		buf.WriteString(")\n")
+	}
+	buf.Write(g.body.Bytes())
+	return format.Source(buf.Bytes())
+}
+
+// accessorName returns the name of the accessor of path on the type named
+// typeName, e.g. LookupUserAddressCity for "Address.City". The characters
+// other than letters and digits separate the words of the name.
+func accessorName(typeName, path string) string {
+	var b strings.Builder
+	b.WriteString("Lookup ")
+	b.WriteString(typeName)
+	b.WriteRune(' ')
+	for _, r := range path {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			b.WriteRune(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return strcase.ToCamel(b.String())
+}
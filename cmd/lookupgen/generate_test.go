@@ -0,0 +1,148 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	lookup "github.com/kevinxw/go-lookup"
+	"github.com/kevinxw/go-lookup/cmd/lookupgen/testdata/user"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) { TestingT(t) }
+
+type S struct{}
+
+var _ = Suite(&S{})
+
+// userPaths are the paths of the go:generate directive of testdata/user.
+var userPaths = strings.Split("email,address.city,phones[0],labels.team,created,friends[*].email,meta.owner", ",")
+
+func (s *S) TestGenerate(c *C) {
+	pkg, err := loadPackage("testdata/user", "user_lookup.go")
+	c.Assert(err, IsNil)
+	src, err := Generate(pkg, "User", userPaths, "json")
+	c.Assert(err, IsNil)
+
+	golden, err := ioutil.ReadFile("testdata/user/user_lookup.go")
+	c.Assert(err, IsNil)
+	c.Assert(string(src), Equals, string(golden))
+
+	_, err = Generate(pkg, "Missing", userPaths, "json")
+	c.Assert(err, ErrorMatches, "type Missing not found in package user")
+
+	_, err = Generate(pkg, "User", []string{"address.zip"}, "json")
+	c.Assert(err, ErrorMatches, `path "address.zip": field "zip" not found in .*`)
+
+	_, err = Generate(pkg, "User", []string{"phones[x]"}, "json")
+	c.Assert(err, ErrorMatches, `path "phones\[x\]": .*invalid index "phones\[x\]"`)
+
+	// The fields are matched by their Go name too, and the paths are parsed
+	// as lookup does.
+	src, err = Generate(pkg, "User", []string{"Address.City", `"phones"[0]`, `labels[~"t*"]`}, "json")
+	c.Assert(err, IsNil)
+	c.Assert(string(src), Matches, `(?s).*func LookupUserAddressCity\(v \*User\) \(out string, err error\).*return v\.Address\.City, nil.*`)
+	c.Assert(string(src), Matches, `(?s).*func LookupUserPhones0\(v \*User\) \(out string, err error\).*return v\.Phones\[0\], nil.*`)
+	c.Assert(string(src), Matches, `(?s).*func LookupUserLabelsT\(v \*User\) \(interface\{\}, error\).*`)
+
+	_, err = Generate(pkg, "User", []string{"friends.email", "friends[*].email"}, "json")
+	c.Assert(err, ErrorMatches, `path "friends\[\*\].email": accessor LookupUserFriendsEmail is already generated for path "friends.email"`)
+}
+
+// accessor returns f returning an interface{}, as lookup.Lookup.
+func accessor[T any](f func(*user.User) (T, error)) func(*user.User) (interface{}, error) {
+	return func(u *user.User) (interface{}, error) {
+		v, err := f(u)
+		return v, err
+	}
+}
+
+func (s *S) TestGeneratedAccessorsMatchLookup(c *C) {
+	accessors := map[string]func(*user.User) (interface{}, error){
+		"email":            accessor(user.LookupUserEmail),
+		"address.city":     accessor(user.LookupUserAddressCity),
+		"phones[0]":        accessor(user.LookupUserPhones0),
+		"labels.team":      accessor(user.LookupUserLabelsTeam),
+		"created":          accessor(user.LookupUserCreated),
+		"friends[*].email": user.LookupUserFriendsEmail,
+		"meta.owner":       user.LookupUserMetaOwner,
+	}
+	c.Assert(accessors, HasLen, len(userPaths))
+
+	users := []*user.User{
+		{},
+		{
+			Email:   "bob@example.com",
+			Address: &user.Address{City: "Paris"},
+			Phones:  []string{"555-0100", "555-0101"},
+			Labels:  map[string]string{"team": "core"},
+			Created: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+			Friends: []*user.User{{Email: "alice@example.com"}, {}},
+			Meta:    map[string]interface{}{"owner": "carol"},
+		},
+		{Phones: []string{}, Labels: map[string]string{}, Friends: []*user.User{}},
+	}
+	for i, u := range users {
+		for _, path := range userPaths {
+			got, err := accessors[path](u)
+			// Lookup ignores the indexes of the keys looked up on pointers,
+			// unlike the accessors, so it's given the User itself.
+			want, wantErr := lookup.Lookup(*u, path, lookup.Options{TagKey: "json"})
+			comment := Commentf("user %d, path %q", i, path)
+			c.Assert(status.Code(err), Equals, status.Code(wantErr), comment)
+			if wantErr == nil {
+				c.Assert(got, DeepEquals, want, comment)
+			}
+		}
+	}
+}
+
+func (s *S) TestGeneratedAccessors(c *C) {
+	u := &user.User{
+		Email:   "bob@example.com",
+		Phones:  []string{"555-0100"},
+		Labels:  map[string]string{"team": "core"},
+		Friends: []*user.User{{Email: "alice@example.com"}},
+		Meta:    map[string]interface{}{"owner": "carol"},
+	}
+	opts := lookup.Options{TagKey: "json"}
+
+	email, err := user.LookupUserEmail(u)
+	c.Assert(err, IsNil)
+	c.Assert(email, Equals, "bob@example.com")
+
+	phone, err := user.LookupUserPhones0(u)
+	c.Assert(err, IsNil)
+	c.Assert(phone, Equals, "555-0100")
+
+	team, err := user.LookupUserLabelsTeam(u)
+	c.Assert(err, IsNil)
+	c.Assert(team, Equals, "core")
+
+	friends, err := user.LookupUserFriendsEmail(u)
+	c.Assert(err, IsNil)
+	want, err := lookup.Lookup(u, "friends[*].email", opts)
+	c.Assert(err, IsNil)
+	c.Assert(friends, DeepEquals, want)
+
+	owner, err := user.LookupUserMetaOwner(u)
+	c.Assert(err, IsNil)
+	c.Assert(owner, Equals, "carol")
+
+	_, err = user.LookupUserAddressCity(u)
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	_, err = user.LookupUserPhones0(&user.User{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	_, err = user.LookupUserLabelsTeam(&user.User{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	_, err = user.LookupUserEmail(nil)
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+}
@@ -0,0 +1,98 @@
+// Command lookupgen generates typed accessors for lookup paths on a type, so
+// hot paths don't pay for reflection:
+//
+//	//go:generate lookupgen -type User -paths Email,Address.City,Tags[0]
+//
+// generates, in user_lookup.go, functions such as
+//
+//	func LookupUserEmail(v *User) (out string, err error)
+//
+// Paths made of struct fields, string map keys and slice indexes are resolved
+// at generation time, and a nil pointer, a missing key or an index out of
+// range fail with NotFound, as with lookup.Lookup. Other paths, e.g. those
+// aggregating slices or going through interfaces, are delegated to
+// lookup.Lookup and their accessors return an interface{}.
+//
+// Paths are parsed as lookup parses them, and fields are matched by their Go
+// name, then by their -tag name. Unlike lookup.Lookup, which ignores them for
+// compatibility, the indexes of the keys looked up on pointers are honored,
+// as with lookup.LookupValue: Tags[0] is the first tag of a *User.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	typeName = flag.String("type", "", "name of the type to generate accessors for; required")
+	paths    = flag.String("paths", "", "comma-separated list of lookup paths; required")
+	tagKey   = flag.String("tag", "", "struct tag naming the fields, as lookup.Options.TagKey")
+	output   = flag.String("output", "", "output file name; default <type>_lookup.go")
+	dir      = flag.String("dir", ".", "directory of the package of the type")
+)
+
+func main() {
+	flag.Parse()
+	if *typeName == "" || *paths == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	name := *output
+	if name == "" {
+		name = strings.ToLower(*typeName) + "_lookup.go"
+	}
+
+	pkg, err := loadPackage(*dir, name)
+	if err != nil {
+		fatalf("loading package: %s", err)
+	}
+	src, err := Generate(pkg, *typeName, strings.Split(*paths, ","), *tagKey)
+	if err != nil {
+		fatalf("generating accessors: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(*dir, name), src, 0644); err != nil {
+		fatalf("writing accessors: %s", err)
+	}
+}
+
+// loadPackage parses and type-checks the package in dir, ignoring its tests
+// and the previously generated file output.
+func loadPackage(dir, output string) (*types.Package, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && fi.Name() != output
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("found %d packages in %s, expected 1", len(pkgs), dir)
+	}
+
+	var files []*ast.File
+	var name string
+	for n, pkg := range pkgs {
+		name = n
+		for _, f := range pkg.Files {
+			files = append(files, f)
+		}
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	return conf.Check(name, fset, files, nil)
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "lookupgen: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,19 @@
+package user
+
+import "time"
+
+//go:generate go run github.com/kevinxw/go-lookup/cmd/lookupgen -type User -tag json -paths email,address.city,phones[0],labels.team,created,friends[*].email,meta.owner
+
+type User struct {
+	Email   string            `json:"email"`
+	Address *Address          `json:"address"`
+	Phones  []string          `json:"phones"`
+	Labels  map[string]string `json:"labels"`
+	Created time.Time         `json:"created"`
+	Friends []*User           `json:"friends"`
+	Meta    interface{}       `json:"meta"`
+}
+
+type Address struct {
+	City string `json:"city"`
+}
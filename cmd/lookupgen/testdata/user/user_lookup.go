@@ -0,0 +1,71 @@
+// Code generated by lookupgen; DO NOT EDIT.
+
+package user
+
+import (
+	"time"
+
+	lookup "github.com/kevinxw/go-lookup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LookupUserEmail returns the value at "email" of v.
+func LookupUserEmail(v *User) (out string, err error) {
+	if v == nil {
+		return out, status.Errorf(codes.NotFound, "path %q not found", "email")
+	}
+	return v.Email, nil
+}
+
+// LookupUserAddressCity returns the value at "address.city" of v.
+func LookupUserAddressCity(v *User) (out string, err error) {
+	if v == nil {
+		return out, status.Errorf(codes.NotFound, "path %q not found", "address.city")
+	}
+	if v.Address == nil {
+		return out, status.Errorf(codes.NotFound, "path %q not found", "address.city")
+	}
+	return v.Address.City, nil
+}
+
+// LookupUserPhones0 returns the value at "phones[0]" of v.
+func LookupUserPhones0(v *User) (out string, err error) {
+	if v == nil {
+		return out, status.Errorf(codes.NotFound, "path %q not found", "phones[0]")
+	}
+	if 0 >= len(v.Phones) {
+		return out, status.Errorf(codes.NotFound, "index %d of key %q out of range", 0, "phones")
+	}
+	return v.Phones[0], nil
+}
+
+// LookupUserLabelsTeam returns the value at "labels.team" of v.
+func LookupUserLabelsTeam(v *User) (out string, err error) {
+	if v == nil {
+		return out, status.Errorf(codes.NotFound, "path %q not found", "labels.team")
+	}
+	v1, ok := v.Labels["team"]
+	if !ok {
+		return out, status.Errorf(codes.NotFound, "key %q not found", "team")
+	}
+	return v1, nil
+}
+
+// LookupUserCreated returns the value at "created" of v.
+func LookupUserCreated(v *User) (out time.Time, err error) {
+	if v == nil {
+		return out, status.Errorf(codes.NotFound, "path %q not found", "created")
+	}
+	return v.Created, nil
+}
+
+// LookupUserFriendsEmail returns the value at "friends[*].email" of v, with lookup.Lookup.
+func LookupUserFriendsEmail(v *User) (interface{}, error) {
+	return lookup.Lookup(v, "friends[*].email", lookup.Options{TagKey: "json"})
+}
+
+// LookupUserMetaOwner returns the value at "meta.owner" of v, with lookup.Lookup.
+func LookupUserMetaOwner(v *User) (interface{}, error) {
+	return lookup.Lookup(v, "meta.owner", lookup.Options{TagKey: "json"})
+}
@@ -0,0 +1,104 @@
+package lookup
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// CompiledPath is a path parsed once by Compile, to be looked up on many
+// values. It's safe for concurrent use.
+type CompiledPath struct {
+	path     string
+	opts     Options
+	parts    []string
+	segments []segment
+}
+
+// segment is a parsed path segment.
+type segment struct {
+	part  string
+	key   string
+	index int
+}
+
+// Segment is a parsed segment of a compiled path.
+type Segment struct {
+	// Key is the key of the segment, unquoted or unescaped.
+	Key string
+	// Index is the index of the segment: NoIndex if it has none,
+	// WildcardIndex for "[*]" and FilterIndex for a key filter.
+	Index int
+}
+
+// The indexes of the segments without a numeric index.
+const (
+	NoIndex       = noIndex
+	WildcardIndex = wildcardIndex
+	FilterIndex   = filterIndex
+)
+
+// Compile parses path with options, so it can be looked up repeatedly without
+// splitting and parsing its segments again. A segment with an invalid index
+// fails with InvalidArgument.
+func Compile(path string, options ...Option) (*CompiledPath, error) {
+	opts := NewOptions(options...)
+	parts := splitPath(path, &opts)
+	segments := make([]segment, len(parts))
+	for i, part := range parts {
+		key, index, err := parseIndex(part)
+		if err != nil {
+			return nil, err
+		}
+		segments[i] = segment{part: part, key: key, index: index}
+	}
+	return &CompiledPath{path: path, opts: opts, parts: parts, segments: segments}, nil
+}
+
+// MustCompile is like Compile but panics if path can't be compiled, to
+// initialize global variables.
+func MustCompile(path string, options ...Option) *CompiledPath {
+	opts := NewOptions(options...)
+	p, err := Compile(path, opts)
+	if err != nil {
+		panic(fmt.Sprintf("lookup: Compile(%q): %s", path, err))
+	}
+	return p
+}
+
+// Lookup is like the Lookup function, for the path and the options of p.
+func (p *CompiledPath) Lookup(i interface{}) (interface{}, error) {
+	return p.LookupContext(context.Background(), i)
+}
+
+// LookupContext is like the LookupContext function, for the path and the
+// options of p.
+func (p *CompiledPath) LookupContext(ctx context.Context, i interface{}) (interface{}, error) {
+	if ctx.Err() == nil && canFastLookup(&p.opts) {
+		if v, ok := fastLookup(i, p.path, &p.opts); ok {
+			return resultInterface(v, &p.opts), nil
+		}
+	}
+
+	t := newTraversal(ctx, p.opts)
+	t.compiled = p
+	v, err := t.lookup(reflect.ValueOf(i), p.parts, nil, 0)
+	if err != nil || !v.IsValid() {
+		return nil, err
+	}
+	return resultInterface(v, &p.opts), nil
+}
+
+// Segments returns the segments of p, as Lookup parses them.
+func (p *CompiledPath) Segments() []Segment {
+	segments := make([]Segment, len(p.segments))
+	for i, s := range p.segments {
+		segments[i] = Segment{Key: s.key, Index: s.index}
+	}
+	return segments
+}
+
+// String returns the source path of p.
+func (p *CompiledPath) String() string {
+	return p.path
+}
@@ -0,0 +1,45 @@
+package lookup
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestCompile(c *C) {
+	for _, path := range []string{
+		"String",
+		"StructSlice.String",
+		"StructSlice[*].Map.foo",
+		"StructSlice[1].String",
+		"Map.foo",
+		"Missing",
+	} {
+		p, err := Compile(path, Options{})
+		c.Assert(err, IsNil)
+		c.Assert(p.String(), Equals, path)
+
+		want, wantErr := Lookup(mapComplexFixture, path, Options{})
+		value, err := p.Lookup(mapComplexFixture)
+		c.Assert(value, DeepEquals, want, Commentf("path %q", path))
+		c.Assert(status.Code(err), Equals, status.Code(wantErr), Commentf("path %q", path))
+	}
+
+	p := MustCompile("a/b[0]", Options{SplitToken: "/"})
+	value, err := p.Lookup(map[string]interface{}{"a": map[string]interface{}{"b": []int{42}}})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 42)
+
+	p = MustCompile(`a/"b[c]"[0]/d[*]/e[~"x*"]/f`, Options{SplitToken: "/"})
+	c.Assert(p.Segments(), DeepEquals, []Segment{
+		{Key: "a", Index: NoIndex},
+		{Key: "b[c]", Index: 0},
+		{Key: "d", Index: WildcardIndex},
+		{Key: "e", Index: FilterIndex},
+		{Key: "f", Index: NoIndex},
+	})
+
+	_, err = Compile("String[x]", Options{})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+	c.Assert(func() { MustCompile("String[", Options{}) }, PanicMatches, `lookup: Compile\("String\["\): .*`)
+}
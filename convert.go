@@ -0,0 +1,506 @@
+package lookup
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LookupString performs a lookup like Lookup, and converts the result into a
+// string. Booleans, numbers and fmt.Stringers are formatted. A value which
+// can't be converted fails with InvalidArgument.
+func LookupString(i interface{}, path string, options ...Option) (string, error) {
+	opts := NewOptions(options...)
+	value, err := lookupNotNil(i, path, opts)
+	if err != nil {
+		return "", err
+	}
+	return toString(value)
+}
+
+// LookupInt performs a lookup like Lookup, and converts the result into an
+// int64. Floats without fractional part, json.Numbers, big numbers, booleans
+// and strings holding numbers, e.g. "42", are converted. A value which can't be converted
+// fails with InvalidArgument.
+func LookupInt(i interface{}, path string, options ...Option) (int64, error) {
+	opts := NewOptions(options...)
+	value, err := lookupNotNil(i, path, opts)
+	if err != nil {
+		return 0, err
+	}
+	return toInt64(value)
+}
+
+// LookupFloat performs a lookup like Lookup, and converts the result into a
+// float64. Numbers, including big numbers, booleans and strings holding
+// numbers are converted. A value which can't be converted fails with
+// InvalidArgument.
+func LookupFloat(i interface{}, path string, options ...Option) (float64, error) {
+	opts := NewOptions(options...)
+	value, err := lookupNotNil(i, path, opts)
+	if err != nil {
+		return 0, err
+	}
+	return toFloat64(value)
+}
+
+// LookupBool performs a lookup like Lookup, and converts the result into a
+// bool. Numbers are true if not 0, and strings are parsed by
+// strconv.ParseBool. A value which can't be converted fails with
+// InvalidArgument.
+func LookupBool(i interface{}, path string, options ...Option) (bool, error) {
+	opts := NewOptions(options...)
+	value, err := lookupNotNil(i, path, opts)
+	if err != nil {
+		return false, err
+	}
+	return toBool(value)
+}
+
+// LookupTime performs a lookup like Lookup, and converts the result into a
+// time.Time. Strings are parsed with Options.TimeLayouts, and numbers are seconds
+// since the Unix epoch. A value which can't be converted fails with
+// InvalidArgument.
+func LookupTime(i interface{}, path string, options ...Option) (time.Time, error) {
+	opts := NewOptions(options...)
+	value, err := lookupNotNil(i, path, opts)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return toTime(value, opts)
+}
+
+// LookupDuration performs a lookup like Lookup, and converts the result into
+// a time.Duration. Strings are parsed by time.ParseDuration, e.g. "30s", and
+// numbers are in Options.DurationUnit, milliseconds by default. A value which
+// can't be converted fails with InvalidArgument.
+func LookupDuration(i interface{}, path string, options ...Option) (time.Duration, error) {
+	opts := NewOptions(options...)
+	value, err := lookupNotNil(i, path, opts)
+	if err != nil {
+		return 0, err
+	}
+	return toDuration(value, opts)
+}
+
+// LookupSlice performs a lookup like Lookup, and converts the elements of the
+// result, which must be a slice, e.g. an aggregation or a JSON list, into Ts
+// as LookupString and the other typed lookups do. Other types are stored as
+// with LookupInto. An element which can't be converted fails with
+// InvalidArgument, giving its index.
+func LookupSlice[T any](i interface{}, path string, options ...Option) ([]T, error) {
+	opts := NewOptions(options...)
+	value, err := lookupNotNil(i, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	elems, err := toSlice(value)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]T, len(elems))
+	for i, elem := range elems {
+		if err := coerce(reflect.ValueOf(&out[i]).Elem(), elem, opts); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "element %d: %s", i, status.Convert(err).Message())
+		}
+	}
+	return out, nil
+}
+
+// LookupStringMap performs a lookup like Lookup, and converts the result, a
+// map or a struct, into a map of Ts. The keys of maps are converted into
+// strings, structs are converted field-wise by the names of their exported
+// fields, and the values are converted as with LookupSlice. A value which
+// can't be converted fails with InvalidArgument, giving its key.
+func LookupStringMap[T any](i interface{}, path string, options ...Option) (map[string]T, error) {
+	opts := NewOptions(options...)
+	value, err := lookupNotNil(i, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	m, err := toStringMap(value)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]T, len(m))
+	for k, v := range m {
+		var elem T
+		if err := coerce(reflect.ValueOf(&elem).Elem(), v, opts); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "key %q: %s", k, status.Convert(err).Message())
+		}
+		out[k] = elem
+	}
+	return out, nil
+}
+
+// lookupNotNil is Lookup failing with NotFound instead of returning nil.
+func lookupNotNil(i interface{}, path string, opts Options) (interface{}, error) {
+	value, err := Lookup(i, path, opts)
+	if err == nil && value == nil {
+		err = status.Errorf(codes.NotFound, "path %q not found", path)
+	}
+	return value, err
+}
+
+// coerce stores value into the settable dst, converting it with toString,
+// toInt64, toFloat64, toBool, toTime or toDuration according to the type of
+// dst. Other types are stored with assign.
+func coerce(dst reflect.Value, value interface{}, opts Options) error {
+	if value != nil && reflect.TypeOf(value).AssignableTo(dst.Type()) {
+		dst.Set(reflect.ValueOf(value))
+		return nil
+	}
+
+	switch dst.Type() {
+	case timeType:
+		t, err := toTime(value, opts)
+		if err == nil {
+			dst.Set(reflect.ValueOf(t))
+		}
+		return err
+	case durationType:
+		d, err := toDuration(value, opts)
+		if err == nil {
+			dst.SetInt(int64(d))
+		}
+		return err
+	}
+
+	switch dst.Kind() {
+	case reflect.String:
+		s, err := toString(value)
+		if err == nil {
+			dst.SetString(s)
+		}
+		return err
+	case reflect.Bool:
+		b, err := toBool(value)
+		if err == nil {
+			dst.SetBool(b)
+		}
+		return err
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return setNumber(dst, value)
+	}
+	return assign(dst, value)
+}
+
+// setNumber sets dst, of a numeric kind, to value converted as by toInt64 or
+// toFloat64. It fails with InvalidArgument if value overflows dst, or loses its
+// fraction, instead of wrapping or truncating it as reflect's conversions do.
+func setNumber(dst reflect.Value, value interface{}) error {
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := toInt64(value)
+		if err == nil && dst.OverflowInt(i) {
+			err = conversionError(value, dst.Type().String())
+		}
+		if err == nil {
+			dst.SetInt(i)
+		}
+		return err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		// The unsigned values beyond math.MaxInt64 don't go through an int64.
+		if v := reflect.ValueOf(value); isUint(v.Kind()) {
+			if dst.OverflowUint(v.Uint()) {
+				return conversionError(value, dst.Type().String())
+			}
+			dst.SetUint(v.Uint())
+			return nil
+		}
+		i, err := toInt64(value)
+		if err == nil && (i < 0 || dst.OverflowUint(uint64(i))) {
+			err = conversionError(value, dst.Type().String())
+		}
+		if err == nil {
+			dst.SetUint(uint64(i))
+		}
+		return err
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat64(value)
+		if err == nil && dst.OverflowFloat(f) {
+			err = conversionError(value, dst.Type().String())
+		}
+		if err == nil {
+			dst.SetFloat(f)
+		}
+		return err
+	}
+	return conversionError(value, dst.Type().String())
+}
+
+func isUint(k reflect.Kind) bool {
+	switch k {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// toString converts v, a string, a []byte, a fmt.Stringer, a bool or a
+// number, into a string.
+func toString(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case json.Number:
+		return v.String(), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	// E.g. a big.Int or a url.URL, whose String method has a pointer
+	// receiver.
+	if rv.Kind() == reflect.Struct && reflect.PtrTo(rv.Type()).Implements(stringerType) {
+		return pointerTo(rv).(fmt.Stringer).String(), nil
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64), nil
+	}
+	return "", conversionError(v, "a string")
+}
+
+// toInt64 converts v, a number, a big number, a bool or a string holding a
+// number, into an int64. Numbers with a fractional part or out of the range of int64 aren't
+// converted.
+func toInt64(v interface{}) (int64, error) {
+	v = fromBig(v)
+	if n, ok := v.(json.Number); ok {
+		v = string(n)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt64 {
+			return 0, conversionError(v, "an int64")
+		}
+		return int64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return floatToInt64(v, rv.Float())
+	case reflect.Bool:
+		if rv.Bool() {
+			return 1, nil
+		}
+		return 0, nil
+	case reflect.String:
+		if i, err := strconv.ParseInt(rv.String(), 10, 64); err == nil {
+			return i, nil
+		}
+		f, err := strconv.ParseFloat(rv.String(), 64)
+		if err != nil {
+			return 0, conversionError(v, "an int64")
+		}
+		return floatToInt64(v, f)
+	}
+	return 0, conversionError(v, "an int64")
+}
+
+func floatToInt64(v interface{}, f float64) (int64, error) {
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, conversionError(v, "an int64")
+	}
+	return int64(f), nil
+}
+
+// toFloat64 converts v, a number, a big number, a bool or a string holding a
+// number, into a float64.
+func toFloat64(v interface{}) (float64, error) {
+	v = fromBig(v)
+	if n, ok := v.(json.Number); ok {
+		v = string(n)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.Bool:
+		if rv.Bool() {
+			return 1, nil
+		}
+		return 0, nil
+	case reflect.String:
+		f, err := strconv.ParseFloat(rv.String(), 64)
+		if err != nil {
+			return 0, conversionError(v, "a float64")
+		}
+		return f, nil
+	}
+	return 0, conversionError(v, "a float64")
+}
+
+// toBool converts v, a bool, a number or a string parsed by strconv.ParseBool,
+// into a bool. Numbers are true if not 0.
+func toBool(v interface{}) (bool, error) {
+	if n, ok := v.(json.Number); ok {
+		f, err := n.Float64()
+		if err != nil {
+			return false, conversionError(v, "a bool")
+		}
+		return f != 0, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() != 0, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint() != 0, nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() != 0, nil
+	case reflect.String:
+		b, err := strconv.ParseBool(rv.String())
+		if err != nil {
+			return false, conversionError(v, "a bool")
+		}
+		return b, nil
+	}
+	return false, conversionError(v, "a bool")
+}
+
+// toTime converts v, a time.Time, a number of seconds since the Unix epoch or
+// a string parsed with opts.TimeLayouts, into a time.Time.
+func toTime(v interface{}, opts Options) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case *time.Time:
+		if t != nil {
+			return *t, nil
+		}
+		return time.Time{}, conversionError(v, "a time")
+	}
+
+	if s, ok := v.(string); ok {
+		layouts := opts.TimeLayouts
+		if len(layouts) == 0 {
+			layouts = []string{time.RFC3339Nano}
+		}
+		for _, layout := range layouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+	}
+
+	if reflect.ValueOf(v).Kind() == reflect.Bool {
+		return time.Time{}, conversionError(v, "a time")
+	}
+	if i, err := toInt64(v); err == nil {
+		return time.Unix(i, 0), nil
+	}
+	f, err := toFloat64(v)
+	if err != nil {
+		return time.Time{}, conversionError(v, "a time")
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*1e9)), nil
+}
+
+// toDuration converts v, a time.Duration, a string parsed by
+// time.ParseDuration or a number of opts.DurationUnit, into a time.Duration.
+func toDuration(v interface{}, opts Options) (time.Duration, error) {
+	if d, ok := v.(time.Duration); ok {
+		return d, nil
+	}
+	if s, ok := v.(string); ok {
+		if d, err := time.ParseDuration(s); err == nil {
+			return d, nil
+		}
+	}
+
+	unit := opts.DurationUnit
+	if unit == 0 {
+		unit = time.Millisecond
+	}
+	if reflect.ValueOf(v).Kind() == reflect.Bool {
+		return 0, conversionError(v, "a duration")
+	}
+	f, err := toFloat64(v)
+	if err != nil || math.Abs(f*float64(unit)) > math.MaxInt64 {
+		return 0, conversionError(v, "a duration")
+	}
+	if i, err := toInt64(v); err == nil {
+		return time.Duration(i) * unit, nil
+	}
+	return time.Duration(f * float64(unit)), nil
+}
+
+// toSlice returns the elements of v, a slice or an array.
+func toSlice(v interface{}) ([]interface{}, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, conversionError(v, "a slice")
+	}
+	out := make([]interface{}, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out, nil
+}
+
+// toStringMap converts v, a map or a struct, into a map. The keys of maps are
+// converted with toString, and structs are converted field-wise, by the names
+// of their exported fields.
+func toStringMap(v interface{}) (map[string]interface{}, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Map:
+		out := make(map[string]interface{}, rv.Len())
+		for _, k := range rv.MapKeys() {
+			key, err := toString(k.Interface())
+			if err != nil {
+				return nil, conversionError(v, "a map with string keys")
+			}
+			out[key] = rv.MapIndex(k).Interface()
+		}
+		return out, nil
+	case reflect.Struct:
+		out := make(map[string]interface{})
+		for _, f := range structFields(rv.Type(), Options{}) {
+			if f.PkgPath != "" || f.Anonymous {
+				continue
+			}
+			if value := fieldByIndex(rv, f.Index); value.IsValid() {
+				out[f.Name] = value.Interface()
+			}
+		}
+		return out, nil
+	}
+	return nil, conversionError(v, "a map")
+}
+
+func conversionError(v interface{}, to string) error {
+	return status.Errorf(codes.InvalidArgument, "can't convert %#v of type %T into %s", v, v, to)
+}
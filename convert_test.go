@@ -0,0 +1,224 @@
+package lookup
+
+import (
+	"encoding/json"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+var convertFixture = map[string]interface{}{
+	"string":  "42",
+	"float":   float64(1),
+	"decimal": 1.5,
+	"number":  json.Number("9007199254740993"),
+	"bool":    true,
+	"color":   MyColor(1),
+	"uint":    uint8(7),
+	"list":    []string{"a"},
+}
+
+func (s *S) TestLookupString(c *C) {
+	tests := map[string]string{
+		"string":  "42",
+		"float":   "1",
+		"decimal": "1.5",
+		"number":  "9007199254740993",
+		"bool":    "true",
+		"color":   "green",
+		"uint":    "7",
+	}
+	for path, want := range tests {
+		value, err := LookupString(convertFixture, path, Options{})
+		c.Assert(err, IsNil, Commentf("path %q", path))
+		c.Assert(value, Equals, want, Commentf("path %q", path))
+	}
+
+	_, err := LookupString(convertFixture, "list", Options{})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+
+	_, err = LookupString(convertFixture, "missing", Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+}
+
+func (s *S) TestLookupInt(c *C) {
+	tests := map[string]int64{
+		"string": 42,
+		"float":  1,
+		"number": 9007199254740993,
+		"bool":   1,
+		"uint":   7,
+	}
+	for path, want := range tests {
+		value, err := LookupInt(convertFixture, path, Options{})
+		c.Assert(err, IsNil, Commentf("path %q", path))
+		c.Assert(value, Equals, want, Commentf("path %q", path))
+	}
+
+	for _, path := range []string{"decimal", "list"} {
+		_, err := LookupInt(convertFixture, path, Options{})
+		c.Assert(status.Code(err), Equals, codes.InvalidArgument, Commentf("path %q", path))
+	}
+}
+
+func (s *S) TestLookupFloat(c *C) {
+	tests := map[string]float64{
+		"string":  42,
+		"float":   1,
+		"decimal": 1.5,
+		"bool":    1,
+		"uint":    7,
+	}
+	for path, want := range tests {
+		value, err := LookupFloat(convertFixture, path, Options{})
+		c.Assert(err, IsNil, Commentf("path %q", path))
+		c.Assert(value, Equals, want, Commentf("path %q", path))
+	}
+
+	_, err := LookupFloat(convertFixture, "list", Options{})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+}
+
+func (s *S) TestLookupBool(c *C) {
+	tests := map[string]bool{
+		"float":   true,
+		"decimal": true,
+		"number":  true,
+		"bool":    true,
+		"uint":    true,
+	}
+	for path, want := range tests {
+		value, err := LookupBool(convertFixture, path, Options{})
+		c.Assert(err, IsNil, Commentf("path %q", path))
+		c.Assert(value, Equals, want, Commentf("path %q", path))
+	}
+
+	_, err := LookupBool(convertFixture, "string", Options{})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+}
+
+func (s *S) TestLookupTime(c *C) {
+	want := time.Date(2022, 2, 11, 17, 18, 37, 0, time.UTC)
+	fixture := map[string]interface{}{
+		"rfc3339": "2022-02-11T17:18:37Z",
+		"date":    "2022-02-11 17:18:37",
+		"unix":    want.Unix(),
+		"float":   float64(want.Unix()) + 0.5,
+		"string":  "1644599917",
+		"time":    want,
+		"bool":    true,
+	}
+	opts := Options{TimeLayouts: []string{time.RFC3339, "2006-01-02 15:04:05"}}
+
+	for _, path := range []string{"rfc3339", "date", "unix", "string", "time"} {
+		value, err := LookupTime(fixture, path, opts)
+		c.Assert(err, IsNil, Commentf("path %q", path))
+		c.Assert(value.Equal(want), Equals, true, Commentf("path %q: %s", path, value))
+	}
+
+	value, err := LookupTime(fixture, "float", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value.Equal(want.Add(500*time.Millisecond)), Equals, true)
+
+	_, err = LookupTime(fixture, "date", Options{})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+
+	_, err = LookupTime(fixture, "bool", opts)
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+}
+
+func (s *S) TestLookupDuration(c *C) {
+	fixture := map[string]interface{}{
+		"string":   "1.5s",
+		"number":   float64(1500),
+		"numeric":  "1500",
+		"decimal":  1.5,
+		"duration": 1500 * time.Millisecond,
+		"bool":     true,
+	}
+
+	for _, path := range []string{"string", "number", "numeric", "duration"} {
+		value, err := LookupDuration(fixture, path, Options{})
+		c.Assert(err, IsNil, Commentf("path %q", path))
+		c.Assert(value, Equals, 1500*time.Millisecond, Commentf("path %q", path))
+	}
+
+	value, err := LookupDuration(fixture, "decimal", Options{DurationUnit: time.Second})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 1500*time.Millisecond)
+
+	_, err = LookupDuration(fixture, "bool", Options{})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+}
+
+func (s *S) TestLookupSlice(c *C) {
+	fixture := map[string]interface{}{
+		"numbers": []interface{}{float64(1), "2", json.Number("3")},
+		"mixed":   []interface{}{float64(1), "foo"},
+		"items":   []interface{}{map[string]interface{}{"name": "foo"}, map[string]interface{}{"name": "bar"}},
+	}
+
+	ints, err := LookupSlice[int](fixture, "numbers", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(ints, DeepEquals, []int{1, 2, 3})
+
+	strs, err := LookupSlice[string](fixture, "numbers", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(strs, DeepEquals, []string{"1", "2", "3"})
+
+	names, err := LookupSlice[string](fixture, "items.name", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(names, DeepEquals, []string{"foo", "bar"})
+
+	type Item struct {
+		Name string
+	}
+	items, err := LookupSlice[Item](fixture, "items", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(items, DeepEquals, []Item{{Name: "foo"}, {Name: "bar"}})
+
+	_, err = LookupSlice[int](fixture, "mixed", Options{})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+	c.Assert(err, ErrorMatches, ".*element 1.*")
+
+	_, err = LookupSlice[uint8](map[string]interface{}{"l": []int{256}}, "l", Options{})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+}
+
+func (s *S) TestLookupStringMap(c *C) {
+	type Metadata struct {
+		Labels      map[string]interface{}
+		Annotations map[MyKey]string
+		Limits      struct {
+			CPU    string
+			Memory float64
+		}
+	}
+	fixture := Metadata{
+		Labels:      map[string]interface{}{"app": "foo", "replicas": float64(3), "tags": []string{"a"}},
+		Annotations: map[MyKey]string{"owner": "bar"},
+	}
+	fixture.Limits.CPU = "2"
+	fixture.Limits.Memory = 512
+
+	annotations, err := LookupStringMap[string](fixture, "Annotations", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(annotations, DeepEquals, map[string]string{"owner": "bar"})
+
+	limits, err := LookupStringMap[int](fixture, "Limits", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(limits, DeepEquals, map[string]int{"CPU": 2, "Memory": 512})
+
+	labels, err := LookupStringMap[interface{}](fixture, "Labels", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(labels, DeepEquals, fixture.Labels)
+
+	_, err = LookupStringMap[string](fixture, "Labels", Options{})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+	c.Assert(err, ErrorMatches, `.*key "tags".*`)
+
+	_, err = LookupStringMap[string](fixture, "Limits.CPU", Options{})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+}
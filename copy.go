@@ -0,0 +1,109 @@
+package lookup
+
+import (
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+)
+
+var protoMessageType = reflect.TypeOf((*proto.Message)(nil)).Elem()
+
+// resultInterface returns the interface of v, the result of a lookup, deep
+// copied if opts.CopyResult is set.
+func resultInterface(v reflect.Value, opts *Options) interface{} {
+	if opts.CopyResult {
+		v = deepCopy(v)
+	}
+	return v.Interface()
+}
+
+// deepCopy returns a copy of v sharing no pointer, map or slice with it. The
+// values reachable several times from v, including through cycles, are
+// copied once. Protobuf messages are copied with proto.Clone. The fields of
+// structs which aren't exported, the keys of maps, channels and functions are
+// copied shallowly.
+func deepCopy(v reflect.Value) reflect.Value {
+	c := copier{copies: make(map[visit]reflect.Value)}
+	return c.copy(v)
+}
+
+type copier struct {
+	// The copies of the pointers, maps and slices already copied.
+	copies map[visit]reflect.Value
+}
+
+func (c *copier) copy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		key := visit{ptr: v.Pointer(), typ: v.Type()}
+		if v.Kind() == reflect.Slice {
+			key.len = v.Len()
+		}
+		if out, ok := c.copies[key]; ok {
+			return out
+		}
+		return c.copyReference(v, key)
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(c.copy(v.Elem()))
+		return out
+
+	case reflect.Array:
+		out := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(c.copy(v.Index(i)))
+		}
+		return out
+
+	case reflect.Struct:
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if f := out.Field(i); f.CanSet() {
+				f.Set(c.copy(v.Field(i)))
+			}
+		}
+		return out
+	}
+	return v
+}
+
+// copyReference copies the pointer, map or slice v, recording its copy under
+// key before copying its elements.
+func (c *copier) copyReference(v reflect.Value, key visit) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.Type().Implements(protoMessageType) && v.CanInterface() {
+			out := reflect.ValueOf(proto.Clone(v.Interface().(proto.Message)))
+			c.copies[key] = out
+			return out
+		}
+		out := reflect.New(v.Type().Elem())
+		c.copies[key] = out
+		out.Elem().Set(c.copy(v.Elem()))
+		return out
+
+	case reflect.Map:
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		c.copies[key] = out
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), c.copy(iter.Value()))
+		}
+		return out
+	}
+
+	out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+	c.copies[key] = out
+	for i := 0; i < v.Len(); i++ {
+		out.Index(i).Set(c.copy(v.Index(i)))
+	}
+	return out
+}
@@ -0,0 +1,68 @@
+package lookup
+
+import (
+	"reflect"
+
+	"google.golang.org/protobuf/types/known/structpb"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestLookup_CopyResult(c *C) {
+	i := map[string]interface{}{
+		"items": []interface{}{map[string]interface{}{"name": "a"}},
+	}
+	value, err := Lookup(i, "items", CopyResult())
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, i["items"])
+	value.([]interface{})[0].(map[string]interface{})["name"] = "b"
+	c.Assert(i["items"].([]interface{})[0].(map[string]interface{})["name"], Equals, "a")
+
+	// Without it, the result aliases i.
+	value, err = Lookup(i, "items")
+	c.Assert(err, IsNil)
+	value.([]interface{})[0].(map[string]interface{})["name"] = "b"
+	c.Assert(i["items"].([]interface{})[0].(map[string]interface{})["name"], Equals, "b")
+
+	values, err := LookupMany(structFixture, []string{"StructSlice", "Map"}, CopyResult())
+	c.Assert(err, IsNil)
+	c.Assert(values["StructSlice"], DeepEquals, structFixture.StructSlice)
+	values["StructSlice"].([]*MyStruct)[0].String = "changed"
+	values["Map"].(map[string]int)["foo"] = 0
+	c.Assert(structFixture.StructSlice[0].String, Equals, "foo")
+	c.Assert(mapFixture["foo"], Equals, 42)
+}
+
+func (s *S) TestDeepCopy(c *C) {
+	type node struct {
+		Name     string
+		Next     *node
+		Children []*node
+		hidden   map[string]int
+	}
+	a := &node{Name: "a", hidden: map[string]int{"x": 1}}
+	b := &node{Name: "b", Next: a}
+	a.Next = b
+	a.Children = []*node{b, b}
+
+	out := deepCopyInterface(a).(*node)
+	c.Assert(out != a, Equals, true)
+	c.Assert(out.Name, Equals, "a")
+	c.Assert(out.Next != b, Equals, true)
+	c.Assert(out.Next.Next == out, Equals, true)
+	c.Assert(out.Children[0] == out.Next, Equals, true)
+	c.Assert(out.Children[1] == out.Next, Equals, true)
+	// The unexported fields are copied shallowly.
+	out.hidden["x"] = 2
+	c.Assert(a.hidden["x"], Equals, 2)
+
+	msg, err := structpb.NewStruct(map[string]interface{}{"a": "b"})
+	c.Assert(err, IsNil)
+	copied := deepCopyInterface(msg).(*structpb.Struct)
+	c.Assert(copied != msg, Equals, true)
+	copied.Fields["a"] = structpb.NewStringValue("c")
+	c.Assert(msg.Fields["a"].GetStringValue(), Equals, "b")
+}
+
+func deepCopyInterface(i interface{}) interface{} {
+	return deepCopy(reflect.ValueOf(i)).Interface()
+}
@@ -0,0 +1,69 @@
+package lookup
+
+import (
+	"reflect"
+)
+
+// deduper tells the values already seen, compared with == if their type is
+// comparable, or with reflect.DeepEqual.
+type deduper struct {
+	seen map[interface{}]bool
+	// The values which can't be map keys.
+	others []interface{}
+}
+
+// add records v, and returns whether it wasn't seen yet. The values which
+// can't be interfaced, e.g. those of unexported fields, are always new.
+func (d *deduper) add(v reflect.Value) bool {
+	if !v.CanInterface() {
+		return true
+	}
+	i := v.Interface()
+	if i == nil || reflect.TypeOf(i).Comparable() {
+		if ok, added := d.addComparable(i); ok {
+			return added
+		}
+	}
+	for _, o := range d.others {
+		if reflect.DeepEqual(o, i) {
+			return false
+		}
+	}
+	d.others = append(d.others, i)
+	return true
+}
+
+// addComparable is add for the values of comparable types. It returns false
+// if i can't be a map key after all, e.g. a struct holding a map in an
+// interface field.
+func (d *deduper) addComparable(i interface{}) (ok, added bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	if d.seen == nil {
+		d.seen = make(map[interface{}]bool)
+	}
+	if d.seen[i] {
+		return true, false
+	}
+	d.seen[i] = true
+	return true, true
+}
+
+// dedupeValue returns the slice v without the duplicates of its elements,
+// keeping the first of each in order.
+func dedupeValue(v reflect.Value) reflect.Value {
+	if !v.IsValid() || v.Len() < 2 {
+		return v
+	}
+	var d deduper
+	out := reflect.MakeSlice(v.Type(), 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		if e := v.Index(i); d.add(e) {
+			out = reflect.Append(out, e)
+		}
+	}
+	return out
+}
@@ -0,0 +1,58 @@
+package lookup
+
+import (
+	"reflect"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestLookup_DedupeAggregated(c *C) {
+	fixture := MyStruct{StructSlice: []*MyStruct{
+		{String: "foo", StructSlice: []*MyStruct{{String: "bar"}, {String: "foo"}}},
+		{String: "foo", StructSlice: []*MyStruct{{String: "bar"}, {String: "baz"}}},
+		{String: "qux"},
+	}}
+
+	value, err := Lookup(fixture, "StructSlice.String", DedupeAggregated())
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []string{"foo", "qux"})
+	value, err = Lookup(fixture, "StructSlice.StructSlice.String", DedupeAggregated())
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []string{"bar", "foo", "baz"})
+	value, err = Lookup(fixture, "StructSlice.String")
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []string{"foo", "foo", "qux"})
+
+	n, err := Count(fixture, "StructSlice.String", DedupeAggregated())
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 2)
+
+	values, err := LookupMany(fixture, []string{"StructSlice.String"}, DedupeAggregated())
+	c.Assert(err, IsNil)
+	c.Assert(values["StructSlice.String"], DeepEquals, []string{"foo", "qux"})
+
+	it, err := Iterate(fixture, "StructSlice.StructSlice.String", DedupeAggregated())
+	c.Assert(err, IsNil)
+	var strings []interface{}
+	for it.Next() {
+		strings = append(strings, it.Value())
+	}
+	c.Assert(it.Err(), IsNil)
+	c.Assert(strings, DeepEquals, []interface{}{"bar", "foo", "baz"})
+}
+
+func (s *S) TestLookup_DedupeAggregatedDeepEqual(c *C) {
+	type item struct{ Tags []string }
+	fixture := map[string][]item{"list": {{Tags: []string{"a"}}, {Tags: []string{"a"}}, {Tags: []string{"b"}}}}
+
+	value, err := Lookup(fixture, "list[*]", DedupeAggregated())
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []item{{Tags: []string{"a"}}, {Tags: []string{"b"}}})
+}
+
+func (s *S) TestDedupeValue(c *C) {
+	type withInterface struct{ I interface{} }
+	// Comparable type, but the dynamic value isn't hashable.
+	v := dedupeValue(reflect.ValueOf([]withInterface{{I: []int{1}}, {I: []int{1}}, {I: 1}}))
+	c.Assert(v.Interface(), DeepEquals, []withInterface{{I: []int{1}}, {I: 1}})
+}
@@ -0,0 +1,64 @@
+package lookup
+
+import (
+	"reflect"
+	"sort"
+)
+
+// ChangeType is the type of a Change.
+type ChangeType int
+
+const (
+	// Added is the type of a leaf only found in the new value.
+	Added ChangeType = iota
+	// Removed is the type of a leaf only found in the old value.
+	Removed
+	// Modified is the type of a leaf found in both values, with different
+	// contents.
+	Modified
+)
+
+// Change is a difference between two values, at the lookup path of a leaf.
+type Change struct {
+	Type ChangeType
+	Path string
+	// Old is the value at Path in the old value, nil if it was Added.
+	Old interface{}
+	// New is the value at Path in the new value, nil if it was Removed.
+	New interface{}
+}
+
+// Diff returns the changes from a to b, sorted by path. The leaves of a and b
+// are those of Flatten, compared with reflect.DeepEqual, so the paths of the
+// changes can be looked up on a or b, or used to patch one of them.
+func Diff(a, b interface{}, options ...Option) ([]Change, error) {
+	opts := NewOptions(options...)
+	before, err := Flatten(a, opts)
+	if err != nil {
+		return nil, err
+	}
+	after, err := Flatten(b, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for path, o := range before {
+		n, ok := after[path]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Type: Removed, Path: path, Old: o})
+		case !reflect.DeepEqual(o, n):
+			changes = append(changes, Change{Type: Modified, Path: path, Old: o, New: n})
+		}
+	}
+	for path, n := range after {
+		if _, ok := before[path]; !ok {
+			changes = append(changes, Change{Type: Added, Path: path, New: n})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
@@ -0,0 +1,40 @@
+package lookup
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestDiff(c *C) {
+	a := map[string]interface{}{
+		"name":  "foo",
+		"tags":  []string{"a", "b"},
+		"inner": map[string]interface{}{"x": 1, "y": 2},
+	}
+	b := map[string]interface{}{
+		"name":  "bar",
+		"tags":  []string{"a"},
+		"inner": map[string]interface{}{"x": 1, "z": nil},
+	}
+
+	changes, err := Diff(a, b, Options{})
+	c.Assert(err, IsNil)
+	c.Assert(changes, DeepEquals, []Change{
+		{Type: Removed, Path: "inner.y", Old: 2},
+		{Type: Added, Path: "inner.z"},
+		{Type: Modified, Path: "name", Old: "foo", New: "bar"},
+		{Type: Removed, Path: "tags[1]", Old: "b"},
+	})
+
+	for _, change := range changes {
+		if change.Type == Added {
+			continue
+		}
+		value, err := Lookup(a, change.Path, Options{})
+		c.Assert(err, IsNil)
+		c.Assert(value, DeepEquals, change.Old)
+	}
+
+	changes, err = Diff(a, a, Options{})
+	c.Assert(err, IsNil)
+	c.Assert(changes, HasLen, 0)
+}
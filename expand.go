@@ -0,0 +1,414 @@
+package lookup
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var jsonNumberType = reflect.TypeOf(json.Number(""))
+
+const (
+	// xmlAttrPrefix prefixes the attributes of an expanded XML element.
+	xmlAttrPrefix = "@"
+	// xmlTextKey holds the text of an expanded XML element having attributes
+	// or children.
+	xmlTextKey = "#text"
+)
+
+// encodedBytes returns the content of v if it's a non-empty string or byte
+// slice (including named types such as json.RawMessage).
+func encodedBytes(v reflect.Value) ([]byte, bool) {
+	if !v.IsValid() || v.IsZero() {
+		return nil, false
+	}
+	switch {
+	case v.Kind() == reflect.String:
+		return []byte(v.String()), true
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		return v.Bytes(), true
+	}
+	return nil, false
+}
+
+// ExpansionCache caches the values decoded from strings and byte slices by the
+// expansion options, so repeated lookups on the same objects don't decode the
+// same documents again. The cached values are shared by all the lookups using
+// the cache, and must not be modified. It's safe for concurrent use.
+type ExpansionCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[expansionKey]reflect.Value
+}
+
+// expansionKey identifies an encoded document, and the expansion options it's
+// decoded with.
+type expansionKey struct {
+	json, base64JSON, xml bool
+	useNumber             bool
+	decodeJSON            uintptr
+	maxBytes, maxDepth    int
+	data                  string
+}
+
+// NewExpansionCache returns an ExpansionCache holding at most maxEntries
+// documents. If maxEntries is 0, the cache isn't bounded.
+func NewExpansionCache(maxEntries int) *ExpansionCache {
+	return &ExpansionCache{maxEntries: maxEntries, entries: make(map[expansionKey]reflect.Value)}
+}
+
+// Len returns the number of documents in the cache.
+func (c *ExpansionCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+func (c *ExpansionCache) get(k expansionKey) (reflect.Value, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.entries[k]
+	return v, ok
+}
+
+func (c *ExpansionCache) put(k expansionKey, v reflect.Value) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		// Evict an arbitrary entry.
+		for k := range c.entries {
+			delete(c.entries, k)
+			break
+		}
+	}
+	c.entries[k] = v
+}
+
+// expandValue returns the value decoded from v if v is expandable according to
+// opts, or v itself. The result is cached in opts.ExpansionCache, if any.
+func expandValue(v reflect.Value, opts Options) (reflect.Value, error) {
+	data, ok := encodedBytes(v)
+	if opts.ExpansionCache == nil || !ok {
+		v, _, err := decodeValue(v, opts)
+		return v, err
+	}
+
+	k := expansionKey{
+		json:       opts.ExpandStringAsJSON,
+		base64JSON: opts.ExpandBase64JSON,
+		xml:        opts.ExpandStringAsXML,
+		useNumber:  opts.JSONUseNumber,
+		maxBytes:   opts.MaxExpandBytes,
+		maxDepth:   opts.MaxExpandDepth,
+		data:       string(data),
+	}
+	if opts.DecodeJSON != nil {
+		k.decodeJSON = reflect.ValueOf(opts.DecodeJSON).Pointer()
+	}
+	if out, ok := opts.ExpansionCache.get(k); ok {
+		if !out.IsValid() {
+			// Not expandable.
+			return v, nil
+		}
+		return out, nil
+	}
+
+	out, expanded, err := decodeValue(v, opts)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if !expanded {
+		opts.ExpansionCache.put(k, reflect.Value{})
+		return v, nil
+	}
+	opts.ExpansionCache.put(k, out)
+	return out, nil
+}
+
+// Expander expands the values found along a path into values which can be
+// traversed further, e.g. by decoding an encoded document.
+type Expander interface {
+	// Expand returns the value expanded from v and true, or false if v isn't
+	// expandable by this Expander. An error fails the lookup.
+	Expand(v interface{}, opts Options) (interface{}, bool, error)
+}
+
+// ExpanderFunc is an Expander calling itself.
+type ExpanderFunc func(v interface{}, opts Options) (interface{}, bool, error)
+
+// Expand calls f.
+func (f ExpanderFunc) Expand(v interface{}, opts Options) (interface{}, bool, error) {
+	return f(v, opts)
+}
+
+type jsonExpander struct{}
+
+func (jsonExpander) Expand(v interface{}, opts Options) (interface{}, bool, error) {
+	return expandStringAsJSON(reflect.ValueOf(v), opts)
+}
+
+type base64JSONExpander struct{}
+
+func (base64JSONExpander) Expand(v interface{}, opts Options) (interface{}, bool, error) {
+	return expandBase64JSON(reflect.ValueOf(v), opts)
+}
+
+type xmlExpander struct{}
+
+func (xmlExpander) Expand(v interface{}, opts Options) (interface{}, bool, error) {
+	return expandStringAsXML(reflect.ValueOf(v), opts)
+}
+
+// expanders returns the built-in expanders enabled by opts, followed by
+// opts.Expanders.
+func expanders(opts Options) []Expander {
+	var e []Expander
+	if opts.ExpandStringAsJSON {
+		e = append(e, jsonExpander{})
+	}
+	if opts.ExpandBase64JSON {
+		e = append(e, base64JSONExpander{})
+	}
+	if opts.ExpandStringAsXML {
+		e = append(e, xmlExpander{})
+	}
+	if opts.UnpackAny {
+		e = append(e, anyExpander{})
+	}
+	return append(e, opts.Expanders...)
+}
+
+// decodeValue returns the value expanded from v by the first accepting
+// expander, or v itself and false. A ResourceExhausted error is returned if v
+// exceeds opts.MaxExpandBytes.
+func decodeValue(v reflect.Value, opts Options) (reflect.Value, bool, error) {
+	e := expanders(opts)
+	if len(e) == 0 {
+		return v, false, nil
+	}
+
+	expanded := false
+	for {
+		if data, ok := encodedBytes(v); ok && opts.MaxExpandBytes > 0 && len(data) > opts.MaxExpandBytes {
+			return reflect.Value{}, false, limitExceeded(&opts, "MaxExpandBytes", status.Errorf(codes.ResourceExhausted, "value of %d bytes exceeds the max expansion size of %d bytes", len(data), opts.MaxExpandBytes))
+		}
+		if !v.IsValid() || !v.CanInterface() {
+			return v, expanded, nil
+		}
+
+		var out interface{}
+		ok := false
+		for _, x := range e {
+			var err error
+			if out, ok, err = x.Expand(v.Interface(), opts); err != nil {
+				return reflect.Value{}, false, err
+			}
+			if ok {
+				break
+			}
+		}
+		if !ok {
+			return v, expanded, nil
+		}
+		v = getRealValue(reflect.ValueOf(out))
+		expanded = true
+
+		// An expanded value may itself be an encoded document, e.g. a JSON
+		// string, but not a json.Number.
+		if _, ok := encodedBytes(v); !ok || v.Type() == jsonNumberType {
+			return v, true, nil
+		}
+	}
+}
+
+// If the input value is expandable as JSON, returns the decoded JSON value,
+// which can be an object, an array or a scalar. Strings, []byte and
+// json.RawMessage are expandable.
+// The error is only set if the document exceeds opts.MaxExpandDepth.
+func expandStringAsJSON(v reflect.Value, opts Options) (interface{}, bool, error) {
+	data, ok := encodedBytes(v)
+	if !ok {
+		return nil, false, nil
+	}
+	if opts.MaxExpandDepth > 0 && jsonDepth(data) > opts.MaxExpandDepth {
+		return nil, false, limitExceeded(&opts, "MaxExpandDepth", status.Errorf(codes.ResourceExhausted, "JSON document exceeds the max expansion depth of %d", opts.MaxExpandDepth))
+	}
+	jsonValue, err := decodeJSON(data, opts)
+	// Only returns the JSON instance when marshal succeeds.
+	if err != nil || jsonValue == nil {
+		if err != nil {
+			logDebug(&opts, "lookup: value not expanded", "format", "JSON", "error", err)
+		}
+		return nil, false, nil
+	}
+	return jsonValue, true, nil
+}
+
+// jsonDepth returns the maximum nesting of objects and arrays in the JSON
+// document data, without decoding it.
+func jsonDepth(data []byte) int {
+	depth, max := 0, 0
+	inString, escaped := false, false
+	for _, c := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{' || c == '[':
+			depth++
+			if depth > max {
+				max = depth
+			}
+		case c == '}' || c == ']':
+			depth--
+		}
+	}
+	return max
+}
+
+// decodeJSON decodes the JSON document data with opts.DecodeJSON if set, or
+// encoding/json, honoring opts.JSONUseNumber.
+func decodeJSON(data []byte, opts Options) (interface{}, error) {
+	if opts.DecodeJSON != nil {
+		return opts.DecodeJSON(data)
+	}
+	if !opts.JSONUseNumber {
+		var v interface{}
+		err := json.Unmarshal(data, &v)
+		return v, err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	// Like json.Unmarshal, reject trailing data.
+	if _, err := d.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid data after the JSON document")
+	}
+	return v, nil
+}
+
+// If the input value is a base64-encoded JSON object or array, returns the
+// decoded JSON value. Both the standard and URL encodings are accepted, with
+// or without padding.
+func expandBase64JSON(v reflect.Value, opts Options) (interface{}, bool, error) {
+	data, ok := encodedBytes(v)
+	if !ok {
+		return nil, false, nil
+	}
+	s := strings.TrimSpace(string(data))
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		decoded, err := enc.DecodeString(s)
+		if err != nil {
+			continue
+		}
+		out, ok, err := expandStringAsJSON(reflect.ValueOf(decoded), opts)
+		if !ok {
+			return nil, false, err
+		}
+		// Only documents are expanded, any short string is likely to decode
+		// to a JSON scalar.
+		switch out.(type) {
+		case map[string]interface{}, []interface{}:
+			return out, true, nil
+		}
+		return nil, false, nil
+	}
+	return nil, false, nil
+}
+
+// If the input value is expandable as XML, returns the decoded document as a
+// map[string]interface{} holding its root element, see decodeXMLElement.
+// The error is only set if the document exceeds opts.MaxExpandDepth.
+func expandStringAsXML(v reflect.Value, opts Options) (interface{}, bool, error) {
+	data, ok := encodedBytes(v)
+	if !ok || !bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		return nil, false, nil
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			logDebug(&opts, "lookup: value not expanded", "format", "XML", "error", err)
+			return nil, false, nil
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			root, err := decodeXMLElement(d, start, 1, opts.MaxExpandDepth)
+			if status.Code(err) == codes.ResourceExhausted {
+				return nil, false, limitExceeded(&opts, "MaxExpandDepth", err)
+			}
+			if err != nil {
+				logDebug(&opts, "lookup: value not expanded", "format", "XML", "error", err)
+				return nil, false, nil
+			}
+			return map[string]interface{}{start.Name.Local: root}, true, nil
+		}
+	}
+}
+
+// decodeXMLElement decodes the element opened by start. An element with only
+// text is decoded as a string. Otherwise it's decoded as a map holding its
+// attributes prefixed with "@", its children by name (as a []interface{} if
+// the name is repeated) and its text, if any, under "#text". depth is the
+// depth of the element, checked against maxDepth if not 0.
+func decodeXMLElement(d *xml.Decoder, start xml.StartElement, depth, maxDepth int) (interface{}, error) {
+	if maxDepth > 0 && depth > maxDepth {
+		return nil, status.Errorf(codes.ResourceExhausted, "XML document exceeds the max expansion depth of %d", maxDepth)
+	}
+	node := make(map[string]interface{})
+	for _, attr := range start.Attr {
+		node[xmlAttrPrefix+attr.Name.Local] = attr.Value
+	}
+
+	var text strings.Builder
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			child, err := decodeXMLElement(d, tok, depth+1, maxDepth)
+			if err != nil {
+				return nil, err
+			}
+			name := tok.Name.Local
+			switch prev := node[name].(type) {
+			case nil:
+				node[name] = child
+			case []interface{}:
+				node[name] = append(prev, child)
+			default:
+				node[name] = []interface{}{prev, child}
+			}
+		case xml.CharData:
+			text.Write(tok)
+		case xml.EndElement:
+			s := strings.TrimSpace(text.String())
+			if len(node) == 0 {
+				return s, nil
+			}
+			if s != "" {
+				node[xmlTextKey] = s
+			}
+			return node, nil
+		}
+	}
+}
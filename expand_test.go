@@ -0,0 +1,209 @@
+package lookup
+
+import (
+	"encoding/json"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+var xmlFixture = map[string]string{
+	"payload": `<?xml version="1.0"?>
+	<user id="42">
+		<name>Bob</name>
+		<email type="work">bob@example.com</email>
+		<group>admin</group>
+		<group>dev</group>
+	</user>`,
+}
+
+func (s *S) TestExpandXML(c *C) {
+	opts := Options{ExpandStringAsXML: true}
+
+	value, err := Lookup(xmlFixture, "payload.user.name", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "Bob")
+
+	value, err = Lookup(xmlFixture, "payload.user.@id", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "42")
+
+	value, err = Lookup(xmlFixture, "payload.user.email.#text", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "bob@example.com")
+
+	value, err = Lookup(xmlFixture, "payload.user.group[1]", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "dev")
+
+	_, err = Lookup(map[string]string{"payload": "<user>"}, "payload.user", opts)
+	c.Assert(err, NotNil)
+}
+
+func (s *S) TestExpandBase64JSON(c *C) {
+	fixture := map[string]interface{}{
+		// {"sub":"1234","roles":["admin"]}
+		"jwt":  "eyJzdWIiOiIxMjM0Iiwicm9sZXMiOlsiYWRtaW4iXX0",
+		"std":  "eyJmb28iOiJiYXIifQ==",
+		"word": "MTIz",
+	}
+	opts := Options{ExpandBase64JSON: true}
+
+	value, err := Lookup(fixture, "jwt.roles[0]", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "admin")
+
+	value, err = Lookup(fixture, "std.foo", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "bar")
+
+	_, err = Lookup(fixture, "std.foo", Options{})
+	c.Assert(err, NotNil)
+
+	_, err = Lookup(fixture, "word.foo", opts)
+	c.Assert(err, NotNil)
+}
+
+func (s *S) TestExpandPaths(c *C) {
+	fixture := map[string]interface{}{
+		"allowed": map[string]string{"payload": `{"foo": "bar"}`},
+		"other":   map[string]string{"payload": `{"foo": "bar"}`},
+		"list":    []string{`{"foo": "bar"}`, `{"foo": "baz"}`},
+	}
+	opts := Options{
+		ExpandStringAsJSON: true,
+		ExpandPaths:        []string{"allowed.payload", "list"},
+	}
+
+	value, err := Lookup(fixture, "allowed.payload.foo", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "bar")
+
+	_, err = Lookup(fixture, "other.payload.foo", opts)
+	c.Assert(err, NotNil)
+
+	value, err = Lookup(fixture, "list[1].foo", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "baz")
+
+	value, err = Lookup(fixture, "list.foo", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []string{"bar", "baz"})
+}
+
+func (s *S) TestExpansionCache(c *C) {
+	cache := NewExpansionCache(2)
+	opts := Options{ExpandStringAsJSON: true, ExpansionCache: cache}
+
+	for i := 0; i < 2; i++ {
+		value, err := Lookup(structFixture, "JSONString.Struct.Substring", opts)
+		c.Assert(err, IsNil)
+		c.Assert(value, Equals, "Abcd")
+	}
+	c.Assert(cache.Len(), Equals, 1)
+
+	// Strings which aren't expandable are cached too.
+	_, err := Lookup(structFixture, "String.foo", opts)
+	c.Assert(err, NotNil)
+	c.Assert(cache.Len(), Equals, 2)
+
+	value, err := Lookup(structFixture, "JSONList[2].foo", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "bar")
+	c.Assert(cache.Len(), Equals, 2)
+}
+
+func (s *S) TestExpandJSONUseNumber(c *C) {
+	fixture := map[string]string{"doc": `{"id": 9007199254740993, "list": [1]}`}
+
+	value, err := Lookup(fixture, "doc.id", Options{ExpandStringAsJSON: true, JSONUseNumber: true})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, json.Number("9007199254740993"))
+
+	value, err = Lookup(fixture, "doc.list[0]", Options{ExpandStringAsJSON: true, JSONUseNumber: true})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, json.Number("1"))
+
+	value, err = Lookup(fixture, "doc.id", Options{ExpandStringAsJSON: true})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, float64(9007199254740993))
+
+	_, err = Lookup(map[string]string{"doc": `{"id": 1} trailing`}, "doc.id", Options{ExpandStringAsJSON: true, JSONUseNumber: true})
+	c.Assert(err, NotNil)
+}
+
+func (s *S) TestExpandDecodeJSON(c *C) {
+	decode := func(data []byte) (interface{}, error) {
+		return map[string]interface{}{"len": len(data)}, nil
+	}
+	value, err := Lookup(map[string]string{"doc": "abc"}, "doc.len", Options{ExpandStringAsJSON: true, DecodeJSON: decode})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 3)
+}
+
+func (s *S) TestExpandLimits(c *C) {
+	fixture := map[string]string{
+		"doc": `{"a": {"b": {"c": "d"}}}`,
+		"xml": `<a><b><c>d</c></b></a>`,
+	}
+
+	value, err := Lookup(fixture, "doc.a.b.c", Options{ExpandStringAsJSON: true, MaxExpandBytes: 64, MaxExpandDepth: 3})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "d")
+
+	_, err = Lookup(fixture, "doc.a.b.c", Options{ExpandStringAsJSON: true, MaxExpandBytes: 10})
+	c.Assert(status.Code(err), Equals, codes.ResourceExhausted)
+
+	_, err = Lookup(fixture, "doc.a.b.c", Options{ExpandStringAsJSON: true, MaxExpandDepth: 2})
+	c.Assert(status.Code(err), Equals, codes.ResourceExhausted)
+
+	// Brackets in strings don't count.
+	value, err = Lookup(map[string]string{"doc": `{"a": "[[[{"}`}, "doc.a", Options{ExpandStringAsJSON: true, MaxExpandDepth: 1})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "[[[{")
+
+	value, err = Lookup(fixture, "xml.a.b.c", Options{ExpandStringAsXML: true, MaxExpandDepth: 3})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "d")
+
+	_, err = Lookup(fixture, "xml.a.b.c", Options{ExpandStringAsXML: true, MaxExpandDepth: 2})
+	c.Assert(status.Code(err), Equals, codes.ResourceExhausted)
+}
+
+func (s *S) TestExpanders(c *C) {
+	// Expands "key=value;..." strings.
+	kv := ExpanderFunc(func(v interface{}, opts Options) (interface{}, bool, error) {
+		s, ok := v.(string)
+		if !ok || !strings.Contains(s, "=") {
+			return nil, false, nil
+		}
+		m := make(map[string]string)
+		for _, pair := range strings.Split(s, ";") {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				return nil, false, status.Errorf(codes.InvalidArgument, "invalid pair %q", pair)
+			}
+			m[kv[0]] = kv[1]
+		}
+		return m, true, nil
+	})
+	fixture := map[string]string{
+		"kv":      "foo=bar;qux=baz",
+		"json":    `{"foo": "a=b"}`,
+		"invalid": "foo=bar;qux",
+	}
+	opts := Options{ExpandStringAsJSON: true, Expanders: []Expander{kv}}
+
+	value, err := Lookup(fixture, "kv.qux", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "baz")
+
+	value, err = Lookup(fixture, "json.foo.a", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "b")
+
+	_, err = Lookup(fixture, "invalid.foo", opts)
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+}
@@ -0,0 +1,130 @@
+package lookup
+
+import (
+	"reflect"
+	"sync"
+)
+
+// structFieldsCache caches the fastFields of the struct types.
+var structFieldsCache sync.Map // map[reflect.Type]*fastFields
+
+// canFastLookup returns whether opts allow fastLookup: values are neither
+// expanded nor unwrapped, fields are promoted, and segments aren't reported.
+func canFastLookup(opts *Options) bool {
+	return !opts.ExpandStringAsJSON && !opts.ExpandBase64JSON && !opts.ExpandStringAsXML &&
+		!opts.UnpackAny && len(opts.Expanders) == 0 &&
+		!opts.UnwrapWrappers && !opts.ConvertTimes && !opts.UnwrapSQLNull && !opts.UnwrapValuers &&
+		!opts.FlatKeys && !opts.NoPromotedFields && opts.OnSegment == nil
+}
+
+// fastLookup resolves path on i without splitting it, for paths made of keys,
+// with numeric indexes or not, resolving to the Go names of struct fields or
+// to the keys of maps with string keys. Such lookups don't allocate, except
+// for map types other than map[string]interface{}. It returns false if the
+// lookup needs the full traversal, e.g. to aggregate, to match a key with opts
+// or to report a missing key, so the result of the full traversal never
+// differs.
+func fastLookup(i interface{}, path string, opts *Options) (reflect.Value, bool) {
+	v := reflect.ValueOf(i)
+	s := newPathScanner(path, opts)
+	for depth := 1; s.scan(); depth++ {
+		if opts.MaxDepth > 0 && depth > opts.MaxDepth {
+			return reflect.Value{}, false
+		}
+		key, index, err := parseIndex(s.part)
+		if err != nil || index == wildcardIndex || index == filterIndex {
+			return reflect.Value{}, false
+		}
+
+		var ok bool
+		if v, ok = fastSegment(v, key, index); !ok {
+			return reflect.Value{}, false
+		}
+	}
+	return v, v.CanInterface()
+}
+
+// fastSegment resolves the path segment made of key and index on v as
+// getSegment does, returning false if it can't be done by fastLookup.
+func fastSegment(v reflect.Value, key string, index int) (reflect.Value, bool) {
+	// For compatibility, the numeric index of a key looked up on a pointer or
+	// an interface is ignored, as by getSegment.
+	if index >= 0 && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		index = noIndex
+	}
+
+	v = getRealValue(v)
+	if !v.IsValid() {
+		return reflect.Value{}, false
+	}
+	if t := v.Type(); hasMethodKeys(t) || t == syncMapType {
+		return reflect.Value{}, false
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		fields := fastStructFields(v.Type())
+		index, ok := fields.indexes[key]
+		if !ok || fields.resolver == valueResolver || (fields.resolver == pointerResolver && v.CanAddr()) {
+			return reflect.Value{}, false
+		}
+		v = fieldByIndex(v, index)
+	case reflect.Map:
+		if !v.CanInterface() {
+			return reflect.Value{}, false
+		}
+		if m, ok := v.Interface().(map[string]interface{}); ok {
+			value, ok := m[key]
+			if !ok {
+				return reflect.Value{}, false
+			}
+			v = reflect.ValueOf(value)
+			break
+		}
+		kt := v.Type().Key()
+		if kt.Kind() != reflect.String || reflect.PtrTo(kt).Implements(textUnmarshalerType) {
+			return reflect.Value{}, false
+		}
+		if _, ok := asPathResolver(v); ok {
+			return reflect.Value{}, false
+		}
+		v = v.MapIndex(reflect.ValueOf(key).Convert(kt))
+	default:
+		return reflect.Value{}, false
+	}
+
+	v = getRealValue(v)
+	if !v.IsValid() || index == noIndex {
+		return v, v.IsValid()
+	}
+	if v.Kind() != reflect.Slice || index < 0 || index >= v.Len() {
+		return reflect.Value{}, false
+	}
+	v = getRealValue(v.Index(index))
+	return v, v.IsValid()
+}
+
+// fastFields is the information about a struct type needed by fastSegment.
+type fastFields struct {
+	// The index of the fields addressable by their Go name.
+	indexes map[string][]int
+	// How the struct implements PathResolver, resolving the keys itself.
+	resolver resolverKind
+}
+
+// fastStructFields returns the fastFields of the struct type t, with the
+// fields named as structField resolves exact names.
+func fastStructFields(t reflect.Type) *fastFields {
+	cached, ok := structFieldsCache.Load(t)
+	if !ok {
+		fields := structFields(t, Options{})
+		indexes := make(map[string][]int, len(fields))
+		for _, f := range fields {
+			if _, ok := indexes[f.Name]; !ok {
+				indexes[f.Name] = f.Index
+			}
+		}
+		cached, _ = structFieldsCache.LoadOrStore(t, &fastFields{indexes: indexes, resolver: resolverKindOf(t)})
+	}
+	return cached.(*fastFields)
+}
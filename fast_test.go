@@ -0,0 +1,158 @@
+package lookup
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+type fastFixture struct {
+	*fastEmbedded
+	A      fastA
+	Labels map[string]string
+	Meta   map[string]interface{}
+	Nil    *fastA
+	List   []*fastB
+}
+
+type fastEmbedded struct {
+	ID string
+}
+
+type fastA struct {
+	B *fastB
+}
+
+type fastB struct {
+	C int
+}
+
+var fastFixtureValue = &fastFixture{
+	fastEmbedded: &fastEmbedded{ID: "id-1"},
+	A:            fastA{B: &fastB{C: 42}},
+	Labels:       map[string]string{"team": "core"},
+	Meta:         map[string]interface{}{"owner": map[string]interface{}{"name": "bob"}},
+	List:         []*fastB{{C: 1}, nil, {C: 3}},
+}
+
+func (s *S) TestFastLookup(c *C) {
+	for _, path := range []string{
+		"A.B.C",
+		"A.B",
+		"ID",
+		"fastEmbedded.ID",
+		"Labels.team",
+		"Meta.owner.name",
+		"Missing",
+		"Labels.missing",
+		"Nil.B",
+		"A.B.C.D",
+		"",
+		"List[0].C",
+		"List[1].C",
+		"List[5].C",
+		"List[*].C",
+		"List.C",
+		"A[0].B",
+	} {
+		for _, i := range []interface{}{fastFixtureValue, *fastFixtureValue} {
+			opts := Options{}
+			v, ok := fastLookup(i, path, &opts)
+
+			t := newTraversal(context.Background(), opts)
+			want, err := t.lookup(reflect.ValueOf(i), splitPath(path, &opts), nil, 0)
+			if !ok {
+				continue
+			}
+			c.Assert(err, IsNil, Commentf("path %q", path))
+			c.Assert(v.Interface(), DeepEquals, want.Interface(), Commentf("path %q", path))
+		}
+	}
+
+	_, ok := fastLookup(fastFixtureValue, "Meta.owner.name", &Options{})
+	c.Assert(ok, Equals, true)
+	_, ok = fastLookup(structFixture, "StructSlice.String", &Options{})
+	c.Assert(ok, Equals, false)
+	v, ok := fastLookup(structFixture, "StructSlice[1].String", &Options{})
+	c.Assert(ok, Equals, true)
+	c.Assert(v.Interface(), Equals, "qux")
+	_, ok = fastLookup(structFixture, "StructSlice[*].String", &Options{})
+	c.Assert(ok, Equals, false)
+	_, ok = fastLookup(fastFixtureValue, "A.B.C", &Options{MaxDepth: 2})
+	c.Assert(ok, Equals, false)
+	c.Assert(canFastLookup(&Options{ExpandStringAsJSON: true}), Equals, false)
+
+	value, err := Lookup(fastFixtureValue, "A->B->C", Options{SplitToken: "->"})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 42)
+}
+
+func (s *S) TestFastLookup_Allocs(c *C) {
+	allocs := testing.AllocsPerRun(100, func() {
+		Lookup(fastFixtureValue, "A.B.C", Options{})
+	})
+	// Boxing the int result.
+	c.Assert(allocs <= 1, Equals, true, Commentf("%v allocations", allocs))
+
+	allocs = testing.AllocsPerRun(100, func() {
+		Lookup(fastFixtureValue, "Meta.owner.name", Options{})
+	})
+	c.Assert(allocs, Equals, 0.0)
+}
+
+func BenchmarkLookup_Struct(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Lookup(fastFixtureValue, "A.B.C", Options{})
+	}
+}
+
+func BenchmarkLookup_StructFull(b *testing.B) {
+	b.ReportAllocs()
+	opts := Options{}
+	for i := 0; i < b.N; i++ {
+		t := newTraversal(context.Background(), opts)
+		t.lookup(reflect.ValueOf(fastFixtureValue), splitPath("A.B.C", &opts), nil, 0)
+	}
+}
+
+func BenchmarkLookup_Map(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Lookup(fastFixtureValue, "Meta.owner.name", Options{})
+	}
+}
+
+func BenchmarkLookup_MapFull(b *testing.B) {
+	b.ReportAllocs()
+	opts := Options{}
+	for i := 0; i < b.N; i++ {
+		t := newTraversal(context.Background(), opts)
+		t.lookup(reflect.ValueOf(fastFixtureValue), splitPath("Meta.owner.name", &opts), nil, 0)
+	}
+}
+
+func BenchmarkLookup_Aggregation(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Lookup(structFixture, "StructSlice.StructSlice.String", Options{})
+	}
+}
+
+func BenchmarkCompiledPath_Aggregation(b *testing.B) {
+	p := MustCompile("StructSlice.StructSlice.String", Options{})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p.Lookup(structFixture)
+	}
+}
+
+func BenchmarkLookup_AggregationValues(b *testing.B) {
+	list := make([]MyStruct, 100)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Lookup(list, "String", Options{})
+	}
+}
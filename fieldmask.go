@@ -0,0 +1,297 @@
+package lookup
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// fieldMaskSplitToken separates the fields of a FieldMask path.
+const fieldMaskSplitToken = "."
+
+// ExtractFieldMask returns a copy of i, a struct, a map or a pointer to one of
+// them, holding only the fields listed by mask. The fields of the paths are
+// resolved as with Lookup, so Options.TagKey set to "protobuf" addresses the
+// fields of generated messages by their .proto names, as FieldMask paths do.
+// The fields are copied shallowly.
+func ExtractFieldMask(i interface{}, mask *fieldmaskpb.FieldMask, options ...Option) (interface{}, error) {
+	opts := NewOptions(options...)
+	v := reflect.ValueOf(i)
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	out := reflect.New(v.Type())
+	if err := applyFieldMask(out.Elem(), v, mask, opts); err != nil {
+		return nil, err
+	}
+	return out.Elem().Interface(), nil
+}
+
+// ApplyFieldMask copies the fields listed by mask from src to dst, which must
+// be a pointer to a value of the same type as src, as an update request
+// carrying a FieldMask does. A field missing from src, e.g. under a nil
+// pointer, is cleared in dst. The fields of the paths are resolved as with
+// ExtractFieldMask.
+func ApplyFieldMask(dst, src interface{}, mask *fieldmaskpb.FieldMask, options ...Option) error {
+	opts := NewOptions(options...)
+	d := reflect.ValueOf(dst)
+	if d.Kind() != reflect.Ptr || d.IsNil() {
+		return status.Errorf(codes.InvalidArgument, "destination must be a non-nil pointer, got %T", dst)
+	}
+
+	s := reflect.ValueOf(src)
+	switch {
+	case s.IsValid() && s.Type() == d.Type():
+		s = s.Elem()
+	case !s.IsValid() || s.Type() != d.Type().Elem():
+		return status.Errorf(codes.InvalidArgument, "source of type %T can't be applied to %T", src, dst)
+	}
+	return applyFieldMask(d.Elem(), s, mask, opts)
+}
+
+func applyFieldMask(dst, src reflect.Value, mask *fieldmaskpb.FieldMask, opts Options) error {
+	for _, path := range mask.GetPaths() {
+		if err := copyPath(dst, src, strings.Split(path, fieldMaskSplitToken), opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyPath sets the value at path in dst, which must be settable, to the value
+// at path in src, allocating the pointers, maps and slices on its way. dst is
+// cleared if src is invalid, i.e. if the path isn't found in src. A segment
+// "[i]" addresses the i-th element of a slice, the slices of dst being
+// allocated with the length of those of src. The value is deep copied if
+// opts.CopyResult is set.
+func copyPath(dst, src reflect.Value, path []string, opts Options) error {
+	if len(path) == 0 {
+		if !src.IsValid() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		if opts.CopyResult {
+			src = deepCopy(src)
+		}
+		dst.Set(src)
+		return nil
+	}
+
+	src = getRealValue(src)
+	if dst.Kind() == reflect.Interface {
+		if !src.IsValid() {
+			// Nothing to copy into, nor to clear.
+			return nil
+		}
+		elem := reflect.New(src.Type()).Elem()
+		if !dst.IsNil() && dst.Elem().Type() == src.Type() {
+			elem.Set(dst.Elem())
+		}
+		if err := copyPath(elem, src, path, opts); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	}
+	for dst.Kind() == reflect.Ptr {
+		if dst.IsNil() {
+			if !src.IsValid() {
+				return nil
+			}
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		dst = dst.Elem()
+	}
+
+	if strings.HasPrefix(path[0], indexOpenChar) {
+		return copyIndex(dst, src, path, opts)
+	}
+	key, _, err := parseIndex(path[0])
+	if err != nil {
+		return err
+	}
+	switch dst.Kind() {
+	case reflect.Struct:
+		f, ok, err := structField(dst.Type(), key, opts)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return status.Errorf(codes.InvalidArgument, "field %q not found in %s", key, dst.Type())
+		}
+		// The masks may come from clients, and the unexported fields can't be set.
+		if f.PkgPath != "" {
+			return status.Errorf(codes.InvalidArgument, "field %q of %s is unexported", key, dst.Type())
+		}
+		var value reflect.Value
+		if src.IsValid() {
+			value = fieldByIndex(src, f.Index)
+		}
+		return copyPath(settableFieldByIndex(dst, f.Index), value, path[1:], opts)
+	case reflect.Map:
+		k, ok := mapKey(dst.Type().Key(), key)
+		if !ok {
+			return status.Errorf(codes.InvalidArgument, "key %q is not a valid key of %s", key, dst.Type())
+		}
+		var value reflect.Value
+		if src.IsValid() {
+			value = src.MapIndex(k)
+			// The key of src matched, e.g. under CaseInsensitive.
+			if !value.IsValid() && hasKeyNames(src.Type().Key(), opts) {
+				index := newKeyIndex(src, opts)
+				i, err := index.match(key, opts)
+				if err != nil {
+					return err
+				}
+				if i != -1 {
+					k, value = index.keys[i], src.MapIndex(index.keys[i])
+				}
+			}
+		}
+		if !value.IsValid() && len(path) == 1 {
+			if !dst.IsNil() {
+				dst.SetMapIndex(k, reflect.Value{})
+			}
+			return nil
+		}
+
+		// Map elements aren't addressable, so the element is updated through
+		// a copy.
+		elem := reflect.New(dst.Type().Elem()).Elem()
+		if existing := dst.MapIndex(k); existing.IsValid() {
+			elem.Set(existing)
+		}
+		if err := copyPath(elem, value, path[1:], opts); err != nil {
+			return err
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(dst.Type()))
+		}
+		dst.SetMapIndex(k, elem)
+		return nil
+	default:
+		return status.Errorf(codes.InvalidArgument, "key %q can't be resolved on a %s", key, dst.Kind())
+	}
+}
+
+// copyIndex is copyPath for path starting with an index segment "[i]".
+func copyIndex(dst, src reflect.Value, path []string, opts Options) error {
+	_, index, err := parseIndex(path[0])
+	if err != nil {
+		return err
+	}
+	if index < 0 {
+		return status.Errorf(codes.InvalidArgument, "index %q must address a single element", path[0])
+	}
+	switch dst.Kind() {
+	case reflect.Slice:
+		if !src.IsValid() || index >= src.Len() {
+			// Nothing to copy, nor to clear.
+			return nil
+		}
+		if dst.Len() < src.Len() {
+			s := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+			reflect.Copy(s, dst)
+			dst.Set(s)
+		}
+	case reflect.Array:
+		if !src.IsValid() {
+			return nil
+		}
+	default:
+		return status.Errorf(codes.InvalidArgument, "index %q can't be resolved on a %s", path[0], dst.Kind())
+	}
+	if index >= dst.Len() {
+		return status.Errorf(codes.InvalidArgument, "index %q out of range", path[0])
+	}
+	return copyPath(dst.Index(index), src.Index(index), path[1:], opts)
+}
+
+// ToFieldMaskPaths validates the lookup paths against the struct or message
+// type t and returns them as canonical FieldMask paths, made of the .proto
+// names of the fields of generated messages, or of the snake_case form of the
+// Go names for other structs. The segments of paths can be Go field names or
+// FieldMask names, without index. Only the last segment of a path may be
+// something else than a struct or a message.
+func ToFieldMaskPaths(t reflect.Type, paths ...string) ([]string, error) {
+	return translateFieldPaths(t, paths, true)
+}
+
+// FromFieldMaskPaths validates the FieldMask paths against the struct or
+// message type t and returns them as lookup paths made of the Go field names.
+func FromFieldMaskPaths(t reflect.Type, paths ...string) ([]string, error) {
+	return translateFieldPaths(t, paths, false)
+}
+
+func translateFieldPaths(t reflect.Type, paths []string, toFieldMask bool) ([]string, error) {
+	out := make([]string, len(paths))
+	for i, path := range paths {
+		segments := strings.Split(path, fieldMaskSplitToken)
+		ty := t
+		for j, segment := range segments {
+			for ty.Kind() == reflect.Ptr {
+				ty = ty.Elem()
+			}
+			if ty.Kind() != reflect.Struct {
+				return nil, status.Errorf(codes.InvalidArgument, "path %q: %q isn't a struct or a message", path, strings.Join(segments[:j], fieldMaskSplitToken))
+			}
+			if strings.Contains(segment, indexOpenChar) {
+				return nil, status.Errorf(codes.InvalidArgument, "path %q: FieldMask paths can't have indexes", path)
+			}
+			f, ok := fieldMaskField(ty, segment, toFieldMask)
+			if !ok {
+				return nil, status.Errorf(codes.InvalidArgument, "path %q: field %q not found in %s", path, segment, ty)
+			}
+			if toFieldMask {
+				segments[j] = fieldMaskName(f)
+			} else {
+				segments[j] = f.Name
+			}
+			ty = f.Type
+		}
+		out[i] = strings.Join(segments, fieldMaskSplitToken)
+	}
+	return out, nil
+}
+
+// fieldMaskField returns the exported field of the struct type t named name in
+// FieldMask paths, or by its Go name if goName is set.
+func fieldMaskField(t reflect.Type, name string, goName bool) (reflect.StructField, bool) {
+	for _, f := range structFields(t, Options{}) {
+		if f.PkgPath != "" {
+			continue
+		}
+		if fieldMaskName(f) == name || (goName && f.Name == name) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// fieldMaskName returns the name of f in FieldMask paths.
+func fieldMaskName(f reflect.StructField) string {
+	if name, ok := tagName(f, "protobuf"); ok {
+		return name
+	}
+	return strcase.ToSnake(f.Name)
+}
+
+// settableFieldByIndex is like reflect.Value.FieldByIndex, but allocates the
+// nil embedded pointers on its way.
+func settableFieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
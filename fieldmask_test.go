@@ -0,0 +1,102 @@
+package lookup
+
+import (
+	"reflect"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/apipb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/sourcecontextpb"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestExtractFieldMask(c *C) {
+	api := &apipb.Api{
+		Name:          "foo",
+		Version:       "v1",
+		SourceContext: &sourcecontextpb.SourceContext{FileName: "foo.proto"},
+		Methods:       []*apipb.Method{{Name: "Get"}},
+	}
+	mask := &fieldmaskpb.FieldMask{Paths: []string{"name", "source_context.file_name"}}
+	value, err := ExtractFieldMask(api, mask, Options{TagKey: "protobuf"})
+	c.Assert(err, IsNil)
+	c.Assert(proto.Equal(value.(*apipb.Api), &apipb.Api{
+		Name:          "foo",
+		SourceContext: &sourcecontextpb.SourceContext{FileName: "foo.proto"},
+	}), Equals, true)
+
+	fixture := map[string]interface{}{
+		"foo": map[string]interface{}{"bar": 1, "qux": 2},
+		"baz": 3,
+	}
+	value, err = ExtractFieldMask(fixture, &fieldmaskpb.FieldMask{Paths: []string{"foo.bar"}}, Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, map[string]interface{}{"foo": map[string]interface{}{"bar": 1}})
+
+	_, err = ExtractFieldMask(api, &fieldmaskpb.FieldMask{Paths: []string{"missing"}}, Options{TagKey: "protobuf"})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+
+	for _, path := range []string{"state", "sizeCache", "unknownFields"} {
+		_, err = ExtractFieldMask(api, &fieldmaskpb.FieldMask{Paths: []string{path}}, WithTagKey("protobuf"))
+		c.Assert(status.Code(err), Equals, codes.InvalidArgument, Commentf("%s", path))
+	}
+}
+
+func (s *S) TestApplyFieldMask(c *C) {
+	target := &apipb.Api{
+		Name:          "foo",
+		Version:       "v1",
+		SourceContext: &sourcecontextpb.SourceContext{FileName: "foo.proto"},
+	}
+	update := &apipb.Api{Name: "bar", Version: "v2"}
+	mask := &fieldmaskpb.FieldMask{Paths: []string{"version", "source_context.file_name"}}
+	err := ApplyFieldMask(target, update, mask, Options{TagKey: "protobuf"})
+	c.Assert(err, IsNil)
+	c.Assert(proto.Equal(target, &apipb.Api{
+		Name:          "foo",
+		Version:       "v2",
+		SourceContext: &sourcecontextpb.SourceContext{},
+	}), Equals, true)
+
+	fixture := map[string]int{"foo": 1, "bar": 2}
+	err = ApplyFieldMask(&fixture, map[string]int{"bar": 3}, &fieldmaskpb.FieldMask{Paths: []string{"foo", "bar"}}, Options{})
+	c.Assert(err, IsNil)
+	c.Assert(fixture, DeepEquals, map[string]int{"bar": 3})
+
+	err = ApplyFieldMask(target, "foo", mask, Options{})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+
+	err = ApplyFieldMask(target, update, &fieldmaskpb.FieldMask{Paths: []string{"sizeCache"}}, Options{TagKey: "protobuf"})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+	c.Assert(err, ErrorMatches, `.*field "sizeCache" of apipb.Api is unexported`)
+}
+
+func (s *S) TestToFieldMaskPaths(c *C) {
+	paths, err := ToFieldMaskPaths(reflect.TypeOf(&apipb.Api{}), "Name", "SourceContext.FileName", "source_context")
+	c.Assert(err, IsNil)
+	c.Assert(paths, DeepEquals, []string{"name", "source_context.file_name", "source_context"})
+
+	paths, err = ToFieldMaskPaths(reflect.TypeOf(MyStruct{}), "StructSlice", "Nested.String")
+	c.Assert(err, IsNil)
+	c.Assert(paths, DeepEquals, []string{"struct_slice", "nested.string"})
+
+	_, err = ToFieldMaskPaths(reflect.TypeOf(&apipb.Api{}), "Methods[0].Name")
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+
+	_, err = ToFieldMaskPaths(reflect.TypeOf(&apipb.Api{}), "Name.Foo")
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+
+	_, err = ToFieldMaskPaths(reflect.TypeOf(&apipb.Api{}), "Missing")
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+}
+
+func (s *S) TestFromFieldMaskPaths(c *C) {
+	paths, err := FromFieldMaskPaths(reflect.TypeOf(&apipb.Api{}), "name", "source_context.file_name")
+	c.Assert(err, IsNil)
+	c.Assert(paths, DeepEquals, []string{"Name", "SourceContext.FileName"})
+
+	_, err = FromFieldMaskPaths(reflect.TypeOf(&apipb.Api{}), "SourceContext")
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+}
@@ -0,0 +1,152 @@
+package lookup
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// keyFilterChar starts the index of a key filter, e.g. Labels[~"app.*"].
+const keyFilterChar = "~"
+
+// maxKeyFilters bounds the number of compiled key filters cached, as the
+// paths may come from the users.
+const maxKeyFilters = 1024
+
+var (
+	// The compiled key filters, by pattern.
+	keyFilters  sync.Map // map[string]*regexp.Regexp
+	keyFiltersN int64
+)
+
+// compileKeyFilter compiles the pattern of a key filter, the index after its
+// "~": a glob quoted as a Go string, e.g. "app.kubernetes.io/*", whose '*'
+// matches any characters and '?' any single one, or a regular expression
+// between slashes, e.g. /^app\./, matching anywhere in the keys unless
+// anchored.
+func compileKeyFilter(pattern string) (*regexp.Regexp, error) {
+	if re, ok := keyFilters.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	var re *regexp.Regexp
+	var err error
+	switch n := keyFilterLen(pattern); {
+	case n != len(pattern):
+		return nil, status.Errorf(codes.InvalidArgument, `invalid key filter %q: must be a quoted glob, e.g. "app.*", or a regular expression between slashes, e.g. /^app\./`, pattern)
+	case pattern[0] == '/':
+		re, err = regexp.Compile(pattern[1 : len(pattern)-1])
+	default:
+		glob, _ := strconv.Unquote(pattern)
+		re, err = regexp.Compile(globExpr(glob))
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid key filter %q: %v", pattern, err)
+	}
+
+	if atomic.AddInt64(&keyFiltersN, 1) <= maxKeyFilters {
+		keyFilters.Store(pattern, re)
+	}
+	return re, nil
+}
+
+// globExpr returns the regular expression matching the whole strings matched
+// by glob.
+func globExpr(glob string) string {
+	var expr strings.Builder
+	expr.WriteString(`^(?s:`)
+	for _, r := range glob {
+		switch r {
+		case '*':
+			expr.WriteString(`.*`)
+		case '?':
+			expr.WriteString(`.`)
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString(`)$`)
+	return expr.String()
+}
+
+// keyFilterLen returns the length of the pattern of the key filter s starts
+// with, after the "[~", or -1 if there is none.
+func keyFilterLen(s string) int {
+	switch {
+	case strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "`"):
+		quoted, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return -1
+		}
+		return len(quoted)
+	case strings.HasPrefix(s, "/"):
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '/':
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// indexOfToken returns the index of the first split token of path, or -1.
+// The tokens in the pattern of a key filter don't split the path, e.g. the
+// dots of Labels[~"app.kubernetes.io/*"].
+func indexOfToken(path, token string) int {
+	if !strings.Contains(path, indexOpenChar+keyFilterChar) {
+		return strings.Index(path, token)
+	}
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path) && isEscapable(path[i+1]):
+			i++
+		case strings.HasPrefix(path[i:], token):
+			return i
+		case strings.HasPrefix(path[i:], indexOpenChar+keyFilterChar):
+			if n := keyFilterLen(path[i+2:]); n != -1 {
+				i += 1 + n
+			}
+		}
+	}
+	return -1
+}
+
+// filterKeys returns a copy of the map v, the value of key, holding only the
+// entries whose keys match the key filter of the path segment part. As when
+// looked up, the keys are matched by their names, e.g. the String of
+// Stringers.
+func filterKeys(v reflect.Value, key, part string) (reflect.Value, error) {
+	if v.Kind() != reflect.Map {
+		return reflect.Value{}, status.Errorf(codes.InvalidArgument, "key %q is not a map", key)
+	}
+	if !v.CanInterface() {
+		return reflect.Value{}, status.Errorf(codes.FailedPrecondition, "the keys of unexported %q can't be filtered", key)
+	}
+	_, suffix := splitIndex(part)
+	re, err := compileKeyFilter(suffix[len(indexOpenChar+keyFilterChar) : len(suffix)-len(indexCloseChar)])
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	stringer := v.Type().Key().Implements(stringerType)
+	filtered := reflect.MakeMap(v.Type())
+	iter := v.MapRange()
+	for iter.Next() {
+		for _, name := range mapKeyNames(iter.Key(), stringer) {
+			if re.MatchString(name) {
+				filtered.SetMapIndex(iter.Key(), iter.Value())
+				break
+			}
+		}
+	}
+	return filtered, nil
+}
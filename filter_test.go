@@ -0,0 +1,105 @@
+package lookup
+
+import (
+	"reflect"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+type Pod struct {
+	Name   string
+	Labels map[string]string
+}
+
+var podsFixture = []Pod{
+	{Name: "web", Labels: map[string]string{"app.kubernetes.io/name": "web", "app.kubernetes.io/version": "1.2", "team": "core"}},
+	{Name: "db", Labels: map[string]string{"app.kubernetes.io/name": "db", "tier": "backend"}},
+}
+
+func (s *S) TestLookup_KeyFilter(c *C) {
+	value, err := Lookup(podsFixture[0], `Labels[~"app.kubernetes.io/*"]`, KeepMapKeys())
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, map[string]string{"app.kubernetes.io/name": "web", "app.kubernetes.io/version": "1.2"})
+
+	value, err = Lookup(podsFixture[0], `Labels[~/^team$|version/]`, KeepMapKeys())
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, map[string]string{"app.kubernetes.io/version": "1.2", "team": "core"})
+
+	value, err = Lookup(podsFixture, `Labels[~"*/name"]`)
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []string{"web", "db"})
+
+	value, err = Lookup(podsFixture[0], `Labels[~"none*"]`, KeepMapKeys())
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, map[string]string{})
+	value, err = Lookup(podsFixture[0], `Labels[~"none*"]`)
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []string{})
+
+	n, err := Count(podsFixture, `Labels[~"app.kubernetes.io/?ame"]`)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 2)
+
+	matches, err := LookupAll(podsFixture[0], `Labels[~"app.*"]`)
+	c.Assert(err, IsNil)
+	c.Assert(matches, DeepEquals, []PathMatch{
+		{Path: "Labels.app.kubernetes.io/name", Value: "web"},
+		{Path: "Labels.app.kubernetes.io/version", Value: "1.2"},
+	})
+
+	p, err := Compile(`Labels[~"team"]`)
+	c.Assert(err, IsNil)
+	value, err = p.Lookup(podsFixture[0])
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []string{"core"})
+
+	ty, err := TypeOf(reflect.TypeOf(Pod{}), `Labels[~"app.*"]`)
+	c.Assert(err, IsNil)
+	c.Assert(ty, Equals, reflect.TypeOf([]string{}))
+}
+
+func (s *S) TestLookup_KeyFilterInvalid(c *C) {
+	for _, path := range []string{`Labels[~app]`, `Labels[~"app]`, `Labels[~/app]`, `Labels[~/(/]`, `Labels[~"a"b]`} {
+		_, err := Lookup(podsFixture[0], path)
+		c.Assert(status.Code(err), Equals, codes.InvalidArgument, Commentf("%s", path))
+	}
+
+	// Not a key filter, whose sentinel index is negative.
+	_, err := Lookup(podsFixture[0], `Labels[-3]`)
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+	c.Assert(err, ErrorMatches, `.*invalid index "Labels\[-3\]"`)
+
+	_, err = Lookup(podsFixture[0], `Name[~"a*"]`)
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+	c.Assert(err, ErrorMatches, `.*key "Name" is not a map`)
+}
+
+func (s *S) TestSplitPath_KeyFilter(c *C) {
+	c.Assert(splitPath(`a.b[~"x.y"].c`, nil), DeepEquals, []string{"a", `b[~"x.y"]`, "c"})
+	c.Assert(splitPath(`a[~/x\/.y/].b`, nil), DeepEquals, []string{`a[~/x\/.y/]`, "b"})
+	c.Assert(splitPath(`a[~x.y]`, nil), DeepEquals, []string{"a[~x", "y]"})
+
+	var parts []string
+	scanner := newPathScanner(`a.b[~"x.y"].c`, nil)
+	for scanner.scan() {
+		parts = append(parts, scanner.part)
+	}
+	c.Assert(parts, DeepEquals, []string{"a", `b[~"x.y"]`, "c"})
+}
+
+func (s *S) TestGlobExpr(c *C) {
+	for glob, matches := range map[string]map[string]bool{
+		"app*":   {"app": true, "app.kubernetes.io/name": true, "my-app": false},
+		"a?c":    {"abc": true, "ac": false, "abbc": false},
+		"a.b":    {"a.b": true, "axb": false},
+		"*/name": {"app.kubernetes.io/name": true, "name": false},
+	} {
+		re, err := compileKeyFilter(`"` + glob + `"`)
+		c.Assert(err, IsNil)
+		for key, match := range matches {
+			c.Assert(re.MatchString(key), Equals, match, Commentf("%s %s", glob, key))
+		}
+	}
+}
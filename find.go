@@ -0,0 +1,72 @@
+package lookup
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FindPaths returns the lookup paths of the values of i satisfying match, in
+// the order they're walked: the fields of structs in order, the keys of maps
+// in sorted order and the elements of slices by index. Values are walked as by
+// Flatten, and match is called with every one of them but i itself, nil for
+// the missing ones, e.g. under nil pointers.
+func FindPaths(i interface{}, match func(interface{}) bool, options ...Option) ([]string, error) {
+	opts := NewOptions(options...)
+	var paths []string
+	err := find(i, opts, func(path []string, v reflect.Value) {
+		var value interface{}
+		if v.IsValid() {
+			value = v.Interface()
+		}
+		if match(value) {
+			paths = append(paths, joinSegments(path, opts))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// Match is a string leaf found by Grep.
+type Match struct {
+	Path  string
+	Value string
+}
+
+// Grep returns the string leaves of i matching the regular expression
+// pattern, with their lookup path, in the order of FindPaths. An invalid
+// pattern fails with InvalidArgument.
+func Grep(i interface{}, pattern string, options ...Option) ([]Match, error) {
+	opts := NewOptions(options...)
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid pattern %q: %s", pattern, err)
+	}
+
+	var matches []Match
+	err = find(i, opts, func(path []string, v reflect.Value) {
+		if v.Kind() == reflect.String && re.MatchString(v.String()) {
+			matches = append(matches, Match{Path: joinSegments(path, opts), Value: v.String()})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// find calls fn with every value walked from i, but i itself.
+func find(i interface{}, opts Options, fn func(path []string, v reflect.Value)) error {
+	t := newTraversal(context.Background(), opts)
+	return t.walk(reflect.ValueOf(i), nil, func(path []string, v reflect.Value) (bool, error) {
+		if len(path) > 0 {
+			fn(path, v)
+		}
+		return true, nil
+	})
+}
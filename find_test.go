@@ -0,0 +1,59 @@
+package lookup
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+var findFixture = map[string]interface{}{
+	"id": "u-42",
+	"profile": map[string]interface{}{
+		"token":   "secret-abc",
+		"friends": []interface{}{"u-7", map[string]interface{}{"id": "u-42"}},
+	},
+	"count": 42,
+}
+
+func (s *S) TestFindPaths(c *C) {
+	paths, err := FindPaths(findFixture, func(v interface{}) bool {
+		return v == "u-42"
+	}, Options{})
+	c.Assert(err, IsNil)
+	c.Assert(paths, DeepEquals, []string{"id", "profile.friends[1].id"})
+
+	for _, path := range paths {
+		value, err := Lookup(findFixture, path, Options{})
+		c.Assert(err, IsNil)
+		c.Assert(value, Equals, "u-42")
+	}
+
+	paths, err = FindPaths(findFixture, func(v interface{}) bool {
+		return v == 42
+	}, Options{})
+	c.Assert(err, IsNil)
+	c.Assert(paths, DeepEquals, []string{"count"})
+
+	paths, err = FindPaths(findFixture, func(v interface{}) bool {
+		return false
+	}, Options{})
+	c.Assert(err, IsNil)
+	c.Assert(paths, HasLen, 0)
+}
+
+func (s *S) TestGrep(c *C) {
+	matches, err := Grep(findFixture, `^u-\d+$`, Options{})
+	c.Assert(err, IsNil)
+	c.Assert(matches, DeepEquals, []Match{
+		{Path: "id", Value: "u-42"},
+		{Path: "profile.friends[0]", Value: "u-7"},
+		{Path: "profile.friends[1].id", Value: "u-42"},
+	})
+
+	matches, err = Grep(findFixture, "secret", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(matches, DeepEquals, []Match{{Path: "profile.token", Value: "secret-abc"}})
+
+	_, err = Grep(findFixture, "(", Options{})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+}
@@ -0,0 +1,186 @@
+package lookup
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Flatten returns the leaves of i keyed by their lookup path, e.g.
+// "StructSlice[1].Map.foo": 42, so each of them can be looked up again on i
+// with its path. The leaves are scalars, byte slices, and structs, maps and
+// slices without children. Values are walked as by lookup, so strings are
+// expanded according to options. The slices held by slices are leaves, as their
+// elements can't be addressed, and map keys holding the split token give
+// paths which can't be looked up.
+func Flatten(i interface{}, options ...Option) (map[string]interface{}, error) {
+	opts := NewOptions(options...)
+	t := newTraversal(context.Background(), opts)
+	out := make(map[string]interface{})
+	err := t.walk(reflect.ValueOf(i), nil, func(path []string, v reflect.Value) (bool, error) {
+		if !isLeaf(v, path, opts) {
+			return true, nil
+		}
+		if len(path) == 0 {
+			// i itself.
+			return false, nil
+		}
+		if v.IsValid() {
+			out[joinSegments(path, opts)] = v.Interface()
+		} else {
+			out[joinSegments(path, opts)] = nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// unflattenValue returns v, if it's a map with string keys holding token, as a
+// map[string]interface{} nesting the segments of its keys. Otherwise v is
+// returned as is.
+func unflattenValue(v reflect.Value, token string) reflect.Value {
+	m := getRealValue(v)
+	if m.Kind() != reflect.Map || m.Type().Key().Kind() != reflect.String {
+		return v
+	}
+
+	keys := m.MapKeys()
+	flat := false
+	for _, k := range keys {
+		if strings.Contains(k.String(), token) {
+			flat = true
+			break
+		}
+	}
+	if !flat {
+		return v
+	}
+
+	root := &flatNode{}
+	for _, k := range keys {
+		root.set(strings.Split(k.String(), token), m.MapIndex(k).Interface())
+	}
+	return reflect.ValueOf(root.nested())
+}
+
+// Unflatten is the inverse of Flatten: it returns the nested maps and slices
+// holding the values of flat at their lookup paths, e.g. {"a.b[1]": 42} gives
+// {"a": {"b": [nil, 42]}}. Keys with conflicting paths, such as "a" and "a.b",
+// or "a.b" and "a[0]", fail with InvalidArgument, as do wildcard indexes and
+// indexes larger than the number of keys.
+func Unflatten(flat map[string]interface{}, options ...Option) (map[string]interface{}, error) {
+	opts := NewOptions(options...)
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	root := &flatNode{}
+	for _, key := range keys {
+		n := root
+		for _, part := range splitPath(key, &opts) {
+			name, index, err := parseIndex(part)
+			if err != nil {
+				return nil, err
+			}
+			if name == "" || index == wildcardIndex || index == filterIndex || index > len(flat) {
+				return nil, status.Errorf(codes.InvalidArgument, "invalid segment %q in key %q", part, key)
+			}
+			if n.hasValue || n.elems != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "key %q conflicts with another key", key)
+			}
+			n = n.child(name)
+			if index == noIndex {
+				continue
+			}
+			if n.hasValue || n.children != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "key %q conflicts with another key", key)
+			}
+			n = n.elem(index)
+		}
+		if n.hasValue || n.children != nil || n.elems != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "key %q conflicts with another key", key)
+		}
+		n.value, n.hasValue = flat[key], true
+	}
+
+	if root.children == nil {
+		return make(map[string]interface{}), nil
+	}
+	return root.nested().(map[string]interface{}), nil
+}
+
+// flatNode is a node of the tree of the segments of flat keys. It holds a
+// value, or the nodes of its keys or of its indexes.
+type flatNode struct {
+	value    interface{}
+	hasValue bool
+	children map[string]*flatNode
+	elems    map[int]*flatNode
+}
+
+func (n *flatNode) child(segment string) *flatNode {
+	if n.children == nil {
+		n.children = make(map[string]*flatNode)
+	}
+	child, ok := n.children[segment]
+	if !ok {
+		child = &flatNode{}
+		n.children[segment] = child
+	}
+	return child
+}
+
+func (n *flatNode) elem(i int) *flatNode {
+	if n.elems == nil {
+		n.elems = make(map[int]*flatNode)
+	}
+	elem, ok := n.elems[i]
+	if !ok {
+		elem = &flatNode{}
+		n.elems[i] = elem
+	}
+	return elem
+}
+
+// set sets value at path, without handling indexes nor conflicts.
+func (n *flatNode) set(path []string, value interface{}) {
+	for _, segment := range path {
+		n = n.child(segment)
+	}
+	n.value, n.hasValue = value, true
+}
+
+// nested returns the value of n: the map of its children or the slice of its
+// elements if it has any, in which case its own value is hidden.
+func (n *flatNode) nested() interface{} {
+	switch {
+	case len(n.children) > 0:
+		m := make(map[string]interface{}, len(n.children))
+		for segment, child := range n.children {
+			m[segment] = child.nested()
+		}
+		return m
+	case len(n.elems) > 0:
+		l := 0
+		for i := range n.elems {
+			if i >= l {
+				l = i + 1
+			}
+		}
+		s := make([]interface{}, l)
+		for i, elem := range n.elems {
+			s[i] = elem.nested()
+		}
+		return s
+	}
+	return n.value
+}
@@ -0,0 +1,100 @@
+package lookup
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestLookup_FlatKeys(c *C) {
+	fixture := map[string]string{
+		"a.b.c":   "1",
+		"a.b.d":   "2",
+		"a.e.c":   "3",
+		"a.e":     "hidden",
+		"f":       "4",
+		"g.h.c.x": "5",
+	}
+	opts := Options{FlatKeys: true}
+
+	value, err := Lookup(fixture, "a.b.c", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "1")
+
+	value, err = Lookup(fixture, "f", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "4")
+
+	value, err = Lookup(fixture, "a.b", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, map[string]interface{}{"c": "1", "d": "2"})
+
+	value, err = Lookup(fixture, "a.c", Options{FlatKeys: true, KeepMapKeys: true})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, map[string]string{"b": "1", "e": "3"})
+
+	value, err = Lookup(map[string]int{"a/b": 1}, "a/b", Options{FlatKeys: true, SplitToken: "/"})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 1)
+
+	_, err = Lookup(fixture, "a.b.c", Options{})
+	c.Assert(err, NotNil)
+}
+
+func (s *S) TestFlatten(c *C) {
+	flat, err := Flatten(structFixture, Options{ExpandStringAsJSON: true})
+	c.Assert(err, IsNil)
+	c.Assert(flat["String"], Equals, "foo")
+	c.Assert(flat["Map.foo"], Equals, 42)
+	c.Assert(flat["StructSlice[1].StructSlice[0].String"], Equals, "qux")
+	c.Assert(flat["StructSlice[0].Nested"], IsNil)
+	c.Assert(flat["JSONString.Struct.StructInArray[1].Field2"], Equals, float64(123))
+	c.Assert(flat["JSONList[2].foo"], Equals, "bar")
+
+	for path, want := range flat {
+		value, err := Lookup(structFixture, path, Options{ExpandStringAsJSON: true})
+		if want == nil {
+			continue
+		}
+		c.Assert(err, IsNil, Commentf("path %q", path))
+		c.Assert(value, DeepEquals, want, Commentf("path %q", path))
+	}
+
+	flat, err = Flatten(map[string]interface{}{"a": []interface{}{}, "b": map[string]int{}}, Options{})
+	c.Assert(err, IsNil)
+	c.Assert(flat, DeepEquals, map[string]interface{}{"a": []interface{}{}, "b": map[string]int{}})
+}
+
+func (s *S) TestUnflatten(c *C) {
+	nested, err := Unflatten(map[string]interface{}{
+		"a.b[1]":   42,
+		"a.b[0].c": "foo",
+		"d":        true,
+	}, Options{})
+	c.Assert(err, IsNil)
+	c.Assert(nested, DeepEquals, map[string]interface{}{
+		"a": map[string]interface{}{"b": []interface{}{map[string]interface{}{"c": "foo"}, 42}},
+		"d": true,
+	})
+
+	flat, err := Flatten(mapComplexFixture, Options{})
+	c.Assert(err, IsNil)
+	nested, err = Unflatten(flat, Options{})
+	c.Assert(err, IsNil)
+	for path, want := range flat {
+		value, err := Lookup(nested, path, Options{})
+		c.Assert(err, IsNil, Commentf("path %q", path))
+		c.Assert(value, DeepEquals, want, Commentf("path %q", path))
+	}
+
+	for _, flat := range []map[string]interface{}{
+		{"a": 1, "a.b": 2},
+		{"a.b": 1, "a[0]": 2},
+		{"a[*]": 1},
+		{"a[5]": 1},
+		{"[0]": 1},
+	} {
+		_, err = Unflatten(flat, Options{})
+		c.Assert(status.Code(err), Equals, codes.InvalidArgument, Commentf("%v", flat))
+	}
+}
@@ -1,12 +1,27 @@
 module github.com/kevinxw/go-lookup
 
-go 1.14
+go 1.18
 
 require (
+	github.com/fsnotify/fsnotify v1.8.0
+	github.com/google/cel-go v0.7.3
 	github.com/google/go-cmp v0.5.7
 	github.com/iancoleman/strcase v0.2.0
-	github.com/niemeyer/pretty v0.0.0-20200227124842-a10e7caefd8e // indirect
-	google.golang.org/genproto v0.0.0-20220211171837-173942840c17 // indirect
+	github.com/peterh/liner v1.2.2
+	google.golang.org/genproto v0.0.0-20220211171837-173942840c17
 	google.golang.org/grpc v1.44.0
+	google.golang.org/protobuf v1.27.1
 	gopkg.in/check.v1 v1.0.0-20200227125254-8fa46927fb4f
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/antlr/antlr4 v0.0.0-20200503195918-621b933c7a7f // indirect
+	github.com/golang/protobuf v1.5.2 // indirect
+	github.com/kr/text v0.1.0 // indirect
+	github.com/mattn/go-runewidth v0.0.3 // indirect
+	github.com/niemeyer/pretty v0.0.0-20200227124842-a10e7caefd8e // indirect
+	github.com/stoewer/go-strcase v1.2.0 // indirect
+	golang.org/x/sys v0.13.0 // indirect
+	golang.org/x/text v0.3.5 // indirect
 )
@@ -0,0 +1,99 @@
+package lookup
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SegmentEvent describes the resolution of a segment of a path, reported to
+// Options.OnSegment before the segment is resolved and after.
+type SegmentEvent struct {
+	// Whether the segment is about to be resolved, or has been.
+	Before bool
+	// The path of the value the segment is resolved on.
+	Prefix string
+	// The segment, e.g. "Items[0]".
+	Segment string
+	// Before, the kind of the value the segment is resolved on, dereferenced.
+	// After, the kind of the value resolved, dereferenced, or Invalid if the
+	// segment failed.
+	Kind reflect.Kind
+	// After, the Go name of the struct field, or the name of the map key, the
+	// segment matched, e.g. "Items" for "items" with CaseInsensitive. Empty if
+	// the segment aggregated because the key isn't found.
+	Match string
+	// Whether the value the segment is resolved on was expanded first, e.g.
+	// decoded from JSON with ExpandJSON.
+	Expanded bool
+	// After, whether the segment aggregates over the elements of a slice or a
+	// map: explicitly with key[*], or because the key isn't found on it. Kind
+	// is then the kind of the container.
+	Aggregated bool
+	// After, the error the segment failed with.
+	Err error
+}
+
+// String returns a one-line description of the event, e.g.
+// `after Items[0] on "Root" (slice): matched "Items", expanded`.
+func (e SegmentEvent) String() string {
+	var b strings.Builder
+	if e.Before {
+		b.WriteString("before ")
+	} else {
+		b.WriteString("after ")
+	}
+	fmt.Fprintf(&b, "%s on %q (%s)", e.Segment, e.Prefix, e.Kind)
+	var notes []string
+	if e.Match != "" {
+		notes = append(notes, fmt.Sprintf("matched %q", e.Match))
+	}
+	if e.Expanded {
+		notes = append(notes, "expanded")
+	}
+	if e.Aggregated {
+		notes = append(notes, "aggregated")
+	}
+	if e.Err != nil {
+		notes = append(notes, "error: "+e.Err.Error())
+	}
+	if len(notes) > 0 {
+		b.WriteString(": ")
+		b.WriteString(strings.Join(notes, ", "))
+	}
+	return b.String()
+}
+
+// onSegment reports e, the event of the segment following prefix and path, to
+// opts.OnSegment, if set, filling its prefix and kind. v is the value the
+// segment is resolved on before, the value resolved after, invalid if it
+// failed.
+func (t *traversal) onSegment(prefix, path []string, e SegmentEvent, v reflect.Value) error {
+	if t.opts.OnSegment == nil {
+		return nil
+	}
+	e.Prefix = joinSegments(joinPath(prefix, path), t.opts)
+	e.Kind = getRealValue(v).Kind()
+	return t.opts.OnSegment(e)
+}
+
+// segmentFailed reports e to opts.OnSegment, if set, for the segment following
+// prefix and path that failed with e.Err. It returns the error the lookup
+// fails with.
+func (t *traversal) segmentFailed(prefix, path []string, e SegmentEvent) error {
+	if t.opts.Logger != nil {
+		logDebug(&t.opts, "lookup: segment not resolved", "path", joinSegments(joinPath(prefix, path), t.opts), "segment", e.Segment, "error", e.Err)
+	}
+	if hookErr := t.onSegment(prefix, path, e, reflect.Value{}); hookErr != nil {
+		return hookErr
+	}
+	return e.Err
+}
+
+// isExpanded returns whether v was changed into expanded by expanding it.
+func isExpanded(v, expanded reflect.Value) bool {
+	if !v.IsValid() || !expanded.IsValid() {
+		return v.IsValid() != expanded.IsValid()
+	}
+	return v.Type() != expanded.Type()
+}
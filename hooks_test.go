@@ -0,0 +1,82 @@
+package lookup
+
+import (
+	"reflect"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestLookup_OnSegment(c *C) {
+	var events []SegmentEvent
+	opts := Options{OnSegment: func(e SegmentEvent) error {
+		events = append(events, e)
+		return nil
+	}}
+
+	value, err := Lookup(structFixture, "Map.foo", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 42)
+	c.Assert(events, DeepEquals, []SegmentEvent{
+		{Before: true, Prefix: "", Segment: "Map", Kind: reflect.Struct},
+		{Prefix: "", Segment: "Map", Kind: reflect.Map, Match: "Map"},
+		{Before: true, Prefix: "Map", Segment: "foo", Kind: reflect.Map},
+		{Prefix: "Map", Segment: "foo", Kind: reflect.Int, Match: "foo"},
+	})
+
+	events = nil
+	_, err = Lookup(structFixture, "StructSlice.String", opts)
+	c.Assert(err, IsNil)
+	c.Assert(events[:4], DeepEquals, []SegmentEvent{
+		{Before: true, Prefix: "", Segment: "StructSlice", Kind: reflect.Struct},
+		{Prefix: "", Segment: "StructSlice", Kind: reflect.Slice, Match: "StructSlice"},
+		{Before: true, Prefix: "StructSlice", Segment: "String", Kind: reflect.Slice},
+		{Prefix: "StructSlice", Segment: "String", Kind: reflect.Slice, Aggregated: true},
+	})
+	// Then the segment on each element.
+	c.Assert(events[4:], HasLen, 4)
+	c.Assert(events[5], DeepEquals, SegmentEvent{Prefix: "StructSlice", Segment: "String", Kind: reflect.String, Match: "String"})
+
+	events = nil
+	_, err = Lookup(structFixture, "StructSlice[*]", opts)
+	c.Assert(err, IsNil)
+	c.Assert(events[1], DeepEquals, SegmentEvent{Prefix: "", Segment: "StructSlice[*]", Kind: reflect.Slice, Match: "StructSlice", Aggregated: true})
+
+	events = nil
+	_, err = Lookup(structFixture, "Missing", opts)
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+	c.Assert(events, HasLen, 2)
+	c.Assert(events[1].Kind, Equals, reflect.Invalid)
+	c.Assert(events[1].Err, Equals, err)
+}
+
+func (s *S) TestLookup_OnSegment_Policy(c *C) {
+	denied := status.Errorf(codes.PermissionDenied, "denied")
+	opts := Options{OnSegment: func(e SegmentEvent) error {
+		if e.Before && e.Segment == "Map" {
+			return denied
+		}
+		return nil
+	}}
+
+	_, err := Lookup(structFixture, "Map.foo", opts)
+	c.Assert(err, Equals, denied)
+	_, err = Lookup(structFixture, "StructSlice.Map.foo", opts)
+	c.Assert(err, Equals, denied)
+	c.Assert(Exists(structFixture, "Map.foo", opts), Equals, false)
+
+	values, err := LookupMany(structFixture, []string{"String", "Map.foo", "StructSlice.Map.foo"}, opts)
+	c.Assert(values, DeepEquals, map[string]interface{}{"String": "foo"})
+	c.Assert(err, DeepEquals, PathErrors{"Map.foo": denied, "StructSlice.Map.foo": denied})
+
+	// A failed segment can be reported with another error.
+	opts.OnSegment = func(e SegmentEvent) error {
+		if e.Err != nil {
+			return status.Errorf(codes.Internal, "%s: %s", e.Segment, e.Err)
+		}
+		return nil
+	}
+	_, err = Lookup(structFixture, "Missing", opts)
+	c.Assert(status.Code(err), Equals, codes.Internal)
+}
@@ -0,0 +1,159 @@
+/*
+Package httplookup resolves lookup paths on HTTP requests. The first segment
+of a path selects the part of the request the rest of the path is looked up
+on:
+
+	query.page           the "page" query parameter
+	header.X-Request-Id  the X-Request-Id header, whose name is canonicalized
+	body.user.email      the path "user.email" on the JSON body
+
+Parameters and headers with a single value are strings, others are slices of
+strings.
+*/
+package httplookup
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	lookup "github.com/kevinxw/go-lookup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	querySource  = "query"
+	headerSource = "header"
+	bodySource   = "body"
+)
+
+// Request wraps an *http.Request to look up paths on it.
+type Request struct {
+	r *http.Request
+	// The body, read on the first lookup needing it.
+	body     []byte
+	bodyRead bool
+}
+
+// New returns a Request wrapping r.
+func New(r *http.Request) *Request {
+	return &Request{r: r}
+}
+
+// Lookup looks path up on r. It's a shortcut for New(r).Lookup(path,
+// options...).
+func Lookup(r *http.Request, path string, options ...lookup.Option) (interface{}, error) {
+	return New(r).Lookup(path, options...)
+}
+
+// Lookup looks path up on the request, with the path semantics and the
+// options of lookup.Lookup. The body is read once, and replaced so it can
+// still be read by the handlers of the request; its size should be limited by
+// the caller, e.g. with http.MaxBytesReader.
+func (r *Request) Lookup(path string, options ...lookup.Option) (interface{}, error) {
+	opts := lookup.NewOptions(options...)
+	source, rest := path, ""
+	if !opts.NoSplit {
+		token := opts.SplitToken
+		if token == "" {
+			token = "."
+		}
+		if i := strings.Index(path, token); i != -1 {
+			source, rest = path[:i], path[i+len(token):]
+		}
+	}
+
+	switch source {
+	case querySource:
+		return lookupValues(r.r.URL.Query(), rest, opts)
+	case headerSource:
+		if rest != "" {
+			rest = canonicalHeaderPath(rest, opts)
+		}
+		return lookupValues(r.r.Header, rest, opts)
+	case bodySource:
+		body, err := r.readBody()
+		if err != nil {
+			return nil, err
+		}
+		if rest == "" {
+			return decodeBody(body, opts)
+		}
+		return lookup.LookupJSONBytes(body, rest, opts)
+	default:
+		return nil, status.Errorf(codes.NotFound, "unknown request part %q; use %s, %s or %s", source, querySource, headerSource, bodySource)
+	}
+}
+
+func (r *Request) readBody() ([]byte, error) {
+	if r.bodyRead {
+		return r.body, nil
+	}
+	if r.r.Body != nil {
+		body, err := ioutil.ReadAll(r.r.Body)
+		r.r.Body.Close()
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "can't read the body: %s", err)
+		}
+		r.body = body
+		r.r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	r.bodyRead = true
+	return r.body, nil
+}
+
+func decodeBody(body []byte, opts lookup.Options) (interface{}, error) {
+	var v interface{}
+	var err error
+	switch {
+	case opts.DecodeJSON != nil:
+		v, err = opts.DecodeJSON(body)
+	case opts.JSONUseNumber:
+		d := json.NewDecoder(bytes.NewReader(body))
+		d.UseNumber()
+		err = d.Decode(&v)
+	default:
+		err = json.Unmarshal(body, &v)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid JSON document: %s", err)
+	}
+	return v, nil
+}
+
+// lookupValues looks path up on values, whose single values are unwrapped
+// from their slices.
+func lookupValues(values map[string][]string, path string, opts lookup.Options) (interface{}, error) {
+	m := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		if len(v) == 1 {
+			m[k] = v[0]
+		} else {
+			m[k] = v
+		}
+	}
+	if path == "" {
+		return m, nil
+	}
+	return lookup.Lookup(m, path, opts)
+}
+
+// canonicalHeaderPath canonicalizes the header name starting path.
+func canonicalHeaderPath(path string, opts lookup.Options) string {
+	if opts.NoSplit {
+		return textproto.CanonicalMIMEHeaderKey(path)
+	}
+	token := opts.SplitToken
+	if token == "" {
+		token = "."
+	}
+	name, rest := path, ""
+	if i := strings.Index(path, token); i != -1 {
+		name, rest = path[:i], path[i:]
+	}
+	return textproto.CanonicalMIMEHeaderKey(name) + rest
+}
@@ -0,0 +1,61 @@
+package httplookup
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	lookup "github.com/kevinxw/go-lookup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) { TestingT(t) }
+
+type S struct{}
+
+var _ = Suite(&S{})
+
+func (s *S) TestLookup(c *C) {
+	r := httptest.NewRequest("POST", "/users?page=2&tag=a&tag=b", strings.NewReader(`{"user": {"email": "bob@example.com"}}`))
+	r.Header.Set("X-Request-Id", "42")
+	req := New(r)
+
+	tests := []struct {
+		path string
+		want interface{}
+	}{
+		{"query.page", "2"},
+		{"query.tag", []string{"a", "b"}},
+		{"header.X-Request-Id", "42"},
+		{"header.x-request-id", "42"},
+		{"body.user.email", "bob@example.com"},
+		{"body", map[string]interface{}{"user": map[string]interface{}{"email": "bob@example.com"}}},
+	}
+	for _, test := range tests {
+		value, err := req.Lookup(test.path, lookup.Options{})
+		c.Assert(err, IsNil, Commentf("path %q", test.path))
+		c.Assert(value, DeepEquals, test.want, Commentf("path %q", test.path))
+	}
+
+	// The body can still be read.
+	body, err := ioutil.ReadAll(r.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Equals, `{"user": {"email": "bob@example.com"}}`)
+
+	value, err := Lookup(r, "header/X-Request-Id", lookup.WithSplitToken("/"))
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "42")
+	value, err = req.Lookup("query.PAGE", lookup.CaseInsensitive())
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "2")
+
+	_, err = req.Lookup("query.missing")
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	_, err = req.Lookup("cookie.session")
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+}
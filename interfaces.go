@@ -0,0 +1,104 @@
+package lookup
+
+import (
+	"reflect"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// interfaceField returns the value named key of the dynamic values of the
+// exported interfaces embedded in the struct v, whose fields and keys aren't
+// promoted by Go, in the order of the embedded interfaces. Promotion is
+// disabled by opts.NoPromotedFields.
+func (t *traversal) interfaceField(v reflect.Value, key string) (reflect.Value, bool) {
+	if t.opts.NoPromotedFields {
+		return reflect.Value{}, false
+	}
+	for _, f := range structFields(v.Type(), t.opts) {
+		if !f.Anonymous || f.PkgPath != "" || f.Type.Kind() != reflect.Interface {
+			continue
+		}
+		field := fieldByIndex(v, f.Index)
+		e := getRealValue(field)
+		if e.Kind() != reflect.Struct {
+			continue
+		}
+		ptr, ok := t.enterInterface(field)
+		if !ok {
+			continue
+		}
+		value, err := t.getValueByName(e, key)
+		delete(t.visiting, ptr)
+		if err == nil {
+			return value, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// enterInterface marks the pointer held by the embedded interface field as
+// being searched, so an interface holding a pointer to its own struct isn't
+// searched forever. It returns false if it already is.
+func (t *traversal) enterInterface(field reflect.Value) (visit, bool) {
+	p := field.Elem()
+	if p.Kind() != reflect.Ptr || p.IsNil() {
+		return visit{}, true
+	}
+	// A path of -1 tells these visits from those of aggregations.
+	ptr := visit{ptr: p.Pointer(), typ: p.Type(), path: -1}
+	if t.visiting[ptr] {
+		return visit{}, false
+	}
+	t.visiting[ptr] = true
+	return ptr, true
+}
+
+// skipsElement returns whether the element elem of the container v, on which
+// path failed with err, is left out of the aggregation instead of failing
+// it. It is if v holds interfaces, e.g. a []Shape, and elem is a struct whose
+// dynamic type has no field for the first key of path, which another element
+// has: the elements of different types are then looked up as their types
+// allow. A key no element has still fails.
+func (t *traversal) skipsElement(v, elem reflect.Value, path []string, err error) bool {
+	if status.Code(err) != codes.NotFound || len(path) == 0 || v.Type().Elem().Kind() != reflect.Interface {
+		return false
+	}
+	key, _, err := parseIndex(path[0])
+	if err != nil {
+		return false
+	}
+	if has, ok := t.hasField(elem, key); !ok || has {
+		return false
+	}
+
+	if v.Kind() == reflect.Map {
+		iter := v.MapRange()
+		for iter.Next() {
+			if has, _ := t.hasField(iter.Value(), key); has {
+				return true
+			}
+		}
+		return false
+	}
+	for i := 0; i < v.Len(); i++ {
+		if has, _ := t.hasField(v.Index(i), key); has {
+			return true
+		}
+	}
+	return false
+}
+
+// hasField returns whether the dynamic type of elem has a field for key,
+// and false if elem isn't a struct.
+func (t *traversal) hasField(elem reflect.Value, key string) (bool, bool) {
+	e := getRealValue(elem)
+	if e.Kind() != reflect.Struct {
+		return false, false
+	}
+	if _, ok, _ := structField(e.Type(), key, t.opts); ok {
+		return true, true
+	}
+	_, ok := t.interfaceField(e, key)
+	return ok, true
+}
@@ -0,0 +1,129 @@
+package lookup
+
+import (
+	"reflect"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+type Shape interface {
+	Area() int
+}
+
+type Square struct {
+	Side int
+	Name string
+}
+
+func (s Square) Area() int { return s.Side * s.Side }
+
+type Circle struct {
+	R    int
+	Name string
+	Tags map[string]string
+}
+
+func (c *Circle) Area() int { return 3 * c.R * c.R }
+
+type Drawing struct {
+	Shape
+	Shapes []Shape
+	ByName map[string]Shape
+}
+
+// Loop embeds an interface which may hold the Loop itself.
+type Loop struct {
+	Shape
+	Next interface{}
+}
+
+func (l *Loop) Area() int { return 0 }
+
+func (s *S) TestLookup_EmbeddedInterface(c *C) {
+	d := Drawing{Shape: Square{Side: 2, Name: "main"}}
+	for path, want := range map[string]interface{}{
+		"Side":       2,
+		"name":       "main",
+		"Shape.Side": 2,
+	} {
+		value, err := Lookup(&d, path, CaseInsensitive())
+		c.Assert(err, IsNil, Commentf("%s", path))
+		c.Assert(value, Equals, want, Commentf("%s", path))
+	}
+
+	// The dynamic fields aren't promoted without promotion, nor from nil.
+	_, err := Lookup(d, "Side", Options{NoPromotedFields: true})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+	_, err = Lookup(Drawing{}, "Side")
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	l := &Loop{}
+	l.Shape = l
+	_, err = Lookup(l, "Missing")
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+}
+
+func (s *S) TestLookup_InterfaceElements(c *C) {
+	d := Drawing{
+		Shapes: []Shape{Square{Side: 1, Name: "a"}, &Circle{R: 2, Name: "b"}, Square{Side: 3}},
+		ByName: map[string]Shape{"a": Square{Side: 1}, "b": &Circle{R: 2}},
+	}
+	for path, want := range map[string]interface{}{
+		"Shapes.Name":    []string{"a", "b", ""},
+		"Shapes.Side":    []int{1, 3},
+		"Shapes[*].R":    []int{2},
+		"Shapes[1].R":    2,
+		"ByName.R":       []int{2},
+		"ByName[*].Side": []int{1},
+	} {
+		value, err := Lookup(d, path)
+		c.Assert(err, IsNil, Commentf("%s", path))
+		c.Assert(value, DeepEquals, want, Commentf("%s", path))
+	}
+
+	value, err := Lookup(d, "Shapes.Side", PreservePositions())
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []int{1, 0, 3})
+	value, err = Lookup(d, "ByName.Side", KeepMapKeys())
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, map[string]int{"a": 1})
+
+	// A key no element has is still missing.
+	_, err = Lookup(d, "Shapes.Missing")
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+	// As is a key missing deeper.
+	_, err = Lookup(d, "Shapes.Tags.x")
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	matches, err := LookupAll(d, "Shapes.Side")
+	c.Assert(err, IsNil)
+	c.Assert(matches, DeepEquals, []PathMatch{{Path: "Shapes[0].Side", Value: 1}, {Path: "Shapes[2].Side", Value: 3}})
+	values, err := LookupMany(d, []string{"Shapes.Side", "Shapes.Name"})
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, map[string]interface{}{"Shapes.Side": []int{1, 3}, "Shapes.Name": []string{"a", "b", ""}})
+
+	it, err := Iterate(d, "Shapes.R")
+	c.Assert(err, IsNil)
+	var rs []interface{}
+	for it.Next() {
+		rs = append(rs, it.Value())
+	}
+	c.Assert(it.Err(), IsNil)
+	c.Assert(rs, DeepEquals, []interface{}{2})
+}
+
+func (s *S) TestWalk_EmbeddedInterface(c *C) {
+	var paths []string
+	err := Walk(Drawing{Shape: &Circle{R: 1}}, func(path string, v reflect.Value) (bool, error) {
+		paths = append(paths, path)
+		return true, nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(paths, DeepEquals, []string{"", "Shapes", "ByName", "R", "Name", "Tags"})
+
+	l := &Loop{}
+	l.Shape = l
+	c.Assert(Walk(l, func(string, reflect.Value) (bool, error) { return true, nil }), IsNil)
+}
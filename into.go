@@ -0,0 +1,81 @@
+package lookup
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LookupInto performs a lookup like Lookup, and stores the result into dst,
+// which must be a non-nil pointer. The result is assigned if its type is
+// assignable to the one of dst, converted between numeric types, and otherwise
+// decoded through its JSON encoding, e.g. from a map into a struct. A nil
+// result sets dst to its zero value. A number which overflows dst, or would
+// lose its fraction, fails with InvalidArgument.
+func LookupInto(i interface{}, path string, dst interface{}, options ...Option) error {
+	opts := NewOptions(options...)
+	d := reflect.ValueOf(dst)
+	if d.Kind() != reflect.Ptr || d.IsNil() {
+		return status.Errorf(codes.InvalidArgument, "destination must be a non-nil pointer, got %T", dst)
+	}
+
+	value, err := Lookup(i, path, opts)
+	if err != nil {
+		return err
+	}
+	return assign(d.Elem(), value)
+}
+
+// assign stores value into the settable dst.
+func assign(dst reflect.Value, value interface{}) error {
+	if value == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(dst.Type()) {
+		dst.Set(v)
+		return nil
+	}
+	if isNumber(v.Kind()) && isNumber(dst.Kind()) {
+		return setNumber(dst, value)
+	}
+
+	data, err := json.Marshal(value)
+	if err == nil {
+		err = json.Unmarshal(data, dst.Addr().Interface())
+	}
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "can't store %T into %s: %s", value, dst.Type(), err)
+	}
+	return nil
+}
+
+func isNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// LookupOr performs a lookup like Lookup, and returns the result stored into a
+// T as with LookupInto. It returns def if the lookup fails, e.g. if path isn't
+// found, if the result is nil or if it can't be stored into a T.
+func LookupOr[T any](i interface{}, path string, def T, options ...Option) T {
+	opts := NewOptions(options...)
+	value, err := Lookup(i, path, opts)
+	if err != nil || value == nil {
+		return def
+	}
+	var out T
+	if err := assign(reflect.ValueOf(&out).Elem(), value); err != nil {
+		return def
+	}
+	return out
+}
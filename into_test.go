@@ -0,0 +1,85 @@
+package lookup
+
+import (
+	"math"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestLookupInto(c *C) {
+	var str string
+	err := LookupInto(structFixture, "String", &str, Options{})
+	c.Assert(err, IsNil)
+	c.Assert(str, Equals, "foo")
+
+	var n int64
+	err = LookupInto(structFixture, "Map.foo", &n, Options{})
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(42))
+
+	var strs []string
+	err = LookupInto(structFixture, "StructSlice.String", &strs, Options{})
+	c.Assert(err, IsNil)
+	c.Assert(strs, DeepEquals, []string{"foo", "qux"})
+
+	type Field struct {
+		FieldA string
+		FieldB int
+	}
+	var field Field
+	err = LookupInto(structFixture, "JSONString.Struct.StructInArray[0]", &field, Options{ExpandStringAsJSON: true})
+	c.Assert(err, IsNil)
+	c.Assert(field, Equals, Field{FieldA: "Abc", FieldB: 123})
+
+	var ints []int
+	err = LookupInto(structFixture, "JSONString.Struct.Array", &ints, Options{ExpandStringAsJSON: true})
+	c.Assert(err, IsNil)
+	c.Assert(ints, DeepEquals, []int{1, 2, 3})
+
+	err = LookupInto(structFixture, "String", &n, Options{})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+
+	err = LookupInto(structFixture, "String", str, Options{})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+
+	err = LookupInto(structFixture, "Missing", &str, Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	// The numbers which don't fit aren't wrapped nor truncated.
+	numbers := map[string]interface{}{"big": 300, "negative": -1, "fraction": 1.9, "whole": 2.0, "huge": uint64(math.MaxUint64), "float": 1e300}
+	var i8 int8
+	c.Assert(status.Code(LookupInto(numbers, "big", &i8)), Equals, codes.InvalidArgument)
+	c.Assert(i8, Equals, int8(0))
+	var u uint
+	c.Assert(status.Code(LookupInto(numbers, "negative", &u)), Equals, codes.InvalidArgument)
+	var i int
+	c.Assert(status.Code(LookupInto(numbers, "fraction", &i)), Equals, codes.InvalidArgument)
+	c.Assert(LookupInto(numbers, "whole", &i), IsNil)
+	c.Assert(i, Equals, 2)
+	var u64 uint64
+	c.Assert(LookupInto(numbers, "huge", &u64), IsNil)
+	c.Assert(u64, Equals, uint64(math.MaxUint64))
+	c.Assert(status.Code(LookupInto(numbers, "huge", &i)), Equals, codes.InvalidArgument)
+	var f32 float32
+	c.Assert(status.Code(LookupInto(numbers, "float", &f32)), Equals, codes.InvalidArgument)
+	c.Assert(LookupInto(numbers, "fraction", &f32), IsNil)
+	c.Assert(f32, Equals, float32(1.9))
+}
+
+func (s *S) TestLookupOr(c *C) {
+	c.Assert(LookupOr(structFixture, "String", "bar", Options{}), Equals, "foo")
+	c.Assert(LookupOr(structFixture, "Missing", "bar", Options{}), Equals, "bar")
+	c.Assert(LookupOr(structFixture, "Map.foo", 1.5, Options{}), Equals, float64(42))
+	c.Assert(LookupOr(structFixture, "String", 1, Options{}), Equals, 1)
+	c.Assert(LookupOr(structFixture, "Nested", "bar", Options{}), Equals, "bar")
+	c.Assert(LookupOr(structFixture, "StructSlice.String", []string(nil), Options{}), DeepEquals, []string{"foo", "qux"})
+
+	// The numbers which don't fit the type of the default fall back to it.
+	numbers := map[string]interface{}{"big": 300, "negative": -1, "fraction": 1.9}
+	c.Assert(LookupOr(numbers, "big", int8(7)), Equals, int8(7))
+	c.Assert(LookupOr(numbers, "negative", uint(7)), Equals, uint(7))
+	c.Assert(LookupOr(numbers, "fraction", 7), Equals, 7)
+	c.Assert(LookupOr(numbers, "big", int16(7)), Equals, int16(300))
+}
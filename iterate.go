@@ -0,0 +1,191 @@
+package lookup
+
+import (
+	"context"
+	"reflect"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Iterator yields the values of a lookup one at a time. See Iterate.
+type Iterator struct {
+	t   *traversal
+	err error
+
+	// The aggregation done lazily, if any.
+	list         reflect.Value
+	visit        visit
+	index        func(i int) reflect.Value
+	n, next      int
+	path, prefix []string
+	depth        int
+
+	// The type and the mergeability of the values of the aggregation, known
+	// from its first valid value.
+	typ       reflect.Type
+	mergeable bool
+	// The number of missing values waiting for typ, with PreservePositions.
+	missing int
+
+	queue []reflect.Value
+	value reflect.Value
+	// The values already yielded, with DedupeAggregated.
+	dedupe *deduper
+}
+
+// Iterate returns an Iterator over the values of the slice Lookup would
+// return for path on i, in the same order. The elements of the first
+// aggregation of path are only looked up as the values are consumed, so
+// stopping early, or streaming the values, doesn't pay for all of them. A
+// result which isn't a slice, e.g. a map, or a []byte, is yielded as a single
+// value, and a missing one yields nothing. The errors of the lookup are
+// returned by Iterate up to the first aggregation, and by Err after it.
+func Iterate(i interface{}, path string, options ...Option) (*Iterator, error) {
+	return IterateContext(context.Background(), i, path, options...)
+}
+
+// IterateContext is like Iterate, but stops with the error of ctx, converted
+// to a status error, as soon as ctx is done.
+func IterateContext(ctx context.Context, i interface{}, path string, options ...Option) (*Iterator, error) {
+	opts := NewOptions(options...)
+	it := &Iterator{t: newTraversal(ctx, opts)}
+	it.t.lazy = it
+	v, err := it.t.lookup(reflect.ValueOf(i), splitPath(path, &opts), nil, 0)
+	it.t.lazy = nil
+	if err != nil {
+		return nil, err
+	}
+	if it.list.IsValid() {
+		// The container stays visited as long as it's iterated over.
+		it.t.visiting[it.visit] = true
+		return it, nil
+	}
+
+	// path doesn't aggregate.
+	switch {
+	case !v.IsValid():
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8:
+		it.push(v, true)
+	default:
+		it.push(v, false)
+	}
+	return it, nil
+}
+
+// aggregate records the aggregation of path over the elements of list, as
+// done by aggreateAggregableValue, to be done as the values are consumed.
+func (it *Iterator) aggregate(list reflect.Value, key visit, path, prefix []string, depth int) {
+	it.list, it.visit, it.index, it.n = list, key, indexFunction(list), list.Len()
+	it.path, it.prefix, it.depth = path, prefix, depth
+	if it.t.opts.DedupeAggregated {
+		it.dedupe = &deduper{}
+	}
+}
+
+// Next advances to the next value, which is then returned by Value. It
+// returns false when there are no more values, or on error.
+func (it *Iterator) Next() bool {
+	for len(it.queue) == 0 {
+		if it.err != nil || !it.advance() {
+			it.value = reflect.Value{}
+			return false
+		}
+	}
+	it.value, it.queue = it.queue[0], it.queue[1:]
+	return true
+}
+
+// Value returns the current value.
+func (it *Iterator) Value() interface{} {
+	if !it.value.IsValid() {
+		return nil
+	}
+	return resultInterface(it.value, &it.t.opts)
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// advance looks up the rest of the path on the next element of the
+// aggregation, queuing the values to yield. It returns false when all the
+// elements have been looked up.
+func (it *Iterator) advance() bool {
+	opts := it.t.opts
+	for it.next < it.n {
+		if it.err = it.t.checkContext(); it.err != nil {
+			return false
+		}
+		elem := it.index(it.next)
+		value, err := it.t.lookup(elemValue(elem), it.path, it.prefix, it.depth)
+		it.next++
+		if err != nil && it.t.skipsElement(it.list, elem, it.path, err) {
+			value, err = reflect.Value{}, nil
+		}
+		if err != nil {
+			if !opts.PreservePositions || status.Code(err) != codes.NotFound {
+				it.err = err
+				return false
+			}
+			value = reflect.Value{}
+		}
+
+		if !value.IsValid() {
+			if opts.PreservePositions {
+				it.missing++
+			}
+			continue
+		}
+		if it.typ == nil {
+			it.typ, it.mergeable = value.Type(), isMergeable(value) && !opts.PreserveNesting
+		}
+		// As with fillZeroValues, the missing values are zeros of the type of
+		// the valid ones.
+		for ; it.missing > 0; it.missing-- {
+			it.push(reflect.Zero(it.typ), it.mergeable)
+		}
+		it.push(value, it.mergeable)
+		return true
+	}
+
+	// The trailing missing values, of the type resolved by path if none was
+	// valid.
+	if it.missing > 0 {
+		ty, mergeable := it.typ, it.mergeable
+		if ty == nil {
+			var err error
+			if ty, err = typeOfPath(it.list.Type().Elem(), it.path, 0, opts); err != nil {
+				it.missing = 0
+				return false
+			}
+			mergeable = ty.Kind() == reflect.Slice && !opts.PreserveNesting
+		}
+		for ; it.missing > 0; it.missing-- {
+			it.push(reflect.Zero(ty), mergeable)
+		}
+		return true
+	}
+	return false
+}
+
+// push queues v, or its elements if it's a slice to merge, skipping the
+// duplicates with DedupeAggregated.
+func (it *Iterator) push(v reflect.Value, merge bool) {
+	if !merge || v.Kind() != reflect.Slice {
+		it.queue = it.appendNew(it.queue, v)
+		return
+	}
+	for i := 0; i < v.Len(); i++ {
+		it.queue = it.appendNew(it.queue, v.Index(i))
+	}
+}
+
+// appendNew appends v to queue, unless it's a duplicate.
+func (it *Iterator) appendNew(queue []reflect.Value, v reflect.Value) []reflect.Value {
+	if it.dedupe != nil && !it.dedupe.add(v) {
+		return queue
+	}
+	return append(queue, v)
+}
@@ -0,0 +1,109 @@
+package lookup
+
+import (
+	"context"
+	"reflect"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func iterateAll(c *C, i interface{}, path string, opts Options) []interface{} {
+	it, err := Iterate(i, path, opts)
+	c.Assert(err, IsNil)
+	var values []interface{}
+	for it.Next() {
+		values = append(values, it.Value())
+	}
+	c.Assert(it.Err(), IsNil)
+	return values
+}
+
+func (s *S) TestIterate(c *C) {
+	for _, path := range []string{
+		"StructSlice.String",
+		"StructSlice.StructSlice.String",
+		"StructSlice[*].Map.foo",
+		"StructSlice.StructSlice",
+		"Map",
+	} {
+		want, err := Lookup(structFixture, path, Options{})
+		c.Assert(err, IsNil)
+		values := iterateAll(c, structFixture, path, Options{})
+		w := reflect.ValueOf(want)
+		if w.Kind() != reflect.Slice {
+			c.Assert(values, DeepEquals, []interface{}{want}, Commentf("path %q", path))
+			continue
+		}
+		c.Assert(values, HasLen, w.Len(), Commentf("path %q", path))
+		for i := range values {
+			c.Assert(values[i], DeepEquals, w.Index(i).Interface(), Commentf("path %q", path))
+		}
+	}
+
+	c.Assert(iterateAll(c, structFixture, "String", Options{}), DeepEquals, []interface{}{"foo"})
+	c.Assert(iterateAll(c, map[string]interface{}{"a": []int{1, 2}}, "a", Options{}), DeepEquals, []interface{}{1, 2})
+	c.Assert(iterateAll(c, MyStruct{}, "StructSlice.String", Options{}), HasLen, 0)
+
+	fixture := []interface{}{map[string]interface{}{"a": 1}, "b", map[string]interface{}{"a": 3}}
+	want, err := Lookup(fixture, "a", Options{PreservePositions: true})
+	c.Assert(err, IsNil)
+	c.Assert(want, DeepEquals, []int{1, 0, 3})
+	c.Assert(iterateAll(c, fixture, "a", Options{PreservePositions: true}), DeepEquals, []interface{}{1, 0, 3})
+
+	// The last element is missing the key.
+	trailing := []interface{}{map[string]interface{}{"a": 1}, "b", "c"}
+	want, err = Lookup(trailing, "a", Options{PreservePositions: true})
+	c.Assert(err, IsNil)
+	c.Assert(want, DeepEquals, []int{1, 0, 0})
+	c.Assert(iterateAll(c, trailing, "a", Options{PreservePositions: true}), DeepEquals, []interface{}{1, 0, 0})
+
+	_, err = Iterate(structFixture, "Missing", Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	it, err := Iterate(fixture, "a", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(it.Next(), Equals, true)
+	c.Assert(it.Value(), Equals, 1)
+	c.Assert(it.Next(), Equals, false)
+	c.Assert(status.Code(it.Err()), Equals, codes.NotFound)
+}
+
+func (s *S) TestIterate_Lazy(c *C) {
+	looked := 0
+	counting := ExpanderFunc(func(v interface{}, opts Options) (interface{}, bool, error) {
+		if _, ok := v.(string); ok {
+			looked++
+		}
+		return nil, false, nil
+	})
+	fixture := map[string]interface{}{"items": []interface{}{
+		map[string]interface{}{"id": "a"},
+		map[string]interface{}{"id": "b"},
+		map[string]interface{}{"id": "c"},
+	}}
+
+	it, err := Iterate(fixture, "items.id.x", Options{Expanders: []Expander{counting}, PreservePositions: true})
+	c.Assert(err, IsNil)
+	c.Assert(looked, Equals, 0)
+	var values []interface{}
+	for it.Next() {
+		values = append(values, it.Value())
+	}
+	c.Assert(values, DeepEquals, []interface{}{nil, nil, nil})
+	c.Assert(looked, Equals, 3)
+
+	it, err = Iterate(fixture, "items.id", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(it.Next(), Equals, true)
+	c.Assert(it.Value(), Equals, "a")
+	c.Assert(it.next, Equals, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	it, err = IterateContext(ctx, fixture, "items.id", Options{})
+	c.Assert(err, IsNil)
+	cancel()
+	c.Assert(it.Next(), Equals, false)
+	c.Assert(status.Code(it.Err()), Equals, codes.Canceled)
+}
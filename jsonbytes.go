@@ -0,0 +1,214 @@
+package lookup
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LookupJSONBytes performs a lookup into the JSON document data, as Lookup
+// does on the decoded document. Paths made of plain keys and indexes are
+// resolved by scanning data, and only the value found is decoded. Other paths,
+// such as those aggregating or using key[*], and lookups with MatchFunctions,
+// Expanders or OnSegment, fall back to decoding the whole document.
+func LookupJSONBytes(data []byte, path string, options ...Option) (interface{}, error) {
+	opts := NewOptions(options...)
+	if raw, ok := scanJSONPath(data, path, opts); ok {
+		v, err := decodeJSON(raw, opts)
+		if err == nil && v != nil {
+			return v, nil
+		}
+	}
+
+	v, err := decodeJSON(data, opts)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid JSON document: %s", err)
+	}
+	return Lookup(v, path, opts)
+}
+
+// scanJSONPath returns the bytes of the value at path in the JSON document
+// data. It returns false if path or opts need a full lookup, if the value isn't
+// found or if data is invalid as far as it's scanned.
+func scanJSONPath(data []byte, path string, opts Options) ([]byte, bool) {
+	if len(opts.MatchFunctions) > 0 || len(opts.Expanders) > 0 || opts.OnSegment != nil {
+		return nil, false
+	}
+
+	start := skipJSONSpace(data, 0)
+	end, ok := jsonValueEnd(data, start)
+	if !ok || skipJSONSpace(data, end) != len(data) {
+		return nil, false
+	}
+	value := data[start:end]
+
+	s := newPathScanner(path, &opts)
+	for depth := 1; s.scan(); depth++ {
+		if checkDepth(depth, opts) != nil {
+			return nil, false
+		}
+		key, index, err := parseIndex(s.part)
+		if err != nil || key == "" || index == wildcardIndex || index == filterIndex {
+			return nil, false
+		}
+		if value, ok = jsonField(value, key); !ok {
+			return nil, false
+		}
+		if index == noIndex {
+			continue
+		}
+		if value, ok = jsonElement(value, index); !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// jsonField returns the value of the field key of the JSON object obj. As
+// with encoding/json, the last of duplicated fields wins.
+func jsonField(obj []byte, key string) ([]byte, bool) {
+	if len(obj) == 0 || obj[0] != '{' {
+		return nil, false
+	}
+
+	var found []byte
+	i := skipJSONSpace(obj, 1)
+	if i < len(obj) && obj[i] == '}' {
+		return nil, false
+	}
+	for i < len(obj) {
+		end, ok := jsonStringEnd(obj, i)
+		if !ok {
+			return nil, false
+		}
+		name := obj[i:end]
+		i = skipJSONSpace(obj, end)
+		if i >= len(obj) || obj[i] != ':' {
+			return nil, false
+		}
+		i = skipJSONSpace(obj, i+1)
+		valueEnd, ok := jsonValueEnd(obj, i)
+		if !ok {
+			return nil, false
+		}
+		if jsonNameEquals(name, key) {
+			found = obj[i:valueEnd]
+		}
+		i = skipJSONSpace(obj, valueEnd)
+		if i < len(obj) && obj[i] == '}' {
+			return found, found != nil
+		}
+		if i >= len(obj) || obj[i] != ',' {
+			return nil, false
+		}
+		i = skipJSONSpace(obj, i+1)
+	}
+	return nil, false
+}
+
+// jsonElement returns the n-th element of the JSON array arr.
+func jsonElement(arr []byte, n int) ([]byte, bool) {
+	if len(arr) == 0 || arr[0] != '[' {
+		return nil, false
+	}
+
+	i := skipJSONSpace(arr, 1)
+	for e := 0; i < len(arr) && arr[i] != ']'; e++ {
+		end, ok := jsonValueEnd(arr, i)
+		if !ok {
+			return nil, false
+		}
+		if e == n {
+			return arr[i:end], true
+		}
+		i = skipJSONSpace(arr, end)
+		if i >= len(arr) || arr[i] != ',' {
+			return nil, false
+		}
+		i = skipJSONSpace(arr, i+1)
+	}
+	return nil, false
+}
+
+// jsonNameEquals returns whether the quoted JSON string name is key.
+func jsonNameEquals(name []byte, key string) bool {
+	unquoted := name[1 : len(name)-1]
+	if bytes.IndexByte(unquoted, '\\') == -1 {
+		return string(unquoted) == key
+	}
+	var s string
+	return json.Unmarshal(name, &s) == nil && s == key
+}
+
+// jsonValueEnd returns the offset following the JSON value starting at the
+// offset i of data. The value is only checked to be well delimited.
+func jsonValueEnd(data []byte, i int) (int, bool) {
+	if i >= len(data) {
+		return 0, false
+	}
+
+	switch data[i] {
+	case '"':
+		return jsonStringEnd(data, i)
+	case '{', '[':
+		depth := 0
+		for j := i; j < len(data); j++ {
+			switch data[j] {
+			case '"':
+				end, ok := jsonStringEnd(data, j)
+				if !ok {
+					return 0, false
+				}
+				j = end - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return j + 1, true
+				}
+			}
+		}
+		return 0, false
+	default:
+		j := i
+		for j < len(data) && !isJSONDelimiter(data[j]) {
+			j++
+		}
+		return j, j > i
+	}
+}
+
+// jsonStringEnd returns the offset following the JSON string starting at the
+// offset i of data.
+func jsonStringEnd(data []byte, i int) (int, bool) {
+	if i >= len(data) || data[i] != '"' {
+		return 0, false
+	}
+	for j := i + 1; j < len(data); j++ {
+		switch data[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1, true
+		}
+	}
+	return 0, false
+}
+
+func skipJSONSpace(data []byte, i int) int {
+	for i < len(data) && isJSONSpace(data[i]) {
+		i++
+	}
+	return i
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isJSONDelimiter(c byte) bool {
+	return isJSONSpace(c) || c == ',' || c == ':' || c == '}' || c == ']'
+}
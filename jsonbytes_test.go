@@ -0,0 +1,61 @@
+package lookup
+
+import (
+	"encoding/json"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+var jsonBytesFixture = []byte(`{
+	"id": 42,
+	"name": "foo",
+	"tags": ["a", "b", {"c": "d"}],
+	"escaped\"key": "e",
+	"nested": {"str": "x, ]}\"", "null": null, "dup": 1, "dup": 2},
+	"items": [{"name": "bar"}, {"name": "qux"}]
+}`)
+
+func (s *S) TestLookupJSONBytes(c *C) {
+	tests := []struct {
+		path string
+		want interface{}
+	}{
+		{"id", float64(42)},
+		{"name", "foo"},
+		{"tags[1]", "b"},
+		{"tags[2].c", "d"},
+		{`escaped"key`, "e"},
+		{"nested.str", `x, ]}"`},
+		{"nested.dup", float64(2)},
+		{"items[1].name", "qux"},
+		{"nested", map[string]interface{}{"str": `x, ]}"`, "null": nil, "dup": float64(2)}},
+		// Full lookups.
+		{"items.name", []string{"bar", "qux"}},
+		{"items[*].name", []string{"bar", "qux"}},
+	}
+	for _, test := range tests {
+		value, err := LookupJSONBytes(jsonBytesFixture, test.path, Options{})
+		c.Assert(err, IsNil, Commentf("path %q", test.path))
+		c.Assert(value, DeepEquals, test.want, Commentf("path %q", test.path))
+	}
+
+	value, err := LookupJSONBytes(jsonBytesFixture, "id", Options{JSONUseNumber: true})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, json.Number("42"))
+
+	value, err = LookupJSONBytes(jsonBytesFixture, "NAME", Options{MatchFunctions: []MatchFunc{strings.ToLower}})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "foo")
+
+	_, err = LookupJSONBytes(jsonBytesFixture, "missing", Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	_, err = LookupJSONBytes(jsonBytesFixture, "tags[5]", Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	_, err = LookupJSONBytes([]byte(`{"id": 42`), "id", Options{})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+}
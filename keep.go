@@ -0,0 +1,76 @@
+package lookup
+
+import (
+	"reflect"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Keep returns a copy of i, e.g. a response, holding only the values at paths,
+// the other fields being zero and the other map keys omitted. Paths are
+// resolved as by LookupAll, so a path aggregating over slices or maps keeps
+// the value of every element it fans out to. The slices holding values kept
+// have the length of those of i, with zero elements where nothing is kept. A
+// path which isn't found keeps nothing. The values kept are copied shallowly,
+// or deep copied if Options.CopyResult is set. The values found in expanded
+// ones aren't in i to be kept, so the options expanding values, e.g.
+// Options.ExpandStringAsJSON, fail with InvalidArgument.
+func Keep(i interface{}, paths []string, options ...Option) (interface{}, error) {
+	opts := NewOptions(options...)
+	if err := checkNoExpansion("Keep", opts); err != nil {
+		return nil, err
+	}
+	v := reflect.ValueOf(i)
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	out := reflect.New(v.Type()).Elem()
+	for _, path := range paths {
+		matches, err := LookupAll(i, path, opts)
+		if status.Code(err) == codes.NotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if err := copyPath(out, v, splitIndexes(splitPath(m.Path, &opts)), opts); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out.Interface(), nil
+}
+
+// checkNoExpansion returns an InvalidArgument error if opts expand values,
+// which fn can't rewrite in place.
+func checkNoExpansion(fn string, opts Options) error {
+	if canExpandType(opts) || opts.UnpackAny {
+		return status.Errorf(codes.InvalidArgument, "%s can't be used with options expanding values", fn)
+	}
+	return nil
+}
+
+// splitIndexes returns the segments of path with the indexes of keys split
+// into their own segments, e.g. "Items", "[0]" for "Items[0]", as copyPath
+// resolves them. The keys are left quoted or escaped.
+func splitIndexes(path []string) []string {
+	out := make([]string, 0, len(path))
+	for _, part := range path {
+		_, index, err := parseIndex(part)
+		if err != nil || index == noIndex {
+			out = append(out, part)
+			continue
+		}
+		// The index is the suffix starting with the last bracket.
+		start := strings.LastIndex(part, indexOpenChar)
+		if start > 0 {
+			out = append(out, part[:start])
+		}
+		out = append(out, part[start:])
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+package lookup
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestKeep(c *C) {
+	value, err := Keep(structFixture, []string{"String", "StructSlice.StructSlice.String", "Missing"})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, MyStruct{
+		String: "foo",
+		StructSlice: []*MyStruct{
+			{StructSlice: []*MyStruct{{String: "bar"}, {String: "foo"}}},
+			{StructSlice: []*MyStruct{{String: "qux"}, {String: "baz"}}},
+		},
+	})
+	// structFixture isn't modified.
+	c.Assert(structFixture.StructSlice[0].String, Equals, "foo")
+
+	value, err = Keep(&structFixture, []string{"Nested", "StructSlice[1].String"})
+	c.Assert(err, IsNil)
+	// The index is ignored on a pointer, as by Lookup.
+	c.Assert(value, DeepEquals, &MyStruct{StructSlice: []*MyStruct{{String: "foo"}, {String: "qux"}}})
+
+	value, err = Keep(structFixture, []string{"StructSlice[1].String"})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, MyStruct{StructSlice: []*MyStruct{nil, {String: "qux"}}})
+
+	value, err = Keep(nil, []string{"String"})
+	c.Assert(err, IsNil)
+	c.Assert(value, IsNil)
+
+	// The values expanded from strings can't be kept in them.
+	_, err = Keep(structFixture, []string{"JSONString.Struct.StructInArray"}, ExpandJSON())
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+	_, err = Keep(structFixture, []string{"String"}, Options{ExpandStringAsXML: true})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+}
+
+func (s *S) TestKeep_Maps(c *C) {
+	i := map[string]interface{}{
+		"user": map[string]interface{}{"Name": "bob", "password": "secret"},
+		"items": []interface{}{
+			map[string]interface{}{"id": 1, "internal": true},
+			map[string]interface{}{"id": 2, "internal": false},
+		},
+		"meta": "x",
+	}
+	value, err := Keep(i, []string{"user.name", "items.id"}, CaseInsensitive())
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, map[string]interface{}{
+		"user": map[string]interface{}{"Name": "bob"},
+		"items": []interface{}{
+			map[string]interface{}{"id": 1},
+			map[string]interface{}{"id": 2},
+		},
+	})
+
+	// The values kept are shared with i, unless they're copied.
+	i = map[string]interface{}{"tags": []string{"a"}}
+	value, err = Keep(i, []string{"tags"})
+	c.Assert(err, IsNil)
+	value.(map[string]interface{})["tags"].([]string)[0] = "b"
+	c.Assert(i["tags"], DeepEquals, []string{"b"})
+	value, err = Keep(i, []string{"tags"}, CopyResult())
+	c.Assert(err, IsNil)
+	value.(map[string]interface{})["tags"].([]string)[0] = "c"
+	c.Assert(i["tags"], DeepEquals, []string{"b"})
+}
@@ -0,0 +1,28 @@
+package lookup
+
+// Logger logs the events of lookups worth knowing when debugging the paths
+// they're given, see Options.Logger. It's implemented by *slog.Logger.
+type Logger interface {
+	// Debug logs msg with the alternating keys and values args.
+	Debug(msg string, args ...interface{})
+}
+
+// logDebug logs msg with args to opts.Logger, if set.
+func logDebug(opts *Options, msg string, args ...interface{}) {
+	if opts.Logger != nil {
+		opts.Logger.Debug(msg, args...)
+	}
+}
+
+// limitExceeded logs err, the error of a lookup exceeding the limit set by the
+// option named limit, and returns it.
+func limitExceeded(opts *Options, limit string, err error) error {
+	logDebug(opts, "lookup: limit exceeded", "limit", limit, "error", err)
+	return err
+}
+
+// logAmbiguousMatch logs that key matched both the candidates named match,
+// which it resolves to, and other.
+func logAmbiguousMatch(opts *Options, key, match, other string) {
+	logDebug(opts, "lookup: ambiguous key", "key", key, "match", match, "other", other)
+}
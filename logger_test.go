@@ -0,0 +1,53 @@
+package lookup
+
+import (
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+type testLogger struct {
+	mu   sync.Mutex
+	logs []string
+}
+
+func (l *testLogger) Debug(msg string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.logs = append(l.logs, msg)
+}
+
+func (s *S) TestLookup_Logger(c *C) {
+	l := &testLogger{}
+	_, err := Lookup(structFixture, "Map.missing.foo", WithLogger(l))
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+	c.Assert(l.logs, DeepEquals, []string{"lookup: segment not resolved"})
+
+	l.logs = nil
+	_, err = Lookup(structFixture, "String.foo", ExpandJSON(), WithLogger(l))
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+	c.Assert(l.logs, DeepEquals, []string{"lookup: value not expanded", "lookup: segment not resolved"})
+
+	l.logs = nil
+	_, err = Lookup(structFixture, "StructSlice[0].String", WithMaxDepth(1), WithLogger(l))
+	c.Assert(status.Code(err), Equals, codes.ResourceExhausted)
+	c.Assert(l.logs, DeepEquals, []string{"lookup: limit exceeded"})
+}
+
+func (s *S) TestLookup_LoggerAmbiguousMatch(c *C) {
+	i := map[string]int{"foo": 1, "FOO": 2}
+	l := &testLogger{}
+	value, err := Lookup(i, "Foo", CaseInsensitive(), WithLogger(l))
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 2)
+	c.Assert(l.logs, DeepEquals, []string{"lookup: ambiguous key"})
+
+	l.logs = nil
+	type T struct{ Foo, FOO int }
+	value, err = Lookup(T{1, 2}, "foo", CaseInsensitive(), WithLogger(l))
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 1)
+	c.Assert(l.logs, DeepEquals, []string{"lookup: ambiguous key"})
+}
@@ -5,10 +5,13 @@ very simple DSL you can access to any property, key or value of any value of Go.
 package lookup
 
 import (
-	"encoding/json"
+	"context"
+	"encoding"
+	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -18,19 +21,153 @@ const (
 	defaultSplitToken = "."
 	indexCloseChar    = "]"
 	indexOpenChar     = "["
+	wildcardChar      = "*"
+)
+
+const (
+	// noIndex is returned by parseIndex for a key without index.
+	noIndex = -1
+	// wildcardIndex is returned by parseIndex for a key[*], which explicitly
+	// aggregates over all the elements of the key.
+	wildcardIndex = -2
+	// filterIndex is returned by parseIndex for a key filter, e.g.
+	// key[~"app.*"], which aggregates over the entries of the map of the key
+	// whose keys match it.
+	filterIndex = -3
 )
 
 type MatchFunc func(string) string
 
 type Options struct {
-	// If true, any string that can be parsed into JSON will be expanded as map[string]interface{}
+	// If true, any string that can be parsed into JSON will be expanded as map[string]interface{}, []interface{}
+	// or a scalar, depending on the JSON document. The same applies to []byte and json.RawMessage values.
 	ExpandStringAsJSON bool
+	// If true, JSON numbers are expanded as json.Number instead of float64, so large integers such as IDs
+	// keep their precision.
+	JSONUseNumber bool
+	// If set, used instead of encoding/json to decode the expanded JSON documents. It must return a nil
+	// value or an error for data which isn't JSON.
+	DecodeJSON func(data []byte) (interface{}, error)
+	// If true, any string or []byte holding a base64-encoded JSON object or array (e.g. a JWT payload) will be
+	// decoded and expanded as with ExpandStringAsJSON.
+	ExpandBase64JSON bool
+	// If not empty, values are only expanded (see ExpandStringAsJSON, ExpandBase64JSON and ExpandStringAsXML)
+	// when their path starts with one of these paths, e.g. "Event.Payload" allows expanding
+	// "Event.Payload" and the values nested into it. Indexes are ignored.
+	ExpandPaths []string
+	// If true, the protobuf wrapper messages, such as google.protobuf.StringValue or Int64Value, are replaced
+	// by the scalar they hold.
+	UnwrapWrappers bool
+	// If true, google.protobuf.Timestamp and Duration messages are converted into time.Time and
+	// time.Duration. The methods of time.Time and time.Duration taking no argument can then be used as
+	// keys, e.g. "CreateTime.Unix".
+	ConvertTimes bool
+	// If true, the database/sql Null types, such as sql.NullString, are replaced by the value they hold, or
+	// nil when they aren't valid.
+	UnwrapSQLNull bool
+	// If true, the values implementing driver.Valuer, such as custom decimal or UUID types, are replaced by
+	// the result of their Value method, on which the path can continue. An error returned by Value fails
+	// the lookup.
+	UnwrapValuers bool
+	// If true, google.protobuf.Any messages are unpacked, through the global type registry, into the message
+	// they hold, so the path can continue into it. Unpacking an Any of an unknown type fails with
+	// FailedPrecondition.
+	UnpackAny bool
+	// Custom expanders tried, in order, on the values found along the path, after the built-in ones enabled by
+	// ExpandStringAsJSON, ExpandBase64JSON, ExpandStringAsXML and UnpackAny. The first expander accepting a value
+	// replaces it by its expanded value.
+	Expanders []Expander
+	// If not 0, expanding a value larger than this number of bytes fails with ResourceExhausted.
+	MaxExpandBytes int
+	// If not 0, expanding a document with objects, arrays or elements nested deeper than this fails with
+	// ResourceExhausted.
+	MaxExpandDepth int
+	// If set, the values decoded by the expansion of strings and byte slices are cached and shared with the
+	// other lookups using the same cache. The cache doesn't tell Expanders apart, so it must not be shared by
+	// lookups using different ones.
+	ExpansionCache *ExpansionCache
+	// If true, any string or []byte that can be parsed into XML will be expanded as a map[string]interface{}
+	// holding the root element. Elements holding only text are strings, others are maps of their children,
+	// of their attributes prefixed with "@" and of their text under "#text". Repeated children are
+	// []interface{}.
+	ExpandStringAsXML bool
 	// A list of functions to be applied before compaing the path and field name.
 	// A section of path and a field in the struct match if any of MatchFunctions returns the same string.
 	// i.e. matchFunc(path) == matchFunc(field)
+	// An exact match always wins, then the functions are tried in order, and the first matching field
+	// (in declaration order) or map key (in sorted order) is used.
 	MatchFunctions []MatchFunc
-	// The token used to split a path. If not specified, by default it's ".".
+	// The token used to split a path, which can be several characters long (e.g. "->"). If not specified,
+	// by default it's ".".
 	SplitToken string
+	// If true, the path isn't split and is used as a single key, e.g. to look up map keys containing dots.
+	NoSplit bool
+	// The struct tag key (e.g. "json", "yaml", "bson") whose names can be used to address struct fields,
+	// in addition to the Go field names. Options such as ",omitempty" are ignored, and fields tagged "-"
+	// can only be addressed by their Go name. With "protobuf", generated messages are addressed by the names
+	// of their .proto fields (e.g. "source_context").
+	TagKey string
+	// If true, a key matching several fields or map keys under the same match function is an error
+	// instead of resolving to the first of them. Exact matches are never ambiguous.
+	FailOnAmbiguousMatch bool
+	// If set, the names of the fields of structs normalized by MatchFunctions are cached and shared with the
+	// other lookups using the same cache, see NameCache.
+	NameCache *NameCache
+	// The maximum number of steps a lookup may take, counting each path segment and each level of
+	// aggregation over a slice or map. Lookups going deeper fail with ResourceExhausted. If 0, there is no limit.
+	MaxDepth int
+	// If true, the fields of embedded structs are not promoted: they can only be reached through the
+	// embedded struct, addressed by its type name (e.g. "Base.ID" instead of "ID").
+	NoPromotedFields bool
+	// If true, a key which isn't found on a slice or a map is an error instead of being looked up on every
+	// element (implicit aggregation). Aggregation must then be explicit with key[*].
+	Strict bool
+	// If true, aggregating over a slice keeps a zero value (nil for pointers and interfaces) for every
+	// element on which the rest of the path isn't found, so the i-th value of the result comes from the
+	// i-th element. Values which are themselves slices are still merged.
+	PreservePositions bool
+	// If true, the values found by an aggregation which are themselves slices, e.g. those of a nested
+	// aggregation, are kept as elements of the result instead of being concatenated, so
+	// "StructSlice.StructSlice.String" returns a [][]string grouped by the outer element.
+	PreserveNesting bool
+	// If true, the duplicates of the values merged by aggregations are removed, keeping the first of each,
+	// e.g. when many elements share the same nested value. Values of comparable types are compared with ==,
+	// the others with reflect.DeepEqual. Count then counts the values left, and PreservePositions is moot.
+	DedupeAggregated bool
+	// If true, aggregating over a map returns a map with the same keys, holding the value found on each
+	// of its values, instead of a slice.
+	KeepMapKeys bool
+	// If true, the keys of maps with string keys holding the split token are handled as nested paths, as if
+	// the map was unflattened: {"a.b.c": 1, "a.b.d": 2} is looked up as {"a": {"b": {"c": 1, "d": 2}}}, so
+	// "a.b.c" and aggregations such as "a.b" work on env or Consul style flat maps. A key which is also the
+	// prefix of other keys is hidden by them.
+	FlatKeys bool
+	// The layouts tried, in order, to parse strings into times by LookupTime and LookupResult.Time. If empty,
+	// time.RFC3339Nano is used. Strings which don't match any layout are parsed as Unix times if they are
+	// numbers.
+	TimeLayouts []string
+	// If greater than 1, aggregations over slices and maps of at least 1000 elements look up the rest of the
+	// path on their elements with up to this number of goroutines, and merge the values in order. Expanders,
+	// MatchFunctions, DecodeJSON and OnSegment must then be safe for concurrent use.
+	Parallelism int
+	// The unit of the numbers converted into durations by LookupDuration, e.g. time.Second. If 0, numbers are
+	// milliseconds.
+	DurationUnit time.Duration
+	// If set, called before and after each segment of a path is resolved, e.g. to log the resolution or to deny
+	// some paths. An error returned fails the lookup with it, instead of the error of the segment, if any.
+	OnSegment func(e SegmentEvent) error
+	// If true, the values returned by lookups are deep copies of the values found, sharing no pointer, map or
+	// slice with i, so they can be modified, or kept while i is, without affecting each other. See LookupValue
+	// for the values which can be set in i instead.
+	CopyResult bool
+	// If set, logs at debug level the segments not found, the keys matching several fields or map keys, the
+	// values failing to expand, and the limits exceeded, e.g. a *slog.Logger. It must be safe for concurrent
+	// use.
+	Logger Logger
+	// If true, a path going through a nil pointer or interface, e.g. "Nested.String" when Nested is nil, is
+	// found with a nil value, as if the value was absent, instead of failing with a NotFound error for which
+	// IsNilPointer returns true.
+	NilPointersAsAbsent bool
 }
 
 // LookupString performs a lookup into a value, using a string. Same as `Lookup`
@@ -39,84 +176,405 @@ type Options struct {
 // match with a Field or a MapIndex. For slice you can use the syntax key[index]
 // to access a specific index. If one key owns to a slice and an index is not
 // specificied the rest of the path will be apllied to evaley value of the
-// slice, and the value will be merged into a slice.
-func Lookup(i interface{}, path string, opts Options) (interface{}, error) {
-	v, err := lookup(i, strings.Split(path, getSplitToken(&opts)), opts)
-	if err == nil {
-		return v.Interface(), nil
+// slice, and the value will be merged into a slice. The syntax key[*] does the
+// same explicitly, and is required when Options.Strict is set. The syntax
+// key[~"glob"] or key[~/regexp/] aggregates over the entries of a map whose
+// keys match, e.g. Labels[~"app.kubernetes.io/*"]. Brackets which
+// aren't a trailing index must be escaped with a backslash, e.g. `foo\[0\]`,
+// or the key quoted, e.g. `"foo[0]"[1]`.
+func Lookup(i interface{}, path string, options ...Option) (interface{}, error) {
+	return LookupContext(context.Background(), i, path, options...)
+}
+
+// LookupContext is like Lookup, but stops with the error of ctx, converted to
+// a status error, as soon as ctx is done.
+func LookupContext(ctx context.Context, i interface{}, path string, options ...Option) (interface{}, error) {
+	opts := NewOptions(options...)
+	if ctx.Err() == nil && canFastLookup(&opts) {
+		if v, ok := fastLookup(i, path, &opts); ok {
+			return resultInterface(v, &opts), nil
+		}
+	}
+
+	t := newTraversal(ctx, opts)
+	v, err := t.lookup(reflect.ValueOf(i), splitPath(path, &opts), nil, 0)
+	if err != nil || !v.IsValid() {
+		return nil, err
+	}
+	return resultInterface(v, &opts), nil
+}
+
+// LookupValue is like Lookup, but returns the reflect.Value found instead of
+// its interface, invalid if nothing is found. If i is a pointer, the values
+// reached from it through struct fields, pointers and indexes, without
+// aggregation nor map values, are addressable, so they can be set. Unlike
+// Lookup, which ignores them for compatibility, the indexes of the keys looked
+// up on pointers and interfaces are honored, e.g. "Friends[1]" on a *User is
+// the second friend, not all of them.
+func LookupValue(i interface{}, path string, options ...Option) (reflect.Value, error) {
+	opts := NewOptions(options...)
+	t := newTraversal(context.Background(), opts)
+	t.addressable = true
+	return t.lookup(reflect.ValueOf(i), splitPath(path, &opts), nil, 0)
+}
+
+// Exists returns whether path is found on i, i.e. whether Lookup would return
+// a non-nil value without error. Aggregations stop at the first value found,
+// without merging, so the errors the later elements would fail with are
+// ignored.
+func Exists(i interface{}, path string, options ...Option) bool {
+	opts := NewOptions(options...)
+	if canFastLookup(&opts) {
+		if _, ok := fastLookup(i, path, &opts); ok {
+			return true
+		}
 	}
-	return nil, err
+
+	t := newTraversal(context.Background(), opts)
+	t.existsOnly = true
+	v, err := t.lookup(reflect.ValueOf(i), splitPath(path, &opts), nil, 0)
+	return err == nil && v.IsValid()
 }
 
-func lookup(i interface{}, path []string, opts Options) (reflect.Value, error) {
-	value := reflect.ValueOf(i)
+// Count returns the number of elements path resolves to on i: the length of
+// the slice or the map found, the number of values an aggregation would
+// return, or 1 for other values. The values of aggregations aren't merged.
+func Count(i interface{}, path string, options ...Option) (int, error) {
+	opts := NewOptions(options...)
+	t := newTraversal(context.Background(), opts)
+	// The duplicates are only known from the values.
+	t.countOnly = !opts.DedupeAggregated
+	v, err := t.lookup(reflect.ValueOf(i), splitPath(path, &opts), nil, 0)
+	switch {
+	case err != nil:
+		return 0, err
+	case !v.IsValid():
+		return 0, nil
+	case v.Type() == countType:
+		return int(v.Int()), nil
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array || v.Kind() == reflect.Map:
+		return v.Len(), nil
+	}
+	return 1, nil
+}
+
+// count is the result of the aggregations of a Count.
+type count int
+
+var countType = reflect.TypeOf(count(0))
+
+// countValues returns the length of the slice mergeValue would return for
+// values, whose counts are added, unless nested is set.
+func countValues(values []reflect.Value, nested bool) count {
+	var n count
+	mergeable, first := false, true
+	for _, v := range values {
+		if !v.IsValid() {
+			continue
+		}
+		if first {
+			mergeable, first = isMergeable(v), false
+		}
+		switch {
+		case nested:
+			n++
+		case v.Type() == countType:
+			n += count(v.Int())
+		case mergeable && isMergeable(v):
+			n += count(v.Len())
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+// traversal holds the state shared by all the steps of a single lookup.
+type traversal struct {
+	ctx  context.Context
+	opts Options
+	// The containers currently being aggregated over, to detect cycles.
+	visiting map[visit]bool
+	// If set, aggregations return their first valid value instead of
+	// merging them, as only the existence of the result matters.
+	existsOnly bool
+	// If set, aggregations return the number of values they would merge, as
+	// a count.
+	countOnly bool
+	// The path being looked up, if it was compiled.
+	compiled *CompiledPath
+	// If set, aggregations are never parallelized, as the traversal is
+	// already one of the goroutines of a parallel aggregation.
+	sequential bool
+	// If set, the first aggregation is recorded in this Iterator instead of
+	// being done, so its elements are looked up as they're consumed.
+	lazy *Iterator
+	// The key values reused to look up maps with string keys, by key type.
+	mapKeys map[reflect.Type]reflect.Value
+	// The indexes of the keys of the maps matched by name, see mapKeyIndex.
+	mapIndexes map[visit]*keyIndex
+	// The name of the field or the key last matched by getValueByName.
+	match string
+	// The path of a LookupMany the aggregations are done for, see visit.
+	entry int
+	// If set, the values found are appended to it with their concrete paths
+	// instead of being merged, see LookupAll.
+	matches *[]PathMatch
+	// If set, the numeric indexes of the keys looked up on pointers and
+	// interfaces are honored, as the values found are to be set in place.
+	addressable bool
+}
+
+// visit identifies a container aggregated over with a remaining path.
+type visit struct {
+	ptr  uintptr
+	typ  reflect.Type
+	len  int
+	path int
+	// The index, plus one, of the path of a LookupMany, whose paths share the
+	// traversal but not their cycles.
+	entry int
+}
+
+func newTraversal(ctx context.Context, opts Options) *traversal {
+	return &traversal{ctx: ctx, opts: opts, visiting: make(map[visit]bool)}
+}
+
+func (t *traversal) checkContext() error {
+	if err := t.ctx.Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	return nil
+}
+
+// lookup resolves path on v. prefix is the path of v from the root of the
+// lookup, and depth is the number of steps (segments and aggregations) already
+// taken to reach v, checked against opts.MaxDepth.
+func (t *traversal) lookup(v reflect.Value, path, prefix []string, depth int) (reflect.Value, error) {
+	opts := t.opts
+	value := v
 	var parent reflect.Value
 	var err error
+	aggregated := false
 
 	for i, part := range path {
-		if opts.ExpandStringAsJSON {
-			// Expand the value if it's expandable and not the last value.
-			if out := expandStringAsJSON(value); out != nil {
-				value = reflect.ValueOf(out)
-			}
+		if err := checkDepth(depth+i+1, opts); err != nil {
+			return reflect.Value{}, err
 		}
+		if err := t.checkContext(); err != nil {
+			return reflect.Value{}, err
+		}
+		// Expand the value if it's expandable and not the last value.
+		unexpanded := value
+		if value, err = t.expand(value, prefix, path[:i]); err != nil {
+			return reflect.Value{}, err
+		}
+		if opts.FlatKeys {
+			value = unflattenValue(value, getSplitToken(&opts))
+		}
+		expanded := opts.OnSegment != nil && isExpanded(unexpanded, value)
 		parent = value
+		if err := t.onSegment(prefix, path[:i], SegmentEvent{Before: true, Segment: part, Expanded: expanded}, value); err != nil {
+			return reflect.Value{}, err
+		}
 
-		value, err = getValueByName(value, part, opts)
+		var key string
+		var index int
+		if key, index, err = t.parseSegment(path, i); err == nil {
+			value, index, err = t.getSegment(value, key, index, prefix, path[:i+1])
+		}
 		if err == nil {
+			wildcard := index == wildcardIndex
+			e := SegmentEvent{Segment: part, Expanded: expanded, Match: t.match, Aggregated: wildcard}
+			if err := t.onSegment(prefix, path[:i], e, value); err != nil {
+				return reflect.Value{}, err
+			}
+			if wildcard {
+				value, err = t.aggreateAggregableValue(value, path[i+1:], joinPath(prefix, path[:i+1]), depth+i+1)
+				aggregated = true
+				break
+			}
 			continue
 		}
 
-		if !isAggregable(parent) {
+		if m, ok := syncMapSnapshot(parent); ok {
+			parent = m
+		}
+		if !isAggregable(parent) || status.Code(err) != codes.NotFound {
+			err = t.segmentFailed(prefix, path[:i], SegmentEvent{Segment: part, Expanded: expanded, Err: err})
 			break
 		}
+		if opts.Strict {
+			err = status.Errorf(codes.NotFound, "key %q not found; use %s[*] to aggregate over a %s", part, part, parent.Kind())
+			return reflect.Value{}, t.segmentFailed(prefix, path[:i], SegmentEvent{Segment: part, Expanded: expanded, Err: err})
+		}
+		if err := t.onSegment(prefix, path[:i], SegmentEvent{Segment: part, Expanded: expanded, Aggregated: true}, parent); err != nil {
+			return reflect.Value{}, err
+		}
 
-		value, err = aggreateAggregableValue(parent, path[i:], opts)
+		value, err = t.aggreateAggregableValue(parent, path[i:], joinPath(prefix, path[:i]), depth+i+1)
+		aggregated = true
 		break
 	}
 
+	if err == nil && isStructpb(value) {
+		value = getRealValue(value)
+	}
+	if err == nil && len(path) == 0 {
+		// An aggregated element.
+		value, err = unwrapValue(value, opts)
+	}
+	if err == nil && t.matches != nil && !aggregated && value.IsValid() {
+		t.addMatch(prefix, path, value)
+	}
 	return value, err
 }
 
-func getValueByName(v reflect.Value, key string, opts Options) (reflect.Value, error) {
-	var value reflect.Value
-	var index int = -1
-	var err error
+// parseSegment returns the key and the index of path[i], pre-parsed if path is
+// a suffix of the compiled path of t, as the paths of aggregations are.
+func (t *traversal) parseSegment(path []string, i int) (string, int, error) {
+	if c := t.compiled; c != nil && len(path) <= len(c.segments) {
+		s := c.segments[len(c.segments)-len(path)+i]
+		if s.part == path[i] {
+			return s.key, s.index, nil
+		}
+	}
+	return parseIndex(path[i])
+}
+
+// getSegment resolves the path segment made of key and index on v. prefix and
+// path make up the path of the resolved value. The index is returned along
+// with the value, which isn't indexed for wildcardIndex, nor for filterIndex,
+// for which the map is filtered and wildcardIndex returned.
+func (t *traversal) getSegment(v reflect.Value, key string, index int, prefix, path []string) (reflect.Value, int, error) {
+	if isStructpb(v) {
+		v = getRealValue(v)
+	}
+	// For compatibility, the numeric index of a key looked up on a pointer or
+	// an interface is ignored, e.g. "StructSlice[0]" on a *MyStruct resolves to
+	// the whole StructSlice, unless the value is to be set, see LookupValue.
+	if index >= 0 && !t.addressable && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		index = noIndex
+	}
 
-	key, index, err = parseIndex(key)
+	value, err := t.getValueByName(v, key)
+	if err != nil && t.opts.NilPointersAsAbsent && IsNilPointer(err) {
+		return reflect.Value{}, noIndex, nil
+	}
 	if err != nil {
-		return reflect.Value{}, err
+		return value, index, err
+	}
+	if value, err = unwrapValue(value, t.opts); err != nil || index == noIndex {
+		return value, index, err
+	}
+
+	// The list may be encoded, e.g. "JSONList[0]".
+	if value, err = t.expand(value, prefix, path); err != nil {
+		return reflect.Value{}, index, err
+	}
+	if index == wildcardIndex || index == filterIndex {
+		if m, ok := syncMapSnapshot(value); ok {
+			value = m
+		}
+		if index == filterIndex {
+			// The filtered map is aggregated over as with key[*].
+			value, err = filterKeys(value, key, path[len(path)-1])
+			return value, wildcardIndex, err
+		}
+		if !isAggregable(value) {
+			return reflect.Value{}, index, status.Errorf(codes.InvalidArgument, "key %q is not a list or a map", key)
+		}
+		return value, index, nil
+	}
+
+	if value.Type().Kind() != reflect.Slice {
+		return reflect.Value{}, index, status.Errorf(codes.InvalidArgument, "key %q is not a list", key)
+	}
+	if index < 0 {
+		return reflect.Value{}, index, status.Errorf(codes.InvalidArgument, "invalid index %d of key %q", index, key)
+	}
+	if index >= value.Len() {
+		return reflect.Value{}, index, status.Errorf(codes.NotFound, "index %d of key %q out of range", index, key)
+	}
+	value, err = unwrapValue(getRealValue(value.Index(index)), t.opts)
+	return value, index, err
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	stringerType        = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	stringType          = reflect.TypeOf("")
+)
+
+// getValueByName returns the field or map value named key of v.
+func (t *traversal) getValueByName(v reflect.Value, key string) (reflect.Value, error) {
+	opts := t.opts
+	var value reflect.Value
+
+	if isStructpb(v) {
+		v = getRealValue(v)
+	}
+	t.match = key
+	if !v.IsValid() {
+		return reflect.Value{}, nilPointerError(key)
+	}
+	if r, ok := asPathResolver(v); ok {
+		i, err := r.LookupKey(key)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if value = reflect.ValueOf(i); !value.IsValid() {
+			return reflect.Value{}, status.Errorf(codes.NotFound, "key %q not found", key)
+		}
+		return value, nil
+	}
+	if v.IsValid() && hasMethodKeys(v.Type()) {
+		if v.Kind() == reflect.Struct {
+			if f, ok, _ := structField(v.Type(), key, opts); ok && f.PkgPath == "" {
+				t.match = f.Name
+				return getRealValue(v.FieldByIndex(f.Index)), nil
+			}
+		}
+		value, ok, err := getMethodValue(v, key, opts)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if !ok {
+			return reflect.Value{}, status.Errorf(codes.NotFound, "key %q not found", key)
+		}
+		return value, nil
+	}
+	if v.IsValid() && v.Type() == syncMapType {
+		return getSyncMapValue(v, key, opts)
 	}
 	switch v.Kind() {
 	case reflect.Ptr, reflect.Interface:
-		return getValueByName(v.Elem(), key, opts)
+		return t.getValueByName(v.Elem(), key)
 	case reflect.Struct:
-		value = v.FieldByName(key)
-
-		if value.Kind() == reflect.Invalid {
-			// We don't use FieldByNameFunc, since it returns zero value if the
-			// match func matches multiple fields. Iterate here and return the
-			// first matching field.
-			for i := 0; i < v.NumField(); i++ {
-				if compareWithMatchFunc(opts.MatchFunctions, v.Type().Field(i).Name, key) {
-					value = v.Field(i)
-					break
-				}
-			}
+		f, ok, err := structField(v.Type(), key, opts)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if ok {
+			value = fieldByIndex(v, f.Index)
+			t.match = f.Name
+		} else if value, ok = t.interfaceField(v, key); ok {
+			return value, nil
 		}
 
 	case reflect.Map:
-		kValue := reflect.Indirect(reflect.New(v.Type().Key()))
-		kValue.SetString(key)
-		value = v.MapIndex(kValue)
-		if value.Kind() == reflect.Invalid {
-			iter := v.MapRange()
-			for iter.Next() {
-				if compareWithMatchFunc(opts.MatchFunctions, key, iter.Key().String()) {
-					kValue.SetString(iter.Key().String())
-					value = v.MapIndex(kValue)
-					break
-				}
+		if kValue, ok := t.mapKey(v.Type().Key(), key); ok {
+			value = v.MapIndex(kValue)
+		}
+		if value.Kind() == reflect.Invalid && hasKeyNames(v.Type().Key(), opts) {
+			index := t.mapKeyIndex(v)
+			i, err := index.match(key, opts)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			if i != -1 {
+				value = v.MapIndex(index.keys[i])
+				t.match = index.names[i][0]
 			}
 		}
 	}
@@ -125,48 +583,407 @@ func getValueByName(v reflect.Value, key string, opts Options) (reflect.Value, e
 		return reflect.Value{}, status.Errorf(codes.NotFound, "key %q not found", key)
 	}
 
-	value = getRealValue(value)
-	if index != -1 {
-		if value.Type().Kind() != reflect.Slice {
-			return reflect.Value{}, status.Errorf(codes.InvalidArgument, "key %q is not a list", key)
+	return getRealValue(value), nil
+}
+
+// mapKey returns the key of type t that key stands for. Key types
+// implementing encoding.TextUnmarshaler are built with UnmarshalText, string
+// kinds are set directly, and the numbers and booleans parsed, e.g. 1 for "1"
+// in a map[int]T. It returns false if key can't be converted to t, e.g. if it
+// overflows t or if t is a struct.
+func mapKey(t reflect.Type, key string) (reflect.Value, bool) {
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		kValue := reflect.New(t)
+		if err := kValue.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key)); err != nil {
+			return reflect.Value{}, false
 		}
+		return kValue.Elem(), true
+	}
 
-		value = getRealValue(value.Index(index))
+	kValue := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		kValue.SetString(key)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(key, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, false
+		}
+		kValue.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(key, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, false
+		}
+		kValue.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(key, t.Bits())
+		if err != nil {
+			return reflect.Value{}, false
+		}
+		kValue.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(key)
+		if err != nil {
+			return reflect.Value{}, false
+		}
+		kValue.SetBool(b)
+	case reflect.Interface:
+		// The string keys, the others are matched by their names.
+		if !stringType.AssignableTo(t) {
+			return reflect.Value{}, false
+		}
+		kValue.Set(reflect.ValueOf(key))
+	default:
+		return reflect.Value{}, false
 	}
+	return kValue, true
+}
 
-	return value, nil
+// mapKeyNames returns the names the map key k can be addressed by: its
+// String() form if stringer is set, followed by the key itself if it's a
+// string kind, or its decimal form if it's a number or a boolean. Other keys,
+// e.g. structs, have no name but their String() form. The interface keys are
+// named by their fmt.Sprint form.
+func mapKeyNames(k reflect.Value, stringer bool) []string {
+	if k.Kind() == reflect.Interface {
+		return []string{fmt.Sprint(k.Interface())}
+	}
+	var names []string
+	if stringer {
+		names = append(names, k.Interface().(fmt.Stringer).String())
+	}
+	switch k.Kind() {
+	case reflect.String:
+		names = append(names, k.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		names = append(names, strconv.FormatInt(k.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		names = append(names, strconv.FormatUint(k.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		names = append(names, strconv.FormatFloat(k.Float(), 'g', -1, k.Type().Bits()))
+	case reflect.Bool:
+		names = append(names, strconv.FormatBool(k.Bool()))
+	}
+	return names
 }
 
+// byNames sorts map keys by their names.
+type byNames struct {
+	keys  []reflect.Value
+	names [][]string
+}
+
+func (s byNames) Len() int { return len(s.keys) }
+
+func (s byNames) Less(i, j int) bool {
+	a, b := s.names[i], s.names[j]
+	for k := 0; k < len(a) && k < len(b); k++ {
+		if a[k] != b[k] {
+			return a[k] < b[k]
+		}
+	}
+	return len(a) < len(b)
+}
+
+func (s byNames) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.names[i], s.names[j] = s.names[j], s.names[i]
+}
+
+// expand expands v according to opts, if its path, made up of prefix and
+// path, is allowed by opts.ExpandPaths.
+func (t *traversal) expand(v reflect.Value, prefix, path []string) (reflect.Value, error) {
+	if len(t.opts.ExpandPaths) == 0 || t.canExpand(prefix, path) {
+		return expandValue(v, t.opts)
+	}
+	return v, nil
+}
+
+// canExpand returns whether the path made up of prefix and path starts with
+// one of opts.ExpandPaths. The indexes of the segments are ignored.
+func (t *traversal) canExpand(prefix, path []string) bool {
+	n := len(prefix) + len(path)
+	segment := func(i int) string {
+		if i < len(prefix) {
+			return prefix[i]
+		}
+		return path[i-len(prefix)]
+	}
+
+	for _, p := range t.opts.ExpandPaths {
+		match := true
+		allowed := newPathScanner(p, &t.opts)
+		for i := 0; allowed.scan(); i++ {
+			if i >= n {
+				match = false
+				break
+			}
+			want, _, _ := parseIndex(allowed.part)
+			got, _, _ := parseIndex(segment(i))
+			if j, _ := matchName(1, func(int) []string { return []string{got} }, want, t.opts); j == -1 {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// joinPath returns the concatenation of prefix and path.
+func joinPath(prefix, path []string) []string {
+	return append(prefix[:len(prefix):len(prefix)], path...)
+}
+
+func checkDepth(depth int, opts Options) error {
+	if opts.MaxDepth > 0 && depth > opts.MaxDepth {
+		return limitExceeded(&opts, "MaxDepth", status.Errorf(codes.ResourceExhausted, "lookup exceeds the max depth of %d", opts.MaxDepth))
+	}
+	return nil
+}
+
+// getRealValue dereferences v, and converts the google.protobuf.Struct,
+// ListValue and Value messages into maps, slices and scalars.
 func getRealValue(v reflect.Value) reflect.Value {
-	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
-		v = v.Elem()
+	for v.IsValid() {
+		if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			v = v.Elem()
+			continue
+		}
+		if s, ok := fromStructpb(v); ok {
+			v = s
+			continue
+		}
+		break
+	}
+	return v
+}
+
+// elemValue returns the value held by the element v of a container, as
+// reflect.ValueOf(v.Interface()) does, without boxing it into an interface.
+func elemValue(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Interface {
+		return v.Elem()
 	}
 	return v
 }
 
-func aggreateAggregableValue(v reflect.Value, path []string, opts Options) (reflect.Value, error) {
-	values := make([]reflect.Value, 0)
+func (t *traversal) aggreateAggregableValue(v reflect.Value, path, prefix []string, depth int) (reflect.Value, error) {
+	opts := t.opts
+
+	// Aggregating over a container which is already being aggregated over
+	// with the same path would never end, e.g. a slice containing itself.
+	key := visit{ptr: v.Pointer(), typ: v.Type(), len: v.Len(), path: len(path), entry: t.entry}
+	if t.visiting[key] {
+		return reflect.Value{}, nil
+	}
+	t.visiting[key] = true
+	defer delete(t.visiting, key)
 
 	l := v.Len()
 	if l == 0 {
-		ty, ok := lookupType(v.Type(), path...)
-		if !ok {
+		// The type the values of the elements would have.
+		ty, err := typeOfPath(v.Type().Elem(), path, 0, opts)
+		if err != nil {
 			return reflect.Value{}, status.Errorf(codes.NotFound, "path %q not found", strings.Join(path, getSplitToken(&opts)))
 		}
-		return reflect.MakeSlice(reflect.SliceOf(ty), 0, 0), nil
+		if t.countOnly {
+			// Nothing to merge, as the empty slice would be.
+			return reflect.ValueOf(count(0)), nil
+		}
+		if v.Kind() == reflect.Map && opts.KeepMapKeys {
+			return reflect.MakeMap(reflect.MapOf(v.Type().Key(), ty)), nil
+		}
+		return reflect.MakeSlice(aggregatedType(ty, opts), 0, 0), nil
 	}
 
+	if t.matches != nil {
+		return reflect.Value{}, t.aggregateMatches(v, path, prefix, depth)
+	}
+	if v.Kind() == reflect.Map && opts.KeepMapKeys {
+		if t.countOnly {
+			n, err := t.countMapValues(v, path, prefix, depth)
+			return reflect.ValueOf(n), err
+		}
+		if !t.existsOnly {
+			return t.aggregateMap(v, path, prefix, depth)
+		}
+	}
+	if it := t.lazy; it != nil {
+		t.lazy = nil
+		it.aggregate(v, key, path, prefix, depth)
+		return reflect.Value{}, nil
+	}
+
+	buf := getValues(l)
+	values := *buf
+	defer func() { putValues(buf, values) }()
+
 	index := indexFunction(v)
-	for i := 0; i < l; i++ {
-		value, err := lookup(index(i).Interface(), path, opts)
+	lookupElement := func(t *traversal, i int) (reflect.Value, error) {
+		value, err := t.lookup(elemValue(index(i)), path, prefix, depth)
+		if err != nil && t.skipsElement(v, index(i), path, err) {
+			value, err = reflect.Value{}, nil
+		}
+		if err != nil && (!opts.PreservePositions || status.Code(err) != codes.NotFound) {
+			return reflect.Value{}, err
+		}
+		return value, nil
+	}
+	if t.canParallelize(l) && !t.existsOnly {
+		values = values[:l]
+		if err := t.lookupParallel(values, lookupElement); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	for i := len(values); i < l; i++ {
+		if err := t.checkContext(); err != nil {
+			return reflect.Value{}, err
+		}
+		value, err := lookupElement(t, i)
 		if err != nil {
 			return reflect.Value{}, err
 		}
+		if t.existsOnly && value.IsValid() {
+			// The aggregation exists, whatever the other elements hold.
+			return value, nil
+		}
 
 		values = append(values, value)
 	}
 
-	return mergeValue(values), nil
+	if opts.PreservePositions {
+		fillZeroValues(values, v.Type().Elem(), path, opts)
+	}
+	if t.countOnly {
+		for _, value := range values {
+			if value.IsValid() {
+				return reflect.ValueOf(countValues(values, opts.PreserveNesting)), nil
+			}
+		}
+		// As merged, so the position is filled in by an enclosing aggregation
+		// under PreservePositions.
+		return reflect.Value{}, nil
+	}
+	if t.existsOnly {
+		// None of the values was valid, but the zero values filled in.
+		for _, value := range values {
+			if value.IsValid() {
+				return value, nil
+			}
+		}
+		return reflect.Value{}, nil
+	}
+	return mergeAggregated(values, opts), nil
+}
+
+// countMapValues returns the size of the map aggregateMap would return.
+func (t *traversal) countMapValues(v reflect.Value, path, prefix []string, depth int) (count, error) {
+	var n count
+	iter := v.MapRange()
+	for iter.Next() {
+		if err := t.checkContext(); err != nil {
+			return 0, err
+		}
+		value, err := t.lookup(elemValue(iter.Value()), path, prefix, depth)
+		if err != nil && t.skipsElement(v, iter.Value(), path, err) {
+			value, err = reflect.Value{}, nil
+		}
+		if err != nil {
+			if !t.opts.PreservePositions || status.Code(err) != codes.NotFound {
+				return 0, err
+			}
+		}
+		if value.IsValid() || t.opts.PreservePositions {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// aggregateMap looks up path on every value of the map v, and returns the
+// results keyed by the keys of v.
+func (t *traversal) aggregateMap(v reflect.Value, path, prefix []string, depth int) (reflect.Value, error) {
+	keys := v.MapKeys()
+	buf := getValues(len(keys))
+	values := (*buf)[:len(keys)]
+	defer putValues(buf, values)
+	for i, k := range keys {
+		if err := t.checkContext(); err != nil {
+			return reflect.Value{}, err
+		}
+		value, err := t.lookup(elemValue(v.MapIndex(k)), path, prefix, depth)
+		if err != nil && t.skipsElement(v, v.MapIndex(k), path, err) {
+			value, err = reflect.Value{}, nil
+		}
+		if err != nil {
+			if !t.opts.PreservePositions || status.Code(err) != codes.NotFound {
+				return reflect.Value{}, err
+			}
+			value = reflect.Value{}
+		}
+		values[i] = value
+	}
+	if t.opts.PreservePositions {
+		fillZeroValues(values, v.Type().Elem(), path, t.opts)
+	}
+
+	// The values may be of different types, e.g. after JSON expansion.
+	var ty reflect.Type
+	for _, value := range values {
+		if !value.IsValid() {
+			continue
+		}
+		if ty == nil {
+			ty = value.Type()
+		} else if ty != value.Type() {
+			ty = interfaceType
+			break
+		}
+	}
+	if ty == nil {
+		return reflect.Value{}, nil
+	}
+
+	m := reflect.MakeMapWithSize(reflect.MapOf(v.Type().Key(), ty), len(keys))
+	for i, k := range keys {
+		if values[i].IsValid() {
+			m.SetMapIndex(k, values[i])
+		}
+	}
+	return m, nil
+}
+
+// fillZeroValues replaces the invalid values by the zero value of the type of
+// the valid ones, or of the type resolved by path on elemType if there is none.
+func fillZeroValues(values []reflect.Value, elemType reflect.Type, path []string, opts Options) {
+	// The missing values of a []interface{} are nil.
+	var ty reflect.Type
+	for _, v := range values {
+		if v.IsValid() {
+			if ty == nil {
+				ty = v.Type()
+			} else if ty != v.Type() {
+				ty = interfaceType
+				break
+			}
+		}
+	}
+	if ty == nil {
+		var err error
+		if ty, err = typeOfPath(elemType, path, 0, opts); err != nil {
+			return
+		}
+	}
+
+	for i, v := range values {
+		if !v.IsValid() {
+			values[i] = reflect.Zero(ty)
+		}
+	}
 }
 
 func indexFunction(v reflect.Value) func(i int) reflect.Value {
@@ -183,48 +1000,72 @@ func indexFunction(v reflect.Value) func(i int) reflect.Value {
 	}
 }
 
-func mergeValue(values []reflect.Value) reflect.Value {
-	values = removeZeroValues(values)
-	l := len(values)
-	if l == 0 {
-		return reflect.Value{}
+// mergeAggregated merges the values of an aggregation, as set by opts.
+func mergeAggregated(values []reflect.Value, opts Options) reflect.Value {
+	value := mergeValue(values, opts.PreserveNesting)
+	if opts.DedupeAggregated {
+		value = dedupeValue(value)
 	}
+	return value
+}
 
-	sample := values[0]
-	mergeable := isMergeable(sample)
-
-	t := sample.Type()
-	if mergeable {
-		t = t.Elem()
+// mergeValue merges the valid values into a slice, concatenating them if
+// they're slices, unless nested is set. Whether they're concatenated follows
+// the first valid value, the others being appended as they are. If the merged
+// values don't all have the same type, e.g. after a JSON expansion, the slice
+// is a []interface{}. The invalid values are skipped in place, so values can
+// be a pooled buffer.
+func mergeValue(values []reflect.Value, nested bool) reflect.Value {
+	var sample reflect.Value
+	for _, v := range values {
+		if v.IsValid() {
+			sample = v
+			break
+		}
 	}
+	if !sample.IsValid() {
+		return reflect.Value{}
+	}
+
+	mergeable := isMergeable(sample) && !nested
 
-	value := reflect.MakeSlice(reflect.SliceOf(t), 0, 0)
-	for i := 0; i < l; i++ {
-		if !values[i].IsValid() {
+	// The result is allocated once, with the length of all the values.
+	var t reflect.Type
+	n := 0
+	for _, v := range values {
+		if !v.IsValid() {
 			continue
 		}
-
-		if mergeable {
-			value = reflect.AppendSlice(value, values[i])
+		ty := v.Type()
+		if mergeable && isMergeable(v) {
+			ty = ty.Elem()
+			n += v.Len()
 		} else {
-			value = reflect.Append(value, values[i])
+			n++
+		}
+		if t == nil {
+			t = ty
+		} else if t != ty {
+			t = interfaceType
 		}
 	}
 
-	return value
-}
-
-func removeZeroValues(values []reflect.Value) []reflect.Value {
-	l := len(values)
-
-	var v []reflect.Value
-	for i := 0; i < l; i++ {
-		if values[i].IsValid() {
-			v = append(v, values[i])
+	value := reflect.MakeSlice(reflect.SliceOf(t), 0, n)
+	for _, v := range values {
+		switch {
+		case !v.IsValid():
+		case !mergeable || !isMergeable(v):
+			value = reflect.Append(value, v)
+		case v.Type().Elem() == t:
+			value = reflect.AppendSlice(value, v)
+		default:
+			for i := 0; i < v.Len(); i++ {
+				value = reflect.Append(value, v.Index(i))
+			}
 		}
 	}
 
-	return v
+	return value
 }
 
 func isAggregable(v reflect.Value) bool {
@@ -233,68 +1074,288 @@ func isAggregable(v reflect.Value) bool {
 	return k == reflect.Map || k == reflect.Slice
 }
 
+// isMergeable tells whether the value v of an aggregation is concatenated
+// with the others. Maps aren't: their elements have no order.
 func isMergeable(v reflect.Value) bool {
-	k := v.Kind()
-	return k == reflect.Map || k == reflect.Slice
+	return v.Kind() == reflect.Slice
 }
 
+// parseIndex returns the key and the index of the path segment s, noIndex if
+// it has none. Only a trailing "[i]", "[*]" or key filter, e.g. `[~"app.*"]`,
+// is an index: the other brackets of a key must be escaped with a backslash,
+// e.g. `foo\[0\]`, or the key quoted as a Go string, e.g. `"foo[0]"` or
+// `"foo[0]"[1]`.
 func parseIndex(s string) (string, int, error) {
-	start := strings.Index(s, indexOpenChar)
-	end := strings.Index(s, indexCloseChar)
+	key, suffix := splitIndex(s)
+	if suffix == "" {
+		return key, noIndex, nil
+	}
+	index, err := parseIndexSuffix(s, suffix)
+	if err != nil {
+		return "", noIndex, err
+	}
+	return key, index, nil
+}
 
-	if start == -1 && end == -1 {
-		return s, -1, nil
+// splitIndex returns the key of the path segment s, unquoted or unescaped, and
+// the suffix of s starting with its first unescaped bracket, which must be its
+// index, if any.
+func splitIndex(s string) (string, string) {
+	if strings.HasPrefix(s, `"`) {
+		if quoted, err := strconv.QuotedPrefix(s); err == nil {
+			key, _ := strconv.Unquote(quoted)
+			return key, s[len(quoted):]
+		}
 	}
 
-	if (start != -1 && end == -1) || (start == -1 && end != -1) {
-		return "", -1, status.Errorf(codes.InvalidArgument, "invalid index %q", s)
+	if !strings.Contains(s, `\`) {
+		start := strings.IndexAny(s, indexOpenChar+indexCloseChar)
+		if start == -1 {
+			return s, ""
+		}
+		return s[:start], s[start:]
 	}
 
-	index, err := strconv.Atoi(s[start+1 : end])
-	if err != nil {
-		return "", -1, status.Errorf(codes.InvalidArgument, "invalid index %q", s)
+	var key strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s) && isEscapable(s[i+1]):
+			i++
+			key.WriteByte(s[i])
+		case c == '[' || c == ']':
+			return key.String(), s[i:]
+		default:
+			key.WriteByte(c)
+		}
+	}
+	return key.String(), ""
+}
+
+// parseIndexSuffix returns the index of the segment s, whose suffix starting
+// with its first unescaped bracket is suffix. The suffix must be the index.
+func parseIndexSuffix(s, suffix string) (int, error) {
+	if len(suffix) < 2 || !strings.HasPrefix(suffix, indexOpenChar) || !strings.HasSuffix(suffix, indexCloseChar) {
+		return noIndex, status.Errorf(codes.InvalidArgument, "invalid index %q", s)
+	}
+	inner := suffix[1 : len(suffix)-1]
+	if inner == wildcardChar {
+		return wildcardIndex, nil
 	}
+	if strings.HasPrefix(inner, keyFilterChar) {
+		if _, err := compileKeyFilter(inner[len(keyFilterChar):]); err != nil {
+			return noIndex, err
+		}
+		return filterIndex, nil
+	}
+	// The negative indexes would be taken for noIndex, wildcardIndex, etc.
+	index, err := strconv.Atoi(inner)
+	if err != nil || index < 0 {
+		return noIndex, status.Errorf(codes.InvalidArgument, "invalid index %q", s)
+	}
+	return index, nil
+}
+
+// isEscapable returns whether c is escaped by a backslash in path segments.
+func isEscapable(c byte) bool {
+	return c == '[' || c == ']' || c == '\\'
+}
 
-	return s[:start], index, nil
+// quoteKey returns key as a path segment, quoted if parseIndex wouldn't
+// return it as is.
+func quoteKey(key string) string {
+	if strings.ContainsAny(key, `[]\`) || strings.HasPrefix(key, `"`) {
+		return strconv.Quote(key)
+	}
+	return key
 }
 
-func lookupType(ty reflect.Type, path ...string) (reflect.Type, bool) {
-	if len(path) == 0 {
-		return ty, true
+func markSeen(seen map[reflect.Type]bool, ty reflect.Type) map[reflect.Type]bool {
+	if seen == nil {
+		seen = make(map[reflect.Type]bool)
 	}
+	seen[ty] = true
+	return seen
+}
 
-	switch ty.Kind() {
-	case reflect.Slice, reflect.Array, reflect.Map:
-		if strings.ContainsAny(path[0], indexOpenChar+indexCloseChar) {
-			return lookupType(ty.Elem(), path[1:]...)
+// structField returns the field of the struct type t addressed by key. The Go
+// field name is tried first, then the name from opts.TagKey, then both again
+// through the match functions, see matchName.
+func structField(t reflect.Type, key string, opts Options) (reflect.StructField, bool, error) {
+	fields := structFields(t, opts)
+	for _, f := range fields {
+		if f.Name == key {
+			return f, true, nil
 		}
-		// Aggregate.
-		return lookupType(ty.Elem(), path...)
-	case reflect.Ptr:
-		return lookupType(ty.Elem(), path...)
-	case reflect.Interface:
-		// We can't know from here without a value. Let's just return this type.
-		return ty, true
-	case reflect.Struct:
-		f, ok := ty.FieldByName(path[0])
-		if ok {
-			return lookupType(f.Type, path[1:]...)
+	}
+	if opts.NameCache != nil {
+		return opts.NameCache.structField(t, key, opts)
+	}
+	// We don't use FieldByNameFunc, since it returns zero value if the
+	// match func matches multiple fields.
+	i, err := matchName(len(fields), func(i int) []string {
+		if name, ok := tagName(fields[i], opts.TagKey); ok {
+			return []string{fields[i].Name, name}
 		}
+		return []string{fields[i].Name}
+	}, key, opts)
+	if i == -1 || err != nil {
+		return reflect.StructField{}, false, err
 	}
-	return nil, false
+	return fields[i], true, nil
 }
 
-// If the input value is expandable as JSON, returns a non-nil map.
-func expandStringAsJSON(v reflect.Value) map[string]interface{} {
-	if v.Kind() != reflect.String || !v.IsValid() || v.IsZero() {
-		return nil
+// structFields returns the fields addressable on the struct type t: its own
+// fields, including the embedded ones by their type name, followed by the
+// fields promoted from embedded structs, shallower ones first. As in Go, a
+// promoted field is hidden by a field with the same name at a shallower level.
+// Promotion is disabled by opts.NoPromotedFields. The Index of each returned
+// field is relative to t.
+func structFields(t reflect.Type, opts Options) []reflect.StructField {
+	type embedded struct {
+		typ   reflect.Type
+		index []int
 	}
-	jsonValue := make(map[string]interface{})
-	// Only returns the JSON instance when marshal succeeds.
-	if err := json.Unmarshal([]byte(v.String()), &jsonValue); err == nil {
-		return jsonValue
+
+	var fields []reflect.StructField
+	hidden := make(map[string]bool)
+	visited := make(map[reflect.Type]bool)
+	for level := []embedded{{typ: t}}; len(level) > 0; {
+		var next []embedded
+		names := make(map[string]bool)
+		for _, e := range level {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+
+			for i := 0; i < e.typ.NumField(); i++ {
+				f := e.typ.Field(i)
+				f.Index = append(append([]int{}, e.index...), i)
+				if !hidden[f.Name] {
+					fields = append(fields, f)
+					names[f.Name] = true
+				}
+
+				if !f.Anonymous || opts.NoPromotedFields {
+					continue
+				}
+				ft := f.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					next = append(next, embedded{typ: ft, index: f.Index})
+				}
+			}
+		}
+		for name := range names {
+			hidden[name] = true
+		}
+		level = next
 	}
-	return nil
+	return fields
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex but returns an invalid value
+// instead of panicking when going through a nil embedded pointer.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// tagName returns the name given to the field by the struct tag tagKey. It
+// returns false if tagKey is empty, the tag is missing, the field is skipped
+// with "-" or the tag doesn't specify a name (e.g. `json:",omitempty"`). The
+// name of the "protobuf" tag of generated messages is its name= option.
+func tagName(f reflect.StructField, tagKey string) (string, bool) {
+	if tagKey == "" {
+		return "", false
+	}
+	tag, ok := f.Tag.Lookup(tagKey)
+	if !ok {
+		return "", false
+	}
+	if tagKey == "protobuf" {
+		for _, opt := range strings.Split(tag, ",") {
+			if strings.HasPrefix(opt, "name=") {
+				return strings.TrimPrefix(opt, "name="), true
+			}
+		}
+		return "", false
+	}
+	if i := strings.Index(tag, ","); i != -1 {
+		tag = tag[:i]
+	}
+	if tag == "" || tag == "-" {
+		return "", false
+	}
+	return tag, true
+}
+
+// splitPath splits path into its segments.
+func splitPath(path string, opts *Options) []string {
+	if opts != nil && opts.NoSplit {
+		return []string{path}
+	}
+	token := getSplitToken(opts)
+	if !strings.Contains(path, indexOpenChar+keyFilterChar) {
+		return strings.Split(path, token)
+	}
+	var parts []string
+	for {
+		n := indexOfToken(path, token)
+		if n == -1 {
+			return append(parts, path)
+		}
+		parts, path = append(parts, path[:n]), path[n+len(token):]
+	}
+}
+
+// pathScanner returns the segments of a path one by one, as splitPath would,
+// without allocating them:
+//
+//	s := newPathScanner(path, &opts)
+//	for s.scan() {
+//		// Use s.part.
+//	}
+type pathScanner struct {
+	rest string
+	// The split token, empty if the path isn't split.
+	token string
+	done  bool
+	// The current segment.
+	part string
+}
+
+func newPathScanner(path string, opts *Options) pathScanner {
+	s := pathScanner{rest: path}
+	if opts == nil || !opts.NoSplit {
+		s.token = getSplitToken(opts)
+	}
+	return s
+}
+
+// scan advances to the next segment, and returns false when there is none.
+func (s *pathScanner) scan() bool {
+	if s.done {
+		return false
+	}
+	if s.token != "" {
+		if n := indexOfToken(s.rest, s.token); n != -1 {
+			s.part, s.rest = s.rest[:n], s.rest[n+len(s.token):]
+			return true
+		}
+	}
+	s.part, s.rest, s.done = s.rest, "", true
+	return true
 }
 
 func getSplitToken(opts *Options) string {
@@ -304,11 +1365,48 @@ func getSplitToken(opts *Options) string {
 	return defaultSplitToken
 }
 
-func compareWithMatchFunc(matchFuncs []MatchFunc, a, b string) bool {
-	for _, f := range matchFuncs {
-		if f(a) == f(b) {
-			return true
+// matchName returns the index of the candidate addressed by key, or -1 if
+// there is none. names returns the names of the i-th candidate. The precedence
+// is: an exact match first, then a match under each of opts.MatchFunctions in
+// their declared order, then the order of the candidates. If
+// opts.FailOnAmbiguousMatch is set and several candidates match under the
+// first matching function, an InvalidArgument error is returned.
+func matchName(n int, names func(i int) []string, key string, opts Options) (int, error) {
+	for i := 0; i < n; i++ {
+		for _, name := range names(i) {
+			if name == key {
+				return i, nil
+			}
 		}
 	}
-	return false
+
+	for _, f := range opts.MatchFunctions {
+		want := f(key)
+		found, ambiguous := -1, false
+		for i := 0; i < n; i++ {
+			for _, name := range names(i) {
+				if f(name) != want {
+					continue
+				}
+				if found == -1 {
+					found = i
+				} else if found != i {
+					if opts.FailOnAmbiguousMatch {
+						return -1, status.Errorf(codes.InvalidArgument, "key %q is ambiguous: matches both %q and %q", key, names(found)[0], names(i)[0])
+					}
+					logAmbiguousMatch(&opts, key, names(found)[0], names(i)[0])
+					ambiguous = true
+				}
+				break
+			}
+			// Look for another match to report it.
+			if found != -1 && !opts.FailOnAmbiguousMatch && (opts.Logger == nil || ambiguous) {
+				break
+			}
+		}
+		if found != -1 {
+			return found, nil
+		}
+	}
+	return -1, nil
 }
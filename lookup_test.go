@@ -1,8 +1,11 @@
 package lookup
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -57,6 +60,221 @@ func (s *S) TestLookup_MapNamed(c *C) {
 	c.Assert(value, Equals, 42)
 }
 
+func (s *S) TestLookup_MapTextUnmarshalerKey(c *C) {
+	fixture := map[MyID]string{{N: 1}: "foo", {N: 2}: "bar"}
+	value, err := Lookup(fixture, "id-2", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "bar")
+
+	_, err = Lookup(fixture, "id-3", Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	_, err = Lookup(fixture, "foo", Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+}
+
+type MyColor int
+
+func (c MyColor) String() string {
+	return [...]string{"red", "green", "blue"}[c]
+}
+
+func (s *S) TestLookup_MapStringerKey(c *C) {
+	fixture := map[MyColor]int{0: 1, 1: 2, 2: 3}
+	value, err := Lookup(fixture, "green", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 2)
+
+	value, err = Lookup(fixture, "BLUE", Options{MatchFunctions: []MatchFunc{strings.ToLower}})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 3)
+
+	_, err = Lookup(fixture, "yellow", Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+}
+
+func (s *S) TestLookup_MapNumericKey(c *C) {
+	for _, t := range []struct {
+		fixture interface{}
+		path    string
+		want    interface{}
+	}{
+		{map[int]string{-1: "a", 2: "b"}, "-1", "a"},
+		{map[uint8]string{2: "b"}, "2", "b"},
+		{map[float64]string{1.5: "c"}, "1.5", "c"},
+		{map[bool]string{true: "d"}, "true", "d"},
+		{map[MyColor]int{0: 1, 1: 2}, "1", 2},
+		{map[MyColor]int{0: 1, 1: 2}, "red", 1},
+		{map[int]map[int]string{1: {2: "e"}}, "1/2", "e"},
+	} {
+		// Not to split "1.5".
+		value, err := Lookup(t.fixture, t.path, WithSplitToken("/"))
+		c.Assert(err, IsNil, Commentf("%T %s", t.fixture, t.path))
+		c.Assert(value, Equals, t.want, Commentf("%T %s", t.fixture, t.path))
+	}
+
+	for _, t := range []struct {
+		fixture interface{}
+		path    string
+	}{
+		{map[int]string{1: "a"}, "foo"},
+		{map[uint8]string{1: "a"}, "300"},
+		{map[uint8]string{1: "a"}, "-1"},
+		{map[[2]int]string{{1, 2}: "a"}, "1"},
+		{map[struct{ A int }]string{{1}: "a"}, "A"},
+	} {
+		_, err := Lookup(t.fixture, t.path, CaseInsensitive())
+		c.Assert(status.Code(err), Equals, codes.NotFound, Commentf("%T %s", t.fixture, t.path))
+	}
+
+	// The numeric keys are matched by the MatchFunctions.
+	value, err := Lookup(map[int]string{10: "a"}, "1_0", WithMatchFunctions(func(s string) string {
+		return strings.ReplaceAll(s, "_", "")
+	}))
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "a")
+}
+
+func (s *S) TestLookup_MapInterfaceKey(c *C) {
+	// As decoded by yaml.v2.
+	fixture := map[interface{}]interface{}{
+		"name": "foo",
+		1:      "one",
+		true:   "yes",
+		"items": []interface{}{
+			map[interface{}]interface{}{"id": 1},
+			map[interface{}]interface{}{"id": 2},
+		},
+		"nested": map[interface{}]interface{}{2.5: "float"},
+	}
+	for path, want := range map[string]interface{}{
+		"name":        "foo",
+		"NAME":        "foo",
+		"1":           "one",
+		"true":        "yes",
+		"items[1].id": 2,
+		"items.id":    []int{1, 2},
+	} {
+		value, err := Lookup(fixture, path, CaseInsensitive())
+		c.Assert(err, IsNil, Commentf("%s", path))
+		c.Assert(value, DeepEquals, want, Commentf("%s", path))
+	}
+
+	value, err := Lookup(fixture, "nested/2.5", WithSplitToken("/"))
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "float")
+
+	_, err = Lookup(fixture, "2")
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+}
+
+func (s *S) TestLookupValue(c *C) {
+	fixture := MyStruct{Nested: &MyStruct{}, StructSlice: []*MyStruct{{String: "foo"}}}
+
+	value, err := LookupValue(&fixture, "Nested.String", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value.CanSet(), Equals, true)
+	value.SetString("bar")
+	c.Assert(fixture.Nested.String, Equals, "bar")
+
+	value, err = LookupValue(fixture, "StructSlice", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value.Type(), Equals, reflect.TypeOf([]*MyStruct{}))
+	c.Assert(value.Index(0).Elem().Field(0).CanSet(), Equals, true)
+
+	value, err = LookupValue(fixture, "Interface", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value.IsValid(), Equals, false)
+
+	// Through a pointer root, the index isn't ignored as by Lookup.
+	list := MyStruct{StructSlice: []*MyStruct{{String: "foo"}, {String: "bar"}}}
+	value, err = LookupValue(&list, "StructSlice[1]")
+	c.Assert(err, IsNil)
+	c.Assert(value.Type(), Equals, reflect.TypeOf(MyStruct{}))
+	c.Assert(value.CanSet(), Equals, true)
+	value.Field(0).SetString("baz")
+	c.Assert(list.StructSlice[0].String, Equals, "foo")
+	c.Assert(list.StructSlice[1].String, Equals, "baz")
+	compat, err := Lookup(&list, "StructSlice[1]")
+	c.Assert(err, IsNil)
+	c.Assert(compat, HasLen, 2)
+}
+
+func (s *S) TestExists(c *C) {
+	for _, path := range []string{"String", "Map.foo", "StructSlice.String", "StructSlice[*].StructSlice.String", "Interface"} {
+		c.Assert(Exists(structFixture, path, Options{}), Equals, true, Commentf("path %q", path))
+	}
+	for _, path := range []string{"Missing", "Map.bar", "StructSlice.Missing", "Nested", "StructSlice[5]"} {
+		c.Assert(Exists(structFixture, path, Options{}), Equals, false, Commentf("path %q", path))
+	}
+	c.Assert(Exists(structFixture, "StructSlice.Map.bar", Options{KeepMapKeys: true}), Equals, false)
+
+	// The aggregation stops at the first value found.
+	looked := 0
+	counting := ExpanderFunc(func(v interface{}, opts Options) (interface{}, bool, error) {
+		if s, ok := v.(string); ok && s != "" {
+			looked++
+			return map[string]interface{}{"x": s}, true, nil
+		}
+		return nil, false, nil
+	})
+	items := []interface{}{"a", "b", "c"}
+	c.Assert(Exists(items, "x", Options{Expanders: []Expander{counting}}), Equals, true)
+	c.Assert(looked, Equals, 1)
+}
+
+func (s *S) TestCount(c *C) {
+	tests := map[string]int{
+		"String":                         1,
+		"Map":                            1,
+		"StructSlice":                    2,
+		"StructSlice.String":             2,
+		"StructSlice.StructSlice":        4,
+		"StructSlice.StructSlice.String": 4,
+		"StructSlice[0].StructSlice":     2,
+		"Nested":                         0,
+	}
+	for path, want := range tests {
+		n, err := Count(structFixture, path, Options{})
+		c.Assert(err, IsNil, Commentf("path %q", path))
+		c.Assert(n, Equals, want, Commentf("path %q", path))
+	}
+
+	byName := map[string]*MyStruct{"a": structFixture.StructSlice[0], "b": structFixture.StructSlice[1]}
+	n, err := Count(byName, "StructSlice", Options{KeepMapKeys: true})
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 2)
+
+	n, err = Count(byName, "StructSlice", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 4)
+
+	_, err = Count(structFixture, "Missing", Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	// Count is the length of the result of Lookup.
+	for _, test := range []struct {
+		i       interface{}
+		path    string
+		options []Option
+		want    int
+	}{
+		{[]map[string]int{{"a": 1}, {"b": 2, "c": 3}}, "a", []Option{PreservePositions()}, 2},
+		{[]map[string]interface{}{{"a": 1}, {"b": "x"}}, "a", []Option{PreservePositions()}, 2},
+		{[]map[string]int{{"b": 2}}, "a", []Option{PreservePositions()}, 1},
+		{MyStruct{StructSlice: []*MyStruct{{StructSlice: []*MyStruct{}}, {StructSlice: []*MyStruct{{String: "x"}}}}}, "StructSlice.StructSlice.String", nil, 1},
+		{MyStruct{StructSlice: []*MyStruct{{StructSlice: []*MyStruct{{String: "x"}}}, {StructSlice: []*MyStruct{}}}}, "StructSlice.StructSlice.String", nil, 1},
+		{MyStruct{StructSlice: []*MyStruct{{StructSlice: []*MyStruct{}}, {StructSlice: []*MyStruct{{String: "x"}}}}}, "StructSlice.StructSlice.String", []Option{PreserveNesting()}, 2},
+	} {
+		n, err := Count(test.i, test.path, test.options...)
+		c.Assert(err, IsNil)
+		c.Assert(n, Equals, test.want, Commentf("%v", test.i))
+		value, err := Lookup(test.i, test.path, test.options...)
+		c.Assert(err, IsNil)
+		c.Assert(reflect.ValueOf(value).Len(), Equals, test.want, Commentf("%v", test.i))
+	}
+}
+
 func (s *S) TestLookup_NotFound(c *C) {
 	_, err := Lookup(structFixture, "qux", Options{})
 	c.Assert(status.Code(err), Equals, codes.NotFound)
@@ -65,6 +283,91 @@ func (s *S) TestLookup_NotFound(c *C) {
 	c.Assert(status.Code(err), Equals, codes.NotFound)
 }
 
+func (s *S) TestLookup_MaxDepth(c *C) {
+	value, err := Lookup(structFixture, "Map.foo", Options{MaxDepth: 2})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 42)
+
+	_, err = Lookup(structFixture, "Map.foo", Options{MaxDepth: 1})
+	c.Assert(status.Code(err), Equals, codes.ResourceExhausted)
+
+	// Each aggregation counts as a step.
+	value, err = Lookup(structFixture, "StructSlice.StructSlice.String", Options{MaxDepth: 5})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []string{"bar", "foo", "qux", "baz"})
+
+	_, err = Lookup(structFixture, "StructSlice.StructSlice.String", Options{MaxDepth: 4})
+	c.Assert(status.Code(err), Equals, codes.ResourceExhausted)
+}
+
+func (s *S) TestLookup_Embedded(c *C) {
+	type Base struct {
+		ID   string
+		Name string
+	}
+	type Middle struct {
+		*Base
+		Name string
+	}
+	type Top struct {
+		Middle
+		Extra int
+	}
+	fixture := Top{Middle: Middle{Base: &Base{ID: "foo", Name: "bar"}, Name: "qux"}, Extra: 42}
+
+	value, err := Lookup(fixture, "id", Options{MatchFunctions: []MatchFunc{strings.ToLower}})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "foo")
+
+	value, err = Lookup(fixture, "Name", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "qux")
+
+	value, err = Lookup(fixture, "Middle.Base.Name", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "bar")
+
+	_, err = Lookup(fixture, "ID", Options{NoPromotedFields: true})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	value, err = Lookup(fixture, "Middle.Base.ID", Options{NoPromotedFields: true})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "foo")
+
+	_, err = Lookup(Top{}, "ID", Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+}
+
+func (s *S) TestLookupContext(c *C) {
+	value, err := LookupContext(context.Background(), structFixture, "StructSlice.String", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []string{"foo", "qux"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = LookupContext(ctx, structFixture, "StructSlice.String", Options{})
+	c.Assert(status.Code(err), Equals, codes.Canceled)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	_, err = LookupContext(ctx, structFixture, "String", Options{})
+	c.Assert(status.Code(err), Equals, codes.DeadlineExceeded)
+}
+
+func (s *S) TestLookup_Cycle(c *C) {
+	type List []interface{}
+	cyclic := List{nil, map[string]int{"foo": 42}}
+	cyclic[0] = cyclic
+
+	value, err := Lookup(cyclic, "foo", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []int{42})
+
+	type Recursive []Recursive
+	_, err = Lookup(Recursive{}, "foo", Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+}
+
 func (s *S) TestAggregableLookup_StructIndex(c *C) {
 	value, err := Lookup(structFixture, "StructSlice.Map.foo", Options{})
 
@@ -104,6 +407,72 @@ func (s *S) TestAggregableLookupString_Complex(c *C) {
 	c.Assert(value, DeepEquals, []int{1, 2, 3})
 }
 
+func (s *S) TestAggregableLookup_Wildcard(c *C) {
+	value, err := Lookup(structFixture, "StructSlice[*].StructSlice[*].String", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []string{"bar", "foo", "qux", "baz"})
+
+	value, err = Lookup(mapComplexFixture, "list[*].baz", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []int{1, 2, 3})
+
+	value, err = Lookup(MyStruct{}, "StructSlice[*].String", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []string{})
+
+	_, err = Lookup(structFixture, "String[*]", Options{})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+}
+
+func (s *S) TestAggregableLookup_Strict(c *C) {
+	_, err := Lookup(structFixture, "StructSlice.String", Options{Strict: true})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	value, err := Lookup(structFixture, "StructSlice[*].String", Options{Strict: true})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []string{"foo", "qux"})
+
+	value, err = Lookup(structFixture, "StructSlice[1].Map.foo", Options{Strict: true})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 42)
+}
+
+func (s *S) TestAggregableLookup_PreservePositions(c *C) {
+	fixture := []*MyStruct{
+		{Nested: &MyStruct{String: "foo"}},
+		{},
+		{Nested: &MyStruct{String: "bar"}},
+	}
+	_, err := Lookup(fixture, "Nested.String", Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	value, err := Lookup(fixture, "Nested.String", Options{PreservePositions: true})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []string{"foo", "", "bar"})
+
+	value, err = Lookup(fixture[1:2], "Nested.String", Options{PreservePositions: true})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []string{""})
+}
+
+func (s *S) TestAggregableLookup_KeepMapKeys(c *C) {
+	fixture := map[string]*MyStruct{
+		"foo": {String: "bar"},
+		"qux": {String: "baz"},
+	}
+	value, err := Lookup(fixture, "String", Options{KeepMapKeys: true})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, map[string]string{"foo": "bar", "qux": "baz"})
+
+	value, err = Lookup(map[string]*MyStruct{}, "String", Options{KeepMapKeys: true})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, map[string]string{})
+
+	value, err = Lookup(map[string]interface{}{"foo": map[string]interface{}{"a": 1}, "bar": map[string]interface{}{"a": "b"}}, "a", Options{KeepMapKeys: true})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, map[string]interface{}{"foo": 1, "bar": "b"})
+}
+
 func (s *S) TestAggregableLookup_EmptySlice(c *C) {
 	fixture := [][]MyStruct{{}}
 	value, err := Lookup(fixture, "String", Options{})
@@ -118,8 +487,45 @@ func (s *S) TestAggregableLookup_EmptyMap(c *C) {
 	c.Assert(value.([]map[string]int), DeepEquals, []map[string]int{})
 }
 
+func (s *S) TestLookup_PreserveNesting(c *C) {
+	value, err := Lookup(structFixture, "StructSlice.StructSlice.String", PreserveNesting())
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, [][]string{{"bar", "foo"}, {"qux", "baz"}})
+	value, err = Lookup(structFixture, "StructSlice[*].StructSlice[*].String", PreserveNesting())
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, [][]string{{"bar", "foo"}, {"qux", "baz"}})
+
+	value, err = Lookup(MyStruct{}, "StructSlice.StructSlice.String", PreserveNesting())
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, [][]string{})
+
+	// The values which aren't nested are still merged.
+	value, err = Lookup(structFixture, "StructSlice.String", PreserveNesting())
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []string{"foo", "qux"})
+
+	n, err := Count(structFixture, "StructSlice.StructSlice.String", PreserveNesting())
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 2)
+	values, err := LookupMany(structFixture, []string{"StructSlice.StructSlice.String"}, PreserveNesting())
+	c.Assert(err, IsNil)
+	c.Assert(values["StructSlice.StructSlice.String"], DeepEquals, [][]string{{"bar", "foo"}, {"qux", "baz"}})
+	ty, err := TypeOf(reflect.TypeOf(structFixture), "StructSlice.StructSlice.String", PreserveNesting())
+	c.Assert(err, IsNil)
+	c.Assert(ty, Equals, reflect.TypeOf([][]string{}))
+
+	it, err := Iterate(structFixture, "StructSlice.StructSlice.String", PreserveNesting())
+	c.Assert(err, IsNil)
+	var groups []interface{}
+	for it.Next() {
+		groups = append(groups, it.Value())
+	}
+	c.Assert(it.Err(), IsNil)
+	c.Assert(groups, DeepEquals, []interface{}{[]string{"bar", "foo"}, []string{"qux", "baz"}})
+}
+
 func (s *S) TestMergeValue(c *C) {
-	v := mergeValue([]reflect.Value{reflect.ValueOf("qux"), reflect.ValueOf("foo")})
+	v := mergeValue([]reflect.Value{reflect.ValueOf("qux"), reflect.ValueOf("foo")}, false)
 	c.Assert(v.Interface(), DeepEquals, []string{"qux", "foo"})
 }
 
@@ -127,16 +533,98 @@ func (s *S) TestMergeValueSlice(c *C) {
 	v := mergeValue([]reflect.Value{
 		reflect.ValueOf([]string{"foo", "bar"}),
 		reflect.ValueOf([]string{"qux", "baz"}),
-	})
+	}, false)
 
 	c.Assert(v.Interface(), DeepEquals, []string{"foo", "bar", "qux", "baz"})
+	c.Assert(v.Cap(), Equals, 4)
+
+	v = mergeValue([]reflect.Value{
+		reflect.ValueOf([]string{"foo", "bar"}),
+		reflect.ValueOf([]string{"qux"}),
+	}, true)
+	c.Assert(v.Interface(), DeepEquals, [][]string{{"foo", "bar"}, {"qux"}})
+}
+
+func (s *S) TestMergeValueMixed(c *C) {
+	v := mergeValue([]reflect.Value{reflect.ValueOf("foo"), reflect.ValueOf(42), reflect.Value{}}, false)
+	c.Assert(v.Interface(), DeepEquals, []interface{}{"foo", 42})
+
+	v = mergeValue([]reflect.Value{reflect.ValueOf([]string{"foo"}), reflect.ValueOf([]int{42, 43})}, false)
+	c.Assert(v.Interface(), DeepEquals, []interface{}{"foo", 42, 43})
+	c.Assert(v.Cap(), Equals, 3)
+
+	v = mergeValue([]reflect.Value{reflect.ValueOf([]string{"foo"}), reflect.ValueOf("bar")}, false)
+	c.Assert(v.Interface(), DeepEquals, []string{"foo", "bar"})
+
+	v = mergeValue([]reflect.Value{reflect.ValueOf("bar"), reflect.ValueOf([]string{"foo"})}, false)
+	c.Assert(v.Interface(), DeepEquals, []interface{}{"bar", []string{"foo"}})
+
+	v = mergeValue([]reflect.Value{reflect.ValueOf(mapFixture), reflect.ValueOf(mapFixture)}, false)
+	c.Assert(v.Interface(), DeepEquals, []map[string]int{mapFixture, mapFixture})
+}
+
+func (s *S) TestAggregableLookup_Heterogeneous(c *C) {
+	fixture := map[string]interface{}{"items": []interface{}{
+		map[string]interface{}{"value": "foo", "tags": []interface{}{"a"}},
+		map[string]interface{}{"value": 42.0, "tags": []string{"b", "c"}},
+		map[string]interface{}{"value": map[string]interface{}{"bar": true}, "tags": "d"},
+	}}
+
+	value, err := Lookup(fixture, "items.value")
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []interface{}{"foo", 42.0, map[string]interface{}{"bar": true}})
+	value, err = Lookup(fixture, "items.tags")
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []interface{}{"a", "b", "c", "d"})
+	n, err := Count(fixture, "items.tags")
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 4)
+
+	value, err = Lookup(structFixture, "StructSlice.Map")
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []map[string]int{mapFixture, mapFixture})
+	n, err = Count(structFixture, "StructSlice.Map")
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 2)
+
+	value, err = Lookup(map[string][]interface{}{"list": {"foo", nil, 42}}, "list[*]", PreservePositions())
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []interface{}{"foo", nil, 42})
 }
 
 func (s *S) TestMergeValueZero(c *C) {
-	v := mergeValue([]reflect.Value{reflect.Value{}, reflect.ValueOf("foo")})
+	v := mergeValue([]reflect.Value{reflect.Value{}, reflect.ValueOf("foo")}, false)
 	c.Assert(v.Interface(), DeepEquals, []string{"foo"})
 }
 
+func (s *S) TestPathScanner(c *C) {
+	for _, test := range []struct {
+		path string
+		opts Options
+	}{
+		{"", Options{}},
+		{"foo", Options{}},
+		{"foo.bar[1].baz", Options{}},
+		{"foo..bar.", Options{}},
+		{"foo->bar->baz", Options{SplitToken: "->"}},
+		{"foo.bar", Options{NoSplit: true}},
+	} {
+		var parts []string
+		scanner := newPathScanner(test.path, &test.opts)
+		for scanner.scan() {
+			parts = append(parts, scanner.part)
+		}
+		c.Assert(parts, DeepEquals, splitPath(test.path, &test.opts), Commentf("path %q", test.path))
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		scanner := newPathScanner("foo.bar[1].baz", nil)
+		for scanner.scan() {
+		}
+	})
+	c.Assert(allocs, Equals, 0.0)
+}
+
 func (s *S) TestParseIndex(c *C) {
 	key, index, err := parseIndex("foo[42]")
 	c.Assert(err, IsNil)
@@ -166,6 +654,94 @@ func (s *S) TestParseIndexMalFormed(c *C) {
 	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
 	c.Assert(key, Equals, "")
 	c.Assert(index, Equals, -1)
+
+	for _, s := range []string{"foo[-1]", "foo[-2]", "foo[-4]"} {
+		key, index, err = parseIndex(s)
+		c.Assert(status.Code(err), Equals, codes.InvalidArgument, Commentf("%s", s))
+		c.Assert(key, Equals, "")
+		c.Assert(index, Equals, -1)
+	}
+}
+
+func (s *S) TestLookup_NegativeIndex(c *C) {
+	for _, path := range []string{"StructSlice[-1]", "StructSlice[-2]", "StructSlice[-4].String"} {
+		_, err := Lookup(structFixture, path)
+		c.Assert(status.Code(err), Equals, codes.InvalidArgument, Commentf("%s", path))
+		_, err = Count(structFixture, path)
+		c.Assert(status.Code(err), Equals, codes.InvalidArgument, Commentf("%s", path))
+		_, err = LookupJSONBytes([]byte(`{"StructSlice": [{"String": "foo"}]}`), path)
+		c.Assert(status.Code(err), Equals, codes.InvalidArgument, Commentf("%s", path))
+	}
+
+	// Not an index of the path, so it can't be parsed as one.
+	t := newTraversal(context.Background(), Options{})
+	_, _, err := t.getSegment(reflect.ValueOf(structFixture), "StructSlice", -4, nil, []string{"StructSlice"})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+	_, ok := fastSegment(reflect.ValueOf(map[string][]int{"foo": {1}}), "foo", -4)
+	c.Assert(ok, Equals, false)
+}
+
+func (s *S) TestParseIndexBrackets(c *C) {
+	for _, t := range []struct {
+		segment string
+		key     string
+		index   int
+	}{
+		{`foo\[0\]`, "foo[0]", noIndex},
+		{`foo\[0\][1]`, "foo[0]", 1},
+		{`foo\\[*]`, `foo\`, wildcardIndex},
+		{`foo\bar`, `foo\bar`, noIndex},
+		{`"foo[0]"`, "foo[0]", noIndex},
+		{`"foo[0]"[2]`, "foo[0]", 2},
+		{`"foo\"]"`, `foo"]`, noIndex},
+		{`"foo`, `"foo`, noIndex},
+	} {
+		key, index, err := parseIndex(t.segment)
+		c.Assert(err, IsNil, Commentf("%s", t.segment))
+		c.Assert(key, Equals, t.key, Commentf("%s", t.segment))
+		c.Assert(index, Equals, t.index, Commentf("%s", t.segment))
+	}
+
+	// Only a trailing index is an index.
+	for _, segment := range []string{"foo[0]bar", "foo[0][1]", `"foo"bar`, `"foo"[x]`, `foo\[0]`} {
+		_, _, err := parseIndex(segment)
+		c.Assert(status.Code(err), Equals, codes.InvalidArgument, Commentf("%s", segment))
+	}
+}
+
+func (s *S) TestLookup_BracketKeys(c *C) {
+	i := map[string]interface{}{
+		"foo[0]": "bracket",
+		"foo":    []interface{}{"zero"},
+		"list[]": []interface{}{"a", "b"},
+	}
+	for path, want := range map[string]interface{}{
+		`foo[0]`:      "zero",
+		`foo\[0\]`:    "bracket",
+		`"foo[0]"`:    "bracket",
+		`"list[]"[1]`: "b",
+		`list\[\][0]`: "a",
+	} {
+		value, err := Lookup(i, path)
+		c.Assert(err, IsNil, Commentf("%s", path))
+		c.Assert(value, Equals, want, Commentf("%s", path))
+	}
+
+	// The paths found quote the keys with brackets, so they can be looked up.
+	i = map[string]interface{}{
+		"m": map[string]interface{}{
+			"k[0]": map[string]interface{}{"x": 1},
+			"k":    map[string]interface{}{"x": 2},
+		},
+	}
+	matches, err := LookupAll(i, "m.x")
+	c.Assert(err, IsNil)
+	c.Assert(matches, DeepEquals, []PathMatch{{Path: `m."k[0]".x`, Value: 1}, {Path: "m.k.x", Value: 2}})
+	for _, m := range matches {
+		value, err := Lookup(i, m.Path)
+		c.Assert(err, IsNil)
+		c.Assert(value, Equals, m.Value)
+	}
 }
 
 func (s *S) TestLookup_CaseSensitive(c *C) {
@@ -199,6 +775,41 @@ func (s *S) TestLookup_CaseInsensitive_FirstMatch(c *C) {
 	c.Assert(value, Equals, 1)
 }
 
+func (s *S) TestLookup_MatchFunctionsOrder(c *C) {
+	value, err := Lookup(caseFixtureStruct, "test_field", Options{MatchFunctions: []MatchFunc{
+		strcase.ToSnake,
+		strings.ToLower,
+	}})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 1)
+
+	value, err = Lookup(caseFixtureMap, "testkey", Options{MatchFunctions: []MatchFunc{
+		strings.ToLower,
+	}})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 1)
+}
+
+func (s *S) TestLookup_FailOnAmbiguousMatch(c *C) {
+	opts := Options{
+		MatchFunctions:       []MatchFunc{strings.ToLower},
+		FailOnAmbiguousMatch: true,
+	}
+	_, err := Lookup(caseFixtureStruct, "testfield", opts)
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+
+	_, err = Lookup(caseFixtureMap, "testkey", opts)
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+
+	value, err := Lookup(caseFixtureStruct, "Testfield", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 2)
+
+	value, err = Lookup(caseFixtureStruct, "foo", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 0)
+}
+
 func (s *S) TestLookup_CaseInsensitiveExactMatch(c *C) {
 	value, err := Lookup(structFixture, "STring", Options{MatchFunctions: []MatchFunc{
 		strings.ToLower,
@@ -247,6 +858,46 @@ func (s *S) TestLookup_ListPtr(c *C) {
 	c.Assert(value, Equals, "first")
 }
 
+func (s *S) TestLookup_SplitToken(c *C) {
+	value, err := Lookup(structFixture, "Map->foo", Options{SplitToken: "->"})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 42)
+
+	value, err = Lookup(map[string]int{"foo.bar": 42}, "foo.bar", Options{NoSplit: true})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 42)
+
+	_, err = Lookup(map[string]int{"foo.bar": 42}, "foo.bar", Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+}
+
+func (s *S) TestLookup_TagKey(c *C) {
+	type Tagged struct {
+		Name     string `yaml:"name,omitempty"`
+		Count    int    `yaml:",omitempty"`
+		Internal string `yaml:"-"`
+	}
+	fixture := Tagged{Name: "foo", Count: 42, Internal: "bar"}
+
+	value, err := Lookup(fixture, "name", Options{TagKey: "yaml"})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "foo")
+
+	value, err = Lookup(fixture, "Count", Options{TagKey: "yaml"})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 42)
+
+	_, err = Lookup(fixture, "-", Options{TagKey: "yaml"})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	_, err = Lookup(fixture, "name", Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	value, err = Lookup([]Tagged{}, "name", Options{TagKey: "yaml"})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []string{})
+}
+
 func TestLookup(t *testing.T) {
 	testCases := []struct {
 		desc    string
@@ -331,6 +982,51 @@ func TestLookup(t *testing.T) {
 			},
 			want: float64(2),
 		},
+		{
+			desc:  "Expanded String - Top-level Array",
+			input: structFixture,
+			path:  "JSONList[2].foo",
+			opts: Options{
+				ExpandStringAsJSON: true,
+			},
+			want: "bar",
+		},
+		{
+			desc:  "Expanded String - Top-level Array - Aggregation",
+			input: map[string]string{"foo": `[{"bar": 1}, {"bar": 2}]`},
+			path:  "foo.bar",
+			opts: Options{
+				ExpandStringAsJSON: true,
+			},
+			want: []float64{1, 2},
+		},
+		{
+			desc:  "Expanded Bytes",
+			input: map[string]interface{}{"foo": []byte(`{"bar": "baz"}`)},
+			path:  "foo.bar",
+			opts: Options{
+				ExpandStringAsJSON: true,
+			},
+			want: "baz",
+		},
+		{
+			desc:  "Expanded RawMessage",
+			input: struct{ Raw json.RawMessage }{Raw: json.RawMessage(`{"bar": [1, 2]}`)},
+			path:  "Raw.bar[1]",
+			opts: Options{
+				ExpandStringAsJSON: true,
+			},
+			want: float64(2),
+		},
+		{
+			desc:  "Expanded String - Double Encoded",
+			input: map[string]string{"foo": `"{\"bar\": \"baz\"}"`},
+			path:  "foo.bar",
+			opts: Options{
+				ExpandStringAsJSON: true,
+			},
+			want: "baz",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -409,9 +1105,7 @@ func ExampleCaseInsensitive() {
 		SoftwareUpdated: true,
 	}
 
-	value, _ := Lookup(i, "softwareupdated", Options{MatchFunctions: []MatchFunc{
-		strings.ToLower,
-	}})
+	value, _ := Lookup(i, "softwareupdated", CaseInsensitive())
 	fmt.Println(value)
 	// Output: true
 }
@@ -423,10 +1117,24 @@ type MyStruct struct {
 	StructSlice []*MyStruct
 	Interface   interface{}
 	JSONString  string
+	JSONList    string
 }
 
 type MyKey string
 
+type MyID struct {
+	N int
+}
+
+func (id *MyID) UnmarshalText(text []byte) error {
+	n, err := strconv.Atoi(strings.TrimPrefix(string(text), "id-"))
+	if err != nil {
+		return err
+	}
+	id.N = n
+	return nil
+}
+
 var mapFixtureNamed = map[MyKey]int{"foo": 42}
 var mapFixture = map[string]int{"foo": 42}
 var structFixture = MyStruct{
@@ -437,6 +1145,7 @@ var structFixture = MyStruct{
 		{Map: mapFixture, String: "foo", StructSlice: []*MyStruct{{String: "bar"}, {String: "foo"}}},
 		{Map: mapFixture, String: "qux", StructSlice: []*MyStruct{{String: "qux"}, {String: "baz"}}},
 	},
+	JSONList: `[1, 2, {"foo": "bar"}]`,
 	JSONString: `
 	{
 		"String": "Abc",
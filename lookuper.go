@@ -0,0 +1,101 @@
+package lookup
+
+import (
+	"context"
+	"reflect"
+)
+
+// Lookuper performs lookups with the options it's created with, so they're
+// configured in one place. Unless its options set one, a Lookuper matching
+// names with MatchFunctions has its own NameCache, shared by all its lookups.
+// It's safe for concurrent use.
+type Lookuper struct {
+	// The options the Lookuper was created with, from which With derives.
+	base Options
+	opts Options
+}
+
+// NewLookuper returns a Lookuper performing its lookups with options.
+func NewLookuper(options ...Option) *Lookuper {
+	base := NewOptions(options...)
+	opts := base
+	if opts.NameCache == nil && len(opts.MatchFunctions) > 0 {
+		opts.NameCache = NewNameCache()
+	}
+	return &Lookuper{base: base, opts: opts}
+}
+
+// With returns a Lookuper with the options of l, updated by options.
+func (l *Lookuper) With(options ...Option) *Lookuper {
+	return NewLookuper(append([]Option{l.base}, options...)...)
+}
+
+// Options returns the options of the lookups of l.
+func (l *Lookuper) Options() Options {
+	return l.opts
+}
+
+// Lookup is like the Lookup function, with the options of l.
+func (l *Lookuper) Lookup(i interface{}, path string) (interface{}, error) {
+	return LookupContext(context.Background(), i, path, l.opts)
+}
+
+// LookupContext is like the LookupContext function, with the options of l.
+func (l *Lookuper) LookupContext(ctx context.Context, i interface{}, path string) (interface{}, error) {
+	return LookupContext(ctx, i, path, l.opts)
+}
+
+// LookupValue is like the LookupValue function, with the options of l.
+func (l *Lookuper) LookupValue(i interface{}, path string) (reflect.Value, error) {
+	return LookupValue(i, path, l.opts)
+}
+
+// Exists is like the Exists function, with the options of l.
+func (l *Lookuper) Exists(i interface{}, path string) bool {
+	return Exists(i, path, l.opts)
+}
+
+// Status is like the Status function, with the options of l.
+func (l *Lookuper) Status(i interface{}, path string) (PathStatus, error) {
+	return Status(i, path, l.opts)
+}
+
+// Count is like the Count function, with the options of l.
+func (l *Lookuper) Count(i interface{}, path string) (int, error) {
+	return Count(i, path, l.opts)
+}
+
+// LookupSetter is like the LookupSetter function, with the options of l.
+func (l *Lookuper) LookupSetter(i interface{}, path string) (func(value interface{}) error, error) {
+	return LookupSetter(i, path, l.opts)
+}
+
+// LookupAll is like the LookupAll function, with the options of l.
+func (l *Lookuper) LookupAll(i interface{}, path string) ([]PathMatch, error) {
+	return LookupAll(i, path, l.opts)
+}
+
+// LookupMany is like the LookupMany function, with the options of l.
+func (l *Lookuper) LookupMany(i interface{}, paths []string) (map[string]interface{}, error) {
+	return LookupMany(i, paths, l.opts)
+}
+
+// Get is like the Get function, with the options of l.
+func (l *Lookuper) Get(i interface{}, path string) LookupResult {
+	return Get(i, path, l.opts)
+}
+
+// Iterate is like the Iterate function, with the options of l.
+func (l *Lookuper) Iterate(i interface{}, path string) (*Iterator, error) {
+	return Iterate(i, path, l.opts)
+}
+
+// Walk is like the Walk function, with the options of l.
+func (l *Lookuper) Walk(i interface{}, fn WalkFunc) error {
+	return Walk(i, fn, l.opts)
+}
+
+// Compile is like the Compile function, with the options of l.
+func (l *Lookuper) Compile(path string) (*CompiledPath, error) {
+	return Compile(path, l.opts)
+}
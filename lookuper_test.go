@@ -0,0 +1,58 @@
+package lookup
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestLookuper(c *C) {
+	l := NewLookuper(CaseInsensitive())
+	c.Assert(l.Options().NameCache, NotNil)
+
+	value, err := l.Lookup(structFixture, "string")
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "foo")
+	c.Assert(l.Exists(structFixture, "map.foo"), Equals, true)
+
+	n, err := l.Count(structFixture, "structslice.string")
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 2)
+
+	p, err := l.Compile("nested")
+	c.Assert(err, IsNil)
+	value, err = p.Lookup(structFixture)
+	c.Assert(err, IsNil)
+	c.Assert(value, IsNil)
+
+	c.Assert(l.Get(structFixture, "STRING").String(), Equals, "foo")
+
+	values, err := l.LookupMany(structFixture, []string{"string", "map.foo"})
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, map[string]interface{}{"string": "foo", "map.foo": 42})
+}
+
+func (s *S) TestLookuper_With(c *C) {
+	l := NewLookuper(WithSplitToken("/"))
+	c.Assert(l.Options().NameCache, IsNil)
+
+	value, err := l.Lookup(structFixture, "Map/foo")
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 42)
+
+	// The derived Lookuper has its own NameCache, for its own MatchFunctions.
+	upper := l.With(WithMatchFunctions(strings.ToUpper))
+	lower := l.With(CaseInsensitive())
+	c.Assert(upper.Options().NameCache, Not(Equals), lower.Options().NameCache)
+	c.Assert(upper.Options().SplitToken, Equals, "/")
+
+	value, err = lower.Lookup(structFixture, "map/FOO")
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 42)
+	_, err = l.Lookup(structFixture, "map/foo")
+	c.Assert(err, NotNil)
+
+	cache := NewNameCache()
+	l = NewLookuper(Options{MatchFunctions: []MatchFunc{strings.ToLower}, NameCache: cache})
+	c.Assert(l.Options().NameCache, Equals, cache)
+}
@@ -0,0 +1,391 @@
+package lookup
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PathErrors holds the errors of the paths which failed in a LookupMany,
+// keyed by path.
+type PathErrors map[string]error
+
+func (e PathErrors) Error() string {
+	paths := make([]string, 0, len(e))
+	for path := range e {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	msgs := make([]string, len(paths))
+	for i, path := range paths {
+		msgs[i] = fmt.Sprintf("%s: %s", path, e[path])
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// LookupMany performs the lookups of paths on i, as Lookup does, in a single
+// traversal: the paths are gathered in a trie, so the segments shared by
+// several paths, the expansions of their values and the aggregations they go
+// through are resolved once. It returns the values of the paths which
+// succeeded, keyed by path, and a PathErrors holding the errors of the others,
+// if any.
+func LookupMany(i interface{}, paths []string, options ...Option) (map[string]interface{}, error) {
+	opts := NewOptions(options...)
+	b := &batch{
+		t:      newTraversal(context.Background(), opts),
+		paths:  make([][]string, len(paths)),
+		values: make([]reflect.Value, len(paths)),
+		errs:   make([]error, len(paths)),
+	}
+	for i, path := range paths {
+		b.paths[i] = splitPath(path, &opts)
+	}
+	b.node(reflect.ValueOf(i), newPathTrie(b.paths), nil, 0, false)
+
+	out := make(map[string]interface{}, len(paths))
+	var pathErrs PathErrors
+	for i, path := range paths {
+		switch {
+		case b.errs[i] != nil:
+			if pathErrs == nil {
+				pathErrs = make(PathErrors)
+			}
+			pathErrs[path] = b.errs[i]
+		case b.values[i].IsValid():
+			out[path] = resultInterface(b.values[i], &opts)
+		default:
+			out[path] = nil
+		}
+	}
+	if pathErrs != nil {
+		return out, pathErrs
+	}
+	return out, nil
+}
+
+// LookupFirst returns the value of the first of paths found on i, e.g. a new
+// field falling back to the legacy one it replaces. A path is found if Lookup
+// returns a non-nil value without error. If none is found, the error of the
+// first path which failed otherwise than with NotFound is returned, or
+// NotFound.
+func LookupFirst(i interface{}, paths []string, options ...Option) (interface{}, error) {
+	opts := NewOptions(options...)
+	var firstErr error
+	for _, path := range paths {
+		value, err := Lookup(i, path, opts)
+		if err == nil && value != nil {
+			return value, nil
+		}
+		if err != nil && firstErr == nil && status.Code(err) != codes.NotFound {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return nil, status.Errorf(codes.NotFound, "none of the paths %q found", paths)
+}
+
+// Project returns a map holding, under each key of projection, the value of
+// the path it's mapped to, e.g. {"id": "Meta.UID"}. The paths are looked up
+// as with LookupMany. A path which isn't found projects nil, and the other
+// errors fail the projection with a PathErrors.
+func Project(i interface{}, projection map[string]string, options ...Option) (map[string]interface{}, error) {
+	opts := NewOptions(options...)
+	paths := make([]string, 0, len(projection))
+	for _, path := range projection {
+		paths = append(paths, path)
+	}
+	values, err := LookupMany(i, paths, opts)
+	if pathErrs, ok := err.(PathErrors); ok {
+		for path, err := range pathErrs {
+			if status.Code(err) == codes.NotFound {
+				delete(pathErrs, path)
+			}
+		}
+		if len(pathErrs) > 0 {
+			return nil, pathErrs
+		}
+	}
+
+	out := make(map[string]interface{}, len(projection))
+	for key, path := range projection {
+		out[key] = values[path]
+	}
+	return out, nil
+}
+
+// pathTrie is a node of the trie of the paths of a LookupMany, shared by the
+// paths starting with the segments leading to it.
+type pathTrie struct {
+	// The number of segments leading to the node.
+	depth int
+	// The indexes of the paths ending at the node.
+	ends []int
+	// The segments following the node, in the order of the paths, and the
+	// nodes they lead to.
+	parts    []string
+	children map[string]*pathTrie
+	// The indexes of the paths going through the node, ends included.
+	paths []int
+}
+
+func newPathTrie(paths [][]string) *pathTrie {
+	root := &pathTrie{}
+	for i, path := range paths {
+		n := root
+		n.paths = append(n.paths, i)
+		for _, part := range path {
+			child, ok := n.children[part]
+			if !ok {
+				if n.children == nil {
+					n.children = make(map[string]*pathTrie)
+				}
+				child = &pathTrie{depth: n.depth + 1}
+				n.children[part] = child
+				n.parts = append(n.parts, part)
+			}
+			n = child
+			n.paths = append(n.paths, i)
+		}
+		n.ends = append(n.ends, i)
+	}
+	return root
+}
+
+// batch holds the paths of a LookupMany and their results, stored at their
+// index. The results of the paths going through an aggregation are also used
+// to collect the values of its elements one after the other.
+type batch struct {
+	t      *traversal
+	paths  [][]string
+	values []reflect.Value
+	errs   []error
+}
+
+func (b *batch) set(i int, value reflect.Value, err error) {
+	b.values[i], b.errs[i] = value, err
+}
+
+func (b *batch) fail(paths []int, err error) {
+	for _, i := range paths {
+		b.set(i, reflect.Value{}, err)
+	}
+}
+
+// node resolves the paths going through the node n on v, as lookup does.
+// prefix is the path of v and depth the steps taken to reach it. element is
+// set if v is an element of an aggregation, whose value is unwrapped if it
+// ends a path.
+func (b *batch) node(v reflect.Value, n *pathTrie, prefix []string, depth int, element bool) {
+	for _, i := range n.ends {
+		if element {
+			value, err := b.t.lookup(v, nil, prefix, depth)
+			b.set(i, value, err)
+			continue
+		}
+		if isStructpb(v) {
+			b.set(i, getRealValue(v), nil)
+		} else {
+			b.set(i, v, nil)
+		}
+	}
+	if len(n.parts) > 0 {
+		b.segments(v, n, n.parts, prefix, depth)
+	}
+}
+
+// segments resolves the paths going through the segments parts following the
+// node n on v, as lookup does. v is expanded once for all of them, and the
+// segments aggregating over v because they aren't found on it are aggregated
+// together.
+func (b *batch) segments(v reflect.Value, n *pathTrie, parts []string, prefix []string, depth int) {
+	t := b.t
+	fail := func(err error) {
+		for _, part := range parts {
+			b.fail(n.children[part].paths, err)
+		}
+	}
+	if err := checkDepth(depth+1, t.opts); err != nil {
+		fail(err)
+		return
+	}
+	if err := t.checkContext(); err != nil {
+		fail(err)
+		return
+	}
+	unexpanded := v
+	v, err := t.expand(v, prefix, nil)
+	if err != nil {
+		fail(err)
+		return
+	}
+	if t.opts.FlatKeys {
+		v = unflattenValue(v, getSplitToken(&t.opts))
+	}
+
+	expanded := t.opts.OnSegment != nil && isExpanded(unexpanded, v)
+	var aggregated []string
+	parent, snapshot := v, false
+	for _, part := range parts {
+		child := n.children[part]
+		if err := t.onSegment(prefix, nil, SegmentEvent{Before: true, Segment: part, Expanded: expanded}, v); err != nil {
+			b.fail(child.paths, err)
+			continue
+		}
+		var next reflect.Value
+		key, index, err := parseIndex(part)
+		if err == nil {
+			next, index, err = t.getSegment(v, key, index, prefix, []string{part})
+		}
+		if err == nil {
+			if err := t.onSegment(prefix, nil, SegmentEvent{Segment: part, Expanded: expanded, Match: t.match, Aggregated: index == wildcardIndex}, next); err != nil {
+				b.fail(child.paths, err)
+				continue
+			}
+			if index == wildcardIndex {
+				b.aggregate(next, child, nil, joinPath(prefix, []string{part}), depth+1)
+				continue
+			}
+			b.node(next, child, joinPath(prefix, []string{part}), depth+1, false)
+			continue
+		}
+
+		if !snapshot {
+			if m, ok := syncMapSnapshot(parent); ok {
+				parent = m
+			}
+			snapshot = true
+		}
+		if !isAggregable(parent) || status.Code(err) != codes.NotFound {
+			b.fail(child.paths, t.segmentFailed(prefix, nil, SegmentEvent{Segment: part, Expanded: expanded, Err: err}))
+			continue
+		}
+		if t.opts.Strict {
+			b.fail(child.paths, t.segmentFailed(prefix, nil, SegmentEvent{Segment: part, Expanded: expanded, Err: status.Errorf(codes.NotFound, "key %q not found; use %s[*] to aggregate over a %s", part, part, parent.Kind())}))
+			continue
+		}
+		if err := t.onSegment(prefix, nil, SegmentEvent{Segment: part, Expanded: expanded, Aggregated: true}, parent); err != nil {
+			b.fail(child.paths, err)
+			continue
+		}
+		aggregated = append(aggregated, part)
+	}
+	if len(aggregated) > 0 {
+		b.aggregate(parent, n, aggregated, prefix, depth+1)
+	}
+}
+
+// aggregate resolves the paths going through the node n on every element of
+// the container v, then merges the values of each path, as
+// aggreateAggregableValue does. If parts is set, only the paths going through
+// these segments following n are resolved, from the segments, otherwise all
+// the paths going through n, from n.
+func (b *batch) aggregate(v reflect.Value, n *pathTrie, parts []string, prefix []string, depth int) {
+	t, opts := b.t, b.t.opts
+	paths := n.paths
+	if parts != nil {
+		paths = nil
+		for _, part := range parts {
+			paths = append(paths, n.children[part].paths...)
+		}
+	}
+
+	l := v.Len()
+	if l == 0 || (v.Kind() == reflect.Map && opts.KeepMapKeys) || t.canParallelize(l) {
+		// These aggregations aren't shared, so they're done path by path.
+		entry := t.entry
+		for _, i := range paths {
+			t.entry = i + 1
+			value, err := t.aggreateAggregableValue(v, b.paths[i][n.depth:], prefix, depth)
+			b.set(i, value, err)
+		}
+		t.entry = entry
+		return
+	}
+
+	// The paths for which v is already being aggregated over are cycles, as
+	// they would be for their own lookup.
+	var active, cycles []int
+	var keys []visit
+	for _, i := range paths {
+		key := visit{ptr: v.Pointer(), typ: v.Type(), len: l, path: len(b.paths[i]) - n.depth, entry: i + 1}
+		if t.visiting[key] {
+			cycles = append(cycles, i)
+			continue
+		}
+		t.visiting[key] = true
+		keys = append(keys, key)
+		active = append(active, i)
+	}
+	defer func() {
+		for _, key := range keys {
+			delete(t.visiting, key)
+		}
+	}()
+
+	bufs := make([]*[]reflect.Value, len(active))
+	results := make([][]reflect.Value, len(active))
+	for k := range active {
+		bufs[k] = getValues(l)
+		results[k] = *bufs[k]
+	}
+	defer func() {
+		for k := range active {
+			putValues(bufs[k], results[k])
+		}
+	}()
+
+	errs := make([]error, len(active))
+	index := indexFunction(v)
+	for i, remaining := 0, len(active); i < l && remaining > 0; i++ {
+		if err := t.checkContext(); err != nil {
+			for k := range active {
+				if errs[k] == nil {
+					errs[k] = err
+				}
+			}
+			break
+		}
+		elem := index(i)
+		if parts == nil {
+			b.node(elemValue(elem), n, prefix, depth, true)
+		} else {
+			b.segments(elemValue(elem), n, parts, prefix, depth)
+		}
+		for k, j := range active {
+			if errs[k] != nil {
+				continue
+			}
+			if err := b.errs[j]; err != nil && t.skipsElement(v, elem, b.paths[j][n.depth:], err) {
+				b.values[j], b.errs[j] = reflect.Value{}, nil
+			}
+			if err := b.errs[j]; err != nil && (!opts.PreservePositions || status.Code(err) != codes.NotFound) {
+				errs[k] = err
+				remaining--
+				continue
+			}
+			results[k] = append(results[k], b.values[j])
+		}
+	}
+
+	for k, i := range active {
+		if errs[k] != nil {
+			b.set(i, reflect.Value{}, errs[k])
+			continue
+		}
+		if opts.PreservePositions {
+			fillZeroValues(results[k], v.Type().Elem(), b.paths[i][n.depth:], opts)
+		}
+		b.set(i, mergeAggregated(results[k], opts), nil)
+	}
+	for _, i := range cycles {
+		b.set(i, reflect.Value{}, nil)
+	}
+}
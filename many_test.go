@@ -0,0 +1,135 @@
+package lookup
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestLookupMany(c *C) {
+	paths := []string{
+		"String",
+		"Map.foo",
+		"StructSlice.String",
+		"StructSlice[1].StructSlice.String",
+		"StructSlice[*].Map.foo",
+		"JSONString.Struct.Substring",
+		"JSONString.Struct.Array",
+		"JSONList[2].foo",
+	}
+	opts := Options{ExpandStringAsJSON: true}
+	values, err := LookupMany(structFixture, append(paths, "Missing", "Map.bar"), opts)
+	c.Assert(err, FitsTypeOf, PathErrors{})
+	errs := err.(PathErrors)
+	c.Assert(errs, HasLen, 2)
+	c.Assert(status.Code(errs["Missing"]), Equals, codes.NotFound)
+	c.Assert(status.Code(errs["Map.bar"]), Equals, codes.NotFound)
+
+	c.Assert(values, HasLen, len(paths))
+	for _, path := range paths {
+		want, err := Lookup(structFixture, path, opts)
+		c.Assert(err, IsNil, Commentf("path %q", path))
+		c.Assert(values[path], DeepEquals, want, Commentf("path %q", path))
+	}
+
+	values, err = LookupMany(structFixture, []string{"String", "Map.foo"}, Options{})
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, map[string]interface{}{"String": "foo", "Map.foo": 42})
+}
+
+func (s *S) TestLookupMany_SharedAggregations(c *C) {
+	type List []interface{}
+	cyclic := List{nil, map[string]interface{}{"foo": 42, "bar": List{map[string]int{"foo": 1}}}}
+	cyclic[0] = cyclic
+
+	fixtures := []interface{}{structFixture, mapComplexFixture, cyclic}
+	paths := []string{
+		"String",
+		"StructSlice.String",
+		"StructSlice[*].String",
+		"StructSlice.StructSlice.String",
+		"StructSlice[*].StructSlice[0].String",
+		"StructSlice.Map.foo",
+		"StructSlice.Missing",
+		"StructSlice[*]",
+		"StructSlice.String[0]",
+		"list.baz",
+		"list[*].baz",
+		"list.missing",
+		"foo",
+		"bar.foo",
+		"[*].foo",
+	}
+	for _, opts := range []Options{
+		{},
+		{PreservePositions: true},
+		{Strict: true},
+		{KeepMapKeys: true},
+		{MaxDepth: 2},
+		{Parallelism: 2},
+	} {
+		for _, fixture := range fixtures {
+			values, err := LookupMany(fixture, paths, opts)
+			errs, _ := err.(PathErrors)
+			for _, path := range paths {
+				comment := Commentf("path %q of %T with %+v", path, fixture, opts)
+				want, wantErr := Lookup(fixture, path, opts)
+				// Aggregations over maps fail with the error of the first
+				// key iterated over, in no particular order.
+				c.Assert(status.Code(errs[path]), Equals, status.Code(wantErr), comment)
+				if wantErr == nil {
+					c.Assert(values[path], DeepEquals, want, comment)
+				}
+			}
+		}
+	}
+}
+
+func (s *S) TestLookupMany_ElementsResolvedOnce(c *C) {
+	var expanded []string
+	opts := Options{Expanders: []Expander{ExpanderFunc(func(v interface{}, opts Options) (interface{}, bool, error) {
+		if m, ok := v.(*MyStruct); ok {
+			expanded = append(expanded, fmt.Sprint(m.String))
+		}
+		return nil, false, nil
+	})}}
+
+	values, err := LookupMany(structFixture, []string{"StructSlice.String", "StructSlice.Map.foo"}, opts)
+	c.Assert(err, IsNil)
+	c.Assert(values["StructSlice.String"], DeepEquals, []string{"foo", "qux"})
+	c.Assert(values["StructSlice.Map.foo"], DeepEquals, []int{42, 42})
+	c.Assert(expanded, DeepEquals, []string{"foo", "qux"})
+}
+
+func (s *S) TestLookupFirst(c *C) {
+	value, err := LookupFirst(structFixture, []string{"Missing", "Nested", "Map.foo", "String"}, Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 42)
+
+	_, err = LookupFirst(structFixture, []string{"Missing", "Nested"}, Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	_, err = LookupFirst(structFixture, []string{"Missing", "String[0]", "Nested"}, Options{})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+}
+
+func (s *S) TestProject(c *C) {
+	record, err := Project(structFixture, map[string]string{
+		"name":    "String",
+		"count":   "Map.foo",
+		"names":   "StructSlice.String",
+		"missing": "Nested.String",
+	}, Options{})
+	c.Assert(err, IsNil)
+	c.Assert(record, DeepEquals, map[string]interface{}{
+		"name":    "foo",
+		"count":   42,
+		"names":   []string{"foo", "qux"},
+		"missing": nil,
+	})
+
+	_, err = Project(structFixture, map[string]string{"name": "String[0]"}, Options{})
+	c.Assert(err, FitsTypeOf, PathErrors{})
+}
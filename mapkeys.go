@@ -0,0 +1,131 @@
+package lookup
+
+import (
+	"reflect"
+	"sort"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// mapKey is like the mapKey function, but reuses the key values of string
+// kinds, as MapIndex doesn't keep them.
+func (t *traversal) mapKey(kt reflect.Type, key string) (reflect.Value, bool) {
+	if kt.Kind() != reflect.String || reflect.PtrTo(kt).Implements(textUnmarshalerType) {
+		return mapKey(kt, key)
+	}
+	k, ok := t.mapKeys[kt]
+	if !ok {
+		if t.mapKeys == nil {
+			t.mapKeys = make(map[reflect.Type]reflect.Value)
+		}
+		k = reflect.New(kt).Elem()
+		t.mapKeys[kt] = k
+	}
+	k.SetString(key)
+	return k, true
+}
+
+// keyIndex indexes the keys of a map by their names, so they're matched as
+// by matchName without going through all of them.
+type keyIndex struct {
+	// The keys, sorted by name so the matching key doesn't depend on the map
+	// iteration order.
+	keys []reflect.Value
+	nameIndex
+}
+
+// mapKeyIndex returns the keyIndex of the map v, built once per traversal, as
+// looking up several paths, or aggregating, may match keys of the same map
+// many times.
+func (t *traversal) mapKeyIndex(v reflect.Value) *keyIndex {
+	id := visit{ptr: v.Pointer(), typ: v.Type(), len: v.Len()}
+	if index, ok := t.mapIndexes[id]; ok {
+		return index
+	}
+	index := newKeyIndex(v, t.opts)
+	if t.mapIndexes == nil {
+		t.mapIndexes = make(map[visit]*keyIndex)
+	}
+	t.mapIndexes[id] = index
+	return index
+}
+
+// hasKeyNames returns whether the keys of maps of key type kt are matched by
+// their names, not only by the keys built by mapKey: if there are
+// MatchFunctions, or if the keys are Stringers or interfaces, e.g. the
+// map[interface{}]interface{} decoded by yaml.v2.
+func hasKeyNames(kt reflect.Type, opts Options) bool {
+	return len(opts.MatchFunctions) > 0 || kt.Implements(stringerType) || kt.Kind() == reflect.Interface
+}
+
+func newKeyIndex(v reflect.Value, opts Options) *keyIndex {
+	stringer := v.Type().Key().Implements(stringerType)
+	keys := v.MapKeys()
+	names := make([][]string, len(keys))
+	for i, k := range keys {
+		names[i] = mapKeyNames(k, stringer)
+	}
+	sort.Sort(byNames{keys, names})
+	return &keyIndex{keys: keys, nameIndex: newNameIndex(names, opts)}
+}
+
+// nameIndex indexes candidates by their names, and by the forms of their
+// names normalized by each of the MatchFunctions.
+type nameIndex struct {
+	names [][]string
+	// The first candidate with each name.
+	exact map[string]int
+	// For each MatchFunc, the candidates with each normalized name, in order.
+	normalized []map[string][]int
+}
+
+func newNameIndex(names [][]string, opts Options) nameIndex {
+	index := nameIndex{
+		names:      names,
+		exact:      make(map[string]int, len(names)),
+		normalized: make([]map[string][]int, len(opts.MatchFunctions)),
+	}
+	for i := range names {
+		for _, name := range names[i] {
+			if _, ok := index.exact[name]; !ok {
+				index.exact[name] = i
+			}
+		}
+	}
+	for j, f := range opts.MatchFunctions {
+		normalized := make(map[string][]int, len(names))
+		for i := range names {
+			for _, name := range names[i] {
+				n := f(name)
+				if found := normalized[n]; len(found) == 0 || found[len(found)-1] != i {
+					normalized[n] = append(found, i)
+				}
+			}
+		}
+		index.normalized[j] = normalized
+	}
+	return index
+}
+
+// match returns the index of the candidate addressed by key, or -1 if there
+// is none, as matchName does.
+func (x *nameIndex) match(key string, opts Options) (int, error) {
+	if i, ok := x.exact[key]; ok {
+		return i, nil
+	}
+	for j, f := range opts.MatchFunctions {
+		found := x.normalized[j][f(key)]
+		if len(found) == 0 {
+			continue
+		}
+		if len(found) > 1 {
+			if opts.FailOnAmbiguousMatch {
+				return -1, status.Errorf(codes.InvalidArgument, "key %q is ambiguous: matches both %q and %q", key, x.names[found[0]][0], x.names[found[1]][0])
+			}
+			logAmbiguousMatch(&opts, key, x.names[found[0]][0], x.names[found[1]][0])
+		}
+		return found[0], nil
+	}
+	return -1, nil
+}
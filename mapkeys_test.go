@@ -0,0 +1,45 @@
+package lookup
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestKeyIndex(c *C) {
+	m := map[string]int{"Foo": 0, "foo": 1, "FOO_BAR": 2, "fooBar": 3, "baz": 4}
+	trim := func(s string) string { return strings.ReplaceAll(s, "_", "") }
+
+	for _, opts := range []Options{
+		{},
+		{MatchFunctions: []MatchFunc{strings.ToLower}},
+		{MatchFunctions: []MatchFunc{trim, strings.ToLower}},
+		{MatchFunctions: []MatchFunc{strings.ToLower}, FailOnAmbiguousMatch: true},
+	} {
+		index := newKeyIndex(reflect.ValueOf(m), opts)
+		for _, key := range []string{"Foo", "FOO", "foobar", "FOOBAR", "Baz", "qux"} {
+			want, wantErr := matchName(len(index.keys), func(i int) []string {
+				return index.names[i]
+			}, key, opts)
+			got, err := index.match(key, opts)
+			c.Assert(got, Equals, want, Commentf("key %q", key))
+			c.Assert(err, DeepEquals, wantErr, Commentf("key %q", key))
+		}
+	}
+}
+
+func (s *S) TestMapKey_Reused(c *C) {
+	t := newTraversal(context.Background(), Options{})
+	m := reflect.ValueOf(mapFixtureNamed)
+	k, ok := t.mapKey(m.Type().Key(), "foo")
+	c.Assert(ok, Equals, true)
+	c.Assert(m.MapIndex(k).Interface(), Equals, 42)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		t.mapKey(m.Type().Key(), "foo")
+	})
+	c.Assert(allocs, Equals, 0.0)
+}
@@ -0,0 +1,52 @@
+package lookup
+
+import (
+	"reflect"
+	"sync"
+)
+
+// NameCache caches the names of the fields of struct types, as normalized by
+// the MatchFunctions, so the lookups sharing it match keys with fields
+// without normalizing all the fields of a struct again for every key. As it
+// doesn't tell them apart, it must only be shared by lookups using the same
+// MatchFunctions, TagKey and NoPromotedFields. The keys of maps, which may
+// change between lookups, are only indexed for the duration of a lookup. It's
+// safe for concurrent use.
+type NameCache struct {
+	fields sync.Map // map[reflect.Type]*fieldIndex
+}
+
+// NewNameCache returns an empty NameCache.
+func NewNameCache() *NameCache {
+	return &NameCache{}
+}
+
+// fieldIndex indexes the fields of a struct type by their names.
+type fieldIndex struct {
+	fields []reflect.StructField
+	nameIndex
+}
+
+// structField returns the field of t matching key, as the structField
+// function does after looking for an exact Go name.
+func (c *NameCache) structField(t reflect.Type, key string, opts Options) (reflect.StructField, bool, error) {
+	cached, ok := c.fields.Load(t)
+	if !ok {
+		fields := structFields(t, opts)
+		names := make([][]string, len(fields))
+		for i, f := range fields {
+			names[i] = []string{f.Name}
+			if name, ok := tagName(f, opts.TagKey); ok {
+				names[i] = append(names[i], name)
+			}
+		}
+		cached, _ = c.fields.LoadOrStore(t, &fieldIndex{fields: fields, nameIndex: newNameIndex(names, opts)})
+	}
+
+	index := cached.(*fieldIndex)
+	i, err := index.match(key, opts)
+	if i == -1 || err != nil {
+		return reflect.StructField{}, false, err
+	}
+	return index.fields[i], true, nil
+}
@@ -0,0 +1,47 @@
+package lookup
+
+import (
+	"reflect"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type nameCacheFixture struct {
+	Foo    int `json:"foo_bar"`
+	FOO    int
+	FooBar int `json:"baz"`
+	qux    int
+}
+
+func (s *S) TestNameCache(c *C) {
+	trim := func(s string) string { return strings.ReplaceAll(s, "_", "") }
+	ty := reflect.TypeOf(nameCacheFixture{})
+
+	for _, opts := range []Options{
+		{MatchFunctions: []MatchFunc{strings.ToLower}},
+		{MatchFunctions: []MatchFunc{trim, strings.ToLower}, TagKey: "json"},
+		{MatchFunctions: []MatchFunc{strings.ToLower}, TagKey: "json", FailOnAmbiguousMatch: true},
+	} {
+		cached := opts
+		cached.NameCache = NewNameCache()
+		for _, key := range []string{"Foo", "foo", "FOOBAR", "foo_bar", "BAZ", "qux", "missing"} {
+			want, wantOK, wantErr := structField(ty, key, opts)
+			for i := 0; i < 2; i++ {
+				got, ok, err := structField(ty, key, cached)
+				c.Assert(ok, Equals, wantOK, Commentf("key %q", key))
+				c.Assert(got.Index, DeepEquals, want.Index, Commentf("key %q", key))
+				c.Assert(err, DeepEquals, wantErr, Commentf("key %q", key))
+			}
+		}
+	}
+}
+
+func (s *S) TestLookup_NameCache(c *C) {
+	opts := Options{MatchFunctions: []MatchFunc{strings.ToLower}, NameCache: NewNameCache()}
+	for i := 0; i < 2; i++ {
+		value, err := Lookup(structFixture, "string", opts)
+		c.Assert(err, IsNil)
+		c.Assert(value, Equals, structFixture.String)
+	}
+}
@@ -0,0 +1,57 @@
+package lookup
+
+import (
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// errorDomain is the domain of the ErrorInfo details of the errors.
+	errorDomain = "github.com/kevinxw/go-lookup"
+	// nilPointerReason is the reason of the errors of the keys looked up on
+	// nil pointers.
+	nilPointerReason = "NIL_POINTER"
+)
+
+// nilPointerError returns the NotFound error of key looked up on a nil
+// pointer or interface, with an ErrorInfo detail telling it apart from a
+// missing key.
+func nilPointerError(key string) error {
+	return errorWithReason(status.Newf(codes.NotFound, "key %q not found: nil pointer", key), nilPointerReason, key)
+}
+
+// errorWithReason returns the error of st, with an ErrorInfo detail holding
+// reason and key.
+func errorWithReason(st *status.Status, reason, key string) error {
+	info := &errdetails.ErrorInfo{Reason: reason, Domain: errorDomain, Metadata: map[string]string{"key": key}}
+	if d, err := st.WithDetails(info); err == nil {
+		st = d
+	}
+	return st.Err()
+}
+
+// hasReason returns whether err, or an error it wraps, has an ErrorInfo
+// detail with reason.
+func hasReason(err error, reason string) bool {
+	var se interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &se) {
+		return false
+	}
+	for _, d := range se.GRPCStatus().Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.Domain == errorDomain && info.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNilPointer returns whether err is the NotFound error of a path going
+// through a nil pointer or interface, e.g. "Nested.String" when Nested is nil,
+// rather than through a missing key. See Options.NilPointersAsAbsent to have
+// such paths found with a nil value instead.
+func IsNilPointer(err error) bool {
+	return hasReason(err, nilPointerReason)
+}
@@ -0,0 +1,57 @@
+package lookup
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestIsNilPointer(c *C) {
+	fixture := MyStruct{StructSlice: []*MyStruct{{Nested: &MyStruct{String: "foo"}}, {}}}
+
+	_, err := Lookup(fixture, "Nested.String")
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+	c.Assert(IsNilPointer(err), Equals, true)
+	c.Assert(err, ErrorMatches, `.*key "String" not found: nil pointer`)
+
+	_, err = Lookup(fixture, "Interface.Key")
+	c.Assert(IsNilPointer(err), Equals, true)
+	_, err = Lookup(fixture, "StructSlice.Nested.String")
+	c.Assert(IsNilPointer(err), Equals, true)
+	_, err = Lookup(map[string]interface{}{"a": nil}, "a.b")
+	c.Assert(IsNilPointer(err), Equals, true)
+
+	_, err = Lookup(fixture, "Missing")
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+	c.Assert(IsNilPointer(err), Equals, false)
+	c.Assert(IsNilPointer(nil), Equals, false)
+	c.Assert(IsNilPointer(status.Error(codes.NotFound, "nil pointer")), Equals, false)
+}
+
+func (s *S) TestLookup_NilPointersAsAbsent(c *C) {
+	fixture := MyStruct{StructSlice: []*MyStruct{{Nested: &MyStruct{String: "foo"}}, {}, nil}}
+
+	value, err := Lookup(fixture, "Nested.Nested.String", NilPointersAsAbsent())
+	c.Assert(err, IsNil)
+	c.Assert(value, IsNil)
+	value, err = Lookup(fixture, "Interface.StructSlice[0]", NilPointersAsAbsent())
+	c.Assert(err, IsNil)
+	c.Assert(value, IsNil)
+
+	// The elements with nil pointers are skipped, or zero with PreservePositions.
+	value, err = Lookup(fixture, "StructSlice.Nested.String", NilPointersAsAbsent())
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []string{"foo"})
+	value, err = Lookup(fixture, "StructSlice.Nested.String", NilPointersAsAbsent(), PreservePositions())
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []string{"foo", "", ""})
+
+	// Missing keys are still missing.
+	_, err = Lookup(fixture, "Missing.String", NilPointersAsAbsent())
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+	c.Assert(Exists(fixture, "Nested.String", NilPointersAsAbsent()), Equals, false)
+
+	values, err := LookupMany(fixture, []string{"Nested.String", "String"}, NilPointersAsAbsent())
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, map[string]interface{}{"Nested.String": nil, "String": ""})
+}
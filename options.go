@@ -0,0 +1,159 @@
+package lookup
+
+import "strings"
+
+// Option configures a lookup, as an alternative to setting the fields of
+// Options, e.g.:
+//
+//	lookup.Lookup(v, path, lookup.CaseInsensitive(), lookup.WithSplitToken("/"))
+//
+// An Options is itself an Option, replacing the configuration set by the
+// options preceding it, so the functions of this package accept either.
+type Option interface {
+	apply(opts *Options)
+}
+
+func (o Options) apply(opts *Options) {
+	*opts = o
+}
+
+// optionFunc is an Option calling itself.
+type optionFunc func(opts *Options)
+
+func (f optionFunc) apply(opts *Options) {
+	f(opts)
+}
+
+// NewOptions returns the Options configured by options, applied in order.
+func NewOptions(options ...Option) Options {
+	if len(options) == 1 {
+		// The common case, without allocating the Options configured.
+		if opts, ok := options[0].(Options); ok {
+			return opts
+		}
+	}
+
+	var opts Options
+	for _, o := range options {
+		// Rather than calling apply, so the Options passed don't escape.
+		switch o := o.(type) {
+		case Options:
+			opts = o
+		case optionFunc:
+			o(&opts)
+		}
+	}
+	return opts
+}
+
+// CaseInsensitive matches the keys with the fields and the map keys
+// regardless of their case, if no exact match is found, by adding
+// strings.ToLower to Options.MatchFunctions.
+func CaseInsensitive() Option {
+	return WithMatchFunctions(strings.ToLower)
+}
+
+// WithMatchFunctions adds fs to Options.MatchFunctions.
+func WithMatchFunctions(fs ...MatchFunc) Option {
+	return optionFunc(func(opts *Options) {
+		opts.MatchFunctions = append(opts.MatchFunctions[:len(opts.MatchFunctions):len(opts.MatchFunctions)], fs...)
+	})
+}
+
+// WithSplitToken sets Options.SplitToken.
+func WithSplitToken(token string) Option {
+	return optionFunc(func(opts *Options) {
+		opts.SplitToken = token
+	})
+}
+
+// WithTagKey sets Options.TagKey.
+func WithTagKey(key string) Option {
+	return optionFunc(func(opts *Options) {
+		opts.TagKey = key
+	})
+}
+
+// WithMaxDepth sets Options.MaxDepth.
+func WithMaxDepth(depth int) Option {
+	return optionFunc(func(opts *Options) {
+		opts.MaxDepth = depth
+	})
+}
+
+// WithExpanders adds expanders to Options.Expanders.
+func WithExpanders(expanders ...Expander) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Expanders = append(opts.Expanders[:len(opts.Expanders):len(opts.Expanders)], expanders...)
+	})
+}
+
+// WithParallelism sets Options.Parallelism.
+func WithParallelism(n int) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Parallelism = n
+	})
+}
+
+// WithLogger sets Options.Logger.
+func WithLogger(l Logger) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Logger = l
+	})
+}
+
+// ExpandJSON sets Options.ExpandStringAsJSON.
+func ExpandJSON() Option {
+	return optionFunc(func(opts *Options) {
+		opts.ExpandStringAsJSON = true
+	})
+}
+
+// Strict sets Options.Strict.
+func Strict() Option {
+	return optionFunc(func(opts *Options) {
+		opts.Strict = true
+	})
+}
+
+// CopyResult sets Options.CopyResult.
+func CopyResult() Option {
+	return optionFunc(func(opts *Options) {
+		opts.CopyResult = true
+	})
+}
+
+// PreservePositions sets Options.PreservePositions.
+func PreservePositions() Option {
+	return optionFunc(func(opts *Options) {
+		opts.PreservePositions = true
+	})
+}
+
+// PreserveNesting sets Options.PreserveNesting.
+func PreserveNesting() Option {
+	return optionFunc(func(opts *Options) {
+		opts.PreserveNesting = true
+	})
+}
+
+// DedupeAggregated sets Options.DedupeAggregated.
+func DedupeAggregated() Option {
+	return optionFunc(func(opts *Options) {
+		opts.DedupeAggregated = true
+	})
+}
+
+// KeepMapKeys sets Options.KeepMapKeys.
+func KeepMapKeys() Option {
+	return optionFunc(func(opts *Options) {
+		opts.KeepMapKeys = true
+	})
+}
+
+// NilPointersAsAbsent sets Options.NilPointersAsAbsent.
+func NilPointersAsAbsent() Option {
+	return optionFunc(func(opts *Options) {
+		opts.NilPointersAsAbsent = true
+	})
+}
@@ -0,0 +1,50 @@
+package lookup
+
+import (
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestNewOptions(c *C) {
+	c.Assert(NewOptions(), DeepEquals, Options{})
+	c.Assert(NewOptions(Options{TagKey: "json"}), DeepEquals, Options{TagKey: "json"})
+
+	opts := NewOptions(WithSplitToken("/"), ExpandJSON(), WithTagKey("json"), WithMaxDepth(3), Strict(), PreservePositions(), KeepMapKeys(), WithParallelism(2))
+	c.Assert(opts, DeepEquals, Options{
+		SplitToken:         "/",
+		ExpandStringAsJSON: true,
+		TagKey:             "json",
+		MaxDepth:           3,
+		Strict:             true,
+		PreservePositions:  true,
+		KeepMapKeys:        true,
+		Parallelism:        2,
+	})
+
+	// An Options replaces the options preceding it.
+	opts = NewOptions(WithSplitToken("/"), Options{TagKey: "json"}, WithMaxDepth(3))
+	c.Assert(opts, DeepEquals, Options{TagKey: "json", MaxDepth: 3})
+
+	opts = NewOptions(Options{MatchFunctions: []MatchFunc{strings.TrimSpace}}, CaseInsensitive())
+	c.Assert(opts.MatchFunctions, HasLen, 2)
+}
+
+func (s *S) TestLookup_FunctionalOptions(c *C) {
+	value, err := Lookup(structFixture, "string", CaseInsensitive())
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "foo")
+
+	value, err = Lookup(structFixture, "JSONString/Struct/Substring", WithSplitToken("/"), ExpandJSON())
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "Abcd")
+
+	_, err = Lookup(structFixture, "StructSlice.String", Strict())
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	value, err = Lookup(structFixture, "String")
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "foo")
+}
@@ -0,0 +1,79 @@
+package lookup
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// minParallelLen is the length from which aggregations are parallelized, as
+// the goroutines cost more than they save on smaller containers.
+const minParallelLen = 1000
+
+// canParallelize returns whether an aggregation over n elements is
+// parallelized.
+func (t *traversal) canParallelize(n int) bool {
+	return t.opts.Parallelism > 1 && n >= minParallelLen && !t.sequential
+}
+
+// lookupParallel sets values[i] to the value of lookup for the element i,
+// computed by up to opts.Parallelism goroutines, each with its own copy of t.
+// The first error, by element, is returned, and stops the other goroutines.
+func (t *traversal) lookupParallel(values []reflect.Value, lookup func(t *traversal, i int) (reflect.Value, error)) error {
+	ctx, cancel := context.WithCancel(t.ctx)
+	defer cancel()
+
+	n := len(values)
+	errs := make([]error, n)
+	var next int64 = -1
+	var wg sync.WaitGroup
+	for w := 0; w < t.opts.Parallelism && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker := t.fork(ctx)
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= n {
+					return
+				}
+				if errs[i] = worker.checkContext(); errs[i] == nil {
+					values[i], errs[i] = lookup(worker, i)
+				}
+				if errs[i] != nil {
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The cancellations caused by the first errors hide nothing.
+	if err := t.checkContext(); err != nil {
+		return err
+	}
+	for _, err := range errs {
+		if err != nil && status.Code(err) != codes.Canceled {
+			return err
+		}
+	}
+	return nil
+}
+
+// fork returns a copy of t, for a goroutine of a parallel aggregation.
+func (t *traversal) fork(ctx context.Context) *traversal {
+	c := *t
+	c.ctx = ctx
+	c.sequential = true
+	c.mapKeys, c.mapIndexes = nil, nil
+	c.visiting = make(map[visit]bool, len(t.visiting))
+	for k, v := range t.visiting {
+		c.visiting[k] = v
+	}
+	return &c
+}
@@ -0,0 +1,63 @@
+package lookup
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestLookup_Parallelism(c *C) {
+	items := make([]interface{}, 5000)
+	for i := range items {
+		item := map[string]interface{}{"id": i, "tags": []string{fmt.Sprint(i)}}
+		if i%3 == 0 {
+			item["name"] = fmt.Sprintf("item-%d", i)
+		}
+		items[i] = item
+	}
+	fixture := map[string]interface{}{"items": items}
+
+	for _, test := range []struct {
+		path string
+		opts Options
+	}{
+		{"items.id", Options{}},
+		{"items.tags", Options{}},
+		{"items[*].id", Options{PreservePositions: true}},
+		{"items.name", Options{}},
+	} {
+		want, wantErr := Lookup(fixture, test.path, test.opts)
+
+		test.opts.Parallelism = 4
+		value, err := Lookup(fixture, test.path, test.opts)
+		c.Assert(value, DeepEquals, want, Commentf("path %q", test.path))
+		c.Assert(err, DeepEquals, wantErr, Commentf("path %q", test.path))
+	}
+
+	want, err := Count(fixture, "items.name", Options{PreservePositions: true})
+	c.Assert(err, IsNil)
+	n, err := Count(fixture, "items.name", Options{Parallelism: 4, PreservePositions: true})
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, want)
+
+	failing := ExpanderFunc(func(v interface{}, opts Options) (interface{}, bool, error) {
+		if v == "item-3000" {
+			return nil, false, status.Errorf(codes.DataLoss, "corrupted")
+		}
+		return nil, false, nil
+	})
+	_, err = Lookup(fixture, "items.name.x", Options{Parallelism: 4, PreservePositions: true, Expanders: []Expander{failing}})
+	c.Assert(status.Code(err), Equals, codes.DataLoss)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	t := newTraversal(ctx, Options{Parallelism: 4})
+	err = t.lookupParallel(make([]reflect.Value, len(items)), func(*traversal, int) (reflect.Value, error) {
+		return reflect.Value{}, nil
+	})
+	c.Assert(status.Code(err), Equals, codes.Canceled)
+}
@@ -0,0 +1,40 @@
+package lookup
+
+import (
+	"reflect"
+	"sync"
+)
+
+// maxPooledValues is the capacity above which the buffers of values aren't
+// put back in valuesPool, so a single huge aggregation doesn't pin its memory.
+const maxPooledValues = 1 << 16
+
+// valuesPool holds the buffers of the values of aggregations, which don't
+// outlive the aggregation once merged.
+var valuesPool = sync.Pool{
+	New: func() interface{} {
+		return new([]reflect.Value)
+	},
+}
+
+// getValues returns an empty buffer from valuesPool, with room for n values.
+func getValues(n int) *[]reflect.Value {
+	buf := valuesPool.Get().(*[]reflect.Value)
+	if cap(*buf) < n {
+		*buf = make([]reflect.Value, 0, n)
+	}
+	return buf
+}
+
+// putValues puts buf back in valuesPool, with values, which must not be used
+// anymore, cleared so the pool doesn't keep what they reference alive.
+func putValues(buf *[]reflect.Value, values []reflect.Value) {
+	if cap(values) > maxPooledValues {
+		return
+	}
+	for i := range values {
+		values[i] = reflect.Value{}
+	}
+	*buf = values[:0]
+	valuesPool.Put(buf)
+}
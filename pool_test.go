@@ -0,0 +1,29 @@
+package lookup
+
+import (
+	"reflect"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestValuesPool(c *C) {
+	buf := getValues(3)
+	c.Assert(*buf, HasLen, 0)
+	c.Assert(cap(*buf) >= 3, Equals, true)
+
+	values := append(*buf, reflect.ValueOf("foo"), reflect.ValueOf(42))
+	putValues(buf, values)
+	c.Assert(*buf, HasLen, 0)
+	// The pooled values don't reference what they held.
+	c.Assert((*buf)[:2], DeepEquals, []reflect.Value{{}, {}})
+}
+
+func (s *S) TestLookup_PooledValues(c *C) {
+	// The results don't share the pooled buffers of the aggregations.
+	first, err := Lookup(structFixture, "StructSlice.String", Options{})
+	c.Assert(err, IsNil)
+	second, err := Lookup(structFixture, "StructSlice.StructSlice.String", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(first, DeepEquals, []string{"foo", "qux"})
+	c.Assert(second, DeepEquals, []string{"bar", "foo", "qux", "baz"})
+}
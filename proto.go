@@ -0,0 +1,140 @@
+package lookup
+
+import (
+	"reflect"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+var (
+	structpbStructType    = reflect.TypeOf((*structpb.Struct)(nil)).Elem()
+	structpbListValueType = reflect.TypeOf((*structpb.ListValue)(nil)).Elem()
+	structpbValueType     = reflect.TypeOf((*structpb.Value)(nil)).Elem()
+	interfaceType         = reflect.TypeOf((*interface{})(nil)).Elem()
+	timestamppbType       = reflect.TypeOf((*timestamppb.Timestamp)(nil)).Elem()
+	durationpbType        = reflect.TypeOf((*durationpb.Duration)(nil)).Elem()
+
+	wrapperspbTypes = map[reflect.Type]bool{
+		reflect.TypeOf((*wrapperspb.DoubleValue)(nil)).Elem(): true,
+		reflect.TypeOf((*wrapperspb.FloatValue)(nil)).Elem():  true,
+		reflect.TypeOf((*wrapperspb.Int64Value)(nil)).Elem():  true,
+		reflect.TypeOf((*wrapperspb.UInt64Value)(nil)).Elem(): true,
+		reflect.TypeOf((*wrapperspb.Int32Value)(nil)).Elem():  true,
+		reflect.TypeOf((*wrapperspb.UInt32Value)(nil)).Elem(): true,
+		reflect.TypeOf((*wrapperspb.BoolValue)(nil)).Elem():   true,
+		reflect.TypeOf((*wrapperspb.StringValue)(nil)).Elem(): true,
+		reflect.TypeOf((*wrapperspb.BytesValue)(nil)).Elem():  true,
+	}
+)
+
+// anyExpander unpacks google.protobuf.Any values into the message they hold,
+// resolved through the global type registry.
+type anyExpander struct{}
+
+func (anyExpander) Expand(v interface{}, opts Options) (interface{}, bool, error) {
+	var a *anypb.Any
+	switch v := v.(type) {
+	case *anypb.Any:
+		a = v
+	case anypb.Any:
+		a = &v
+	default:
+		return nil, false, nil
+	}
+	if a == nil || a.GetTypeUrl() == "" {
+		return nil, false, nil
+	}
+
+	m, err := a.UnmarshalNew()
+	if err != nil {
+		return nil, false, status.Errorf(codes.FailedPrecondition, "cannot unpack %q: %v", a.GetTypeUrl(), err)
+	}
+	return m, true, nil
+}
+
+// isStructpb returns whether v is, or points to, a google.protobuf.Struct,
+// ListValue or Value.
+func isStructpb(v reflect.Value) bool {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return false
+	}
+	t := v.Type()
+	return t == structpbStructType || t == structpbListValueType || t == structpbValueType
+}
+
+// fromStructpb converts the google.protobuf.Struct v into its map of fields,
+// the ListValue v into its slice of values and the Value v into the value of
+// its kind, a nil interface for null values. It returns false if v isn't one of
+// them.
+func fromStructpb(v reflect.Value) (reflect.Value, bool) {
+	t := v.Type()
+	if t != structpbStructType && t != structpbListValueType && t != structpbValueType {
+		return reflect.Value{}, false
+	}
+	switch m := pointerTo(v).(type) {
+	case *structpb.Struct:
+		return reflect.ValueOf(m.GetFields()), true
+	case *structpb.ListValue:
+		return reflect.ValueOf(m.GetValues()), true
+	case *structpb.Value:
+		switch k := m.GetKind().(type) {
+		case *structpb.Value_StringValue:
+			return reflect.ValueOf(k.StringValue), true
+		case *structpb.Value_NumberValue:
+			return reflect.ValueOf(k.NumberValue), true
+		case *structpb.Value_BoolValue:
+			return reflect.ValueOf(k.BoolValue), true
+		case *structpb.Value_StructValue:
+			return reflect.ValueOf(k.StructValue), true
+		case *structpb.Value_ListValue:
+			return reflect.ValueOf(k.ListValue), true
+		}
+	}
+	// A null value.
+	return reflect.Zero(interfaceType), true
+}
+
+// fromWrapperspb returns the scalar held by the wrapper message v, e.g. a
+// google.protobuf.StringValue. It returns false if v isn't a wrapper.
+func fromWrapperspb(v reflect.Value) (reflect.Value, bool) {
+	if !wrapperspbTypes[v.Type()] {
+		return reflect.Value{}, false
+	}
+	return v.FieldByName("Value"), true
+}
+
+// fromTimepb converts the google.protobuf.Timestamp v into a time.Time, and the
+// google.protobuf.Duration v into a time.Duration. It returns false if v isn't
+// one of them.
+func fromTimepb(v reflect.Value) (reflect.Value, bool) {
+	switch v.Type() {
+	case timestamppbType:
+		return reflect.ValueOf(pointerTo(v).(*timestamppb.Timestamp).AsTime()), true
+	case durationpbType:
+		return reflect.ValueOf(pointerTo(v).(*durationpb.Duration).AsDuration()), true
+	}
+	return reflect.Value{}, false
+}
+
+// pointerTo returns a pointer to v, or to a copy of v if v isn't addressable,
+// e.g. to call the methods of a message.
+func pointerTo(v reflect.Value) interface{} {
+	if !v.CanAddr() {
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		v = p.Elem()
+	}
+	return v.Addr().Interface()
+}
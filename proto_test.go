@@ -0,0 +1,150 @@
+package lookup
+
+import (
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestUnpackAny(c *C) {
+	payload, err := anypb.New(timestamppb.New(time.Unix(42, 0)))
+	c.Assert(err, IsNil)
+	fixture := struct {
+		Payload *anypb.Any
+	}{Payload: payload}
+
+	value, err := Lookup(fixture, "Payload.Seconds", Options{UnpackAny: true})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, int64(42))
+
+	_, err = Lookup(fixture, "Payload.Seconds", Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	fixture.Payload = &anypb.Any{TypeUrl: "type.googleapis.com/unknown.Message"}
+	_, err = Lookup(fixture, "Payload.Seconds", Options{UnpackAny: true})
+	c.Assert(status.Code(err), Equals, codes.FailedPrecondition)
+}
+
+func (s *S) TestLookup_Structpb(c *C) {
+	fixture, err := structpb.NewStruct(map[string]interface{}{
+		"name": "foo",
+		"spec": map[string]interface{}{
+			"replicas": 3,
+			"enabled":  true,
+			"extra":    nil,
+		},
+		"items": []interface{}{
+			map[string]interface{}{"id": "a"},
+			map[string]interface{}{"id": "b"},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	value, err := Lookup(fixture, "name", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "foo")
+
+	value, err = Lookup(fixture, "spec.replicas", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, float64(3))
+
+	value, err = Lookup(struct{ Data *structpb.Struct }{fixture}, "Data.spec.enabled", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, true)
+
+	value, err = Lookup(fixture, "spec.extra", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value, IsNil)
+
+	value, err = Lookup(fixture, "items[1].id", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "b")
+
+	value, err = Lookup(fixture, "items.id", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []string{"a", "b"})
+
+	_, err = Lookup(fixture, "spec.missing", Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+}
+
+func (s *S) TestUnwrapWrappers(c *C) {
+	type Message struct {
+		Name  *wrapperspb.StringValue
+		Count *wrapperspb.Int64Value
+		Tags  []*wrapperspb.StringValue
+	}
+	fixture := Message{
+		Name:  wrapperspb.String("foo"),
+		Count: wrapperspb.Int64(42),
+		Tags:  []*wrapperspb.StringValue{wrapperspb.String("a"), wrapperspb.String("b")},
+	}
+	opts := Options{UnwrapWrappers: true}
+
+	value, err := Lookup(fixture, "Name", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "foo")
+
+	value, err = Lookup(fixture, "Count", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, int64(42))
+
+	value, err = Lookup(fixture, "Tags[1]", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "b")
+
+	value, err = Lookup(fixture, "Tags[*]", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []string{"a", "b"})
+
+	value, err = Lookup(fixture, "Name.Value", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "foo")
+}
+
+func (s *S) TestConvertTimes(c *C) {
+	created := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	fixture := struct {
+		Created *timestamppb.Timestamp
+		Timeout *durationpb.Duration
+	}{
+		Created: timestamppb.New(created),
+		Timeout: durationpb.New(90 * time.Second),
+	}
+	opts := Options{ConvertTimes: true}
+
+	value, err := Lookup(fixture, "Created", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, created)
+
+	value, err = Lookup(fixture, "Created.Unix", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, created.Unix())
+
+	value, err = Lookup(fixture, "created.year", Options{ConvertTimes: true, MatchFunctions: []MatchFunc{strings.ToLower}})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 2021)
+
+	value, err = Lookup(fixture, "Timeout", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 90*time.Second)
+
+	value, err = Lookup(fixture, "Timeout.Minutes", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 1.5)
+
+	_, err = Lookup(fixture, "Created.Format", opts)
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	value, err = Lookup(fixture, "Created.Seconds", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, created.Unix())
+}
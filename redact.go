@@ -0,0 +1,129 @@
+package lookup
+
+import (
+	"reflect"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Redact returns a deep copy of i, e.g. a request to log, with the values at
+// paths replaced by mask, e.g. "***". Paths are resolved as by LookupAll, so a
+// path aggregating over slices or maps masks the value of every element it
+// fans out to. Values which can't hold mask, e.g. numbers for a string mask,
+// are zeroed instead. A path which isn't found masks nothing. The values found
+// in expanded ones aren't in i to be masked, so the options expanding values,
+// e.g. Options.ExpandStringAsJSON, fail with InvalidArgument.
+func Redact(i interface{}, paths []string, mask interface{}, options ...Option) (interface{}, error) {
+	opts := NewOptions(options...)
+	if err := checkNoExpansion("Redact", opts); err != nil {
+		return nil, err
+	}
+	v := reflect.ValueOf(i)
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	out := reflect.New(v.Type()).Elem()
+	out.Set(deepCopy(v))
+	for _, path := range paths {
+		matches, err := LookupAll(i, path, opts)
+		if status.Code(err) == codes.NotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if err := redactPath(out, splitIndexes(splitPath(m.Path, &opts)), mask, opts); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out.Interface(), nil
+}
+
+// redactPath sets the value at path in v, which must be settable, to mask.
+func redactPath(v reflect.Value, path []string, mask interface{}, opts Options) error {
+	if len(path) == 0 {
+		if err := assign(v, mask); err != nil {
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		if err := redactPath(elem, path, mask, opts); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return redactPath(v.Elem(), path, mask, opts)
+	}
+
+	key, index, err := parseIndex(path[0])
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(path[0], indexOpenChar) {
+		if (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || index < 0 || index >= v.Len() {
+			return status.Errorf(codes.InvalidArgument, "index %q can't be resolved on a %s", path[0], v.Kind())
+		}
+		return redactPath(v.Index(index), path[1:], mask, opts)
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		f, ok, err := structField(v.Type(), key, opts)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return status.Errorf(codes.InvalidArgument, "field %q not found in %s", key, v.Type())
+		}
+		field := fieldByIndex(v, f.Index)
+		if !field.IsValid() {
+			// Behind a nil embedded pointer.
+			return nil
+		}
+		if !field.CanSet() {
+			return status.Errorf(codes.FailedPrecondition, "field %q of %s can't be redacted", key, v.Type())
+		}
+		return redactPath(field, path[1:], mask, opts)
+	case reflect.Map:
+		k, ok := mapKey(v.Type().Key(), key)
+		if !ok || !v.MapIndex(k).IsValid() {
+			index := newKeyIndex(v, opts)
+			i, err := index.match(key, opts)
+			if err != nil {
+				return err
+			}
+			if i == -1 {
+				return nil
+			}
+			k = index.keys[i]
+		}
+
+		// Map elements aren't addressable, so the element is updated through
+		// a copy.
+		elem := reflect.New(v.Type().Elem()).Elem()
+		elem.Set(v.MapIndex(k))
+		if err := redactPath(elem, path[1:], mask, opts); err != nil {
+			return err
+		}
+		v.SetMapIndex(k, elem)
+		return nil
+	default:
+		return status.Errorf(codes.InvalidArgument, "key %q can't be resolved on a %s", key, v.Kind())
+	}
+}
@@ -0,0 +1,51 @@
+package lookup
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestRedact(c *C) {
+	value, err := Redact(map[string]interface{}{
+		"user": map[string]interface{}{"Name": "bob", "password": "secret"},
+		"items": []interface{}{
+			map[string]interface{}{"id": 1, "token": "a"},
+			map[string]interface{}{"id": 2, "token": "b"},
+		},
+		"codes": map[string]int{"x": 1},
+	}, []string{"user.Password", "items.token", "codes.x", "missing"}, "***", CaseInsensitive())
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, map[string]interface{}{
+		"user": map[string]interface{}{"Name": "bob", "password": "***"},
+		"items": []interface{}{
+			map[string]interface{}{"id": 1, "token": "***"},
+			map[string]interface{}{"id": 2, "token": "***"},
+		},
+		// An int can't hold the mask.
+		"codes": map[string]int{"x": 0},
+	})
+
+	value, err = Redact(nil, []string{"String"}, "***")
+	c.Assert(err, IsNil)
+	c.Assert(value, IsNil)
+
+	// The values expanded from strings can't be masked in them.
+	_, err = Redact(structFixture, []string{"JSONString.Struct.StructInArray"}, "***", ExpandJSON())
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+	_, err = Redact(structFixture, []string{"String"}, "***", Options{ExpandStringAsXML: true})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+}
+
+func (s *S) TestRedact_Struct(c *C) {
+	value, err := Redact(&structFixture, []string{"String", "StructSlice.StructSlice.String"}, "***")
+	c.Assert(err, IsNil)
+	out := value.(*MyStruct)
+	c.Assert(out.String, Equals, "***")
+	c.Assert(out.StructSlice[0].StructSlice[1].String, Equals, "***")
+	c.Assert(out.StructSlice[1].StructSlice[0].String, Equals, "***")
+	c.Assert(out.StructSlice[0].String, Equals, "foo")
+	// structFixture isn't modified.
+	c.Assert(structFixture.String, Equals, "foo")
+	c.Assert(structFixture.StructSlice[0].StructSlice[1].String, Equals, "foo")
+}
@@ -0,0 +1,84 @@
+package lookup
+
+import (
+	"reflect"
+	"sync"
+)
+
+// PathResolver is implemented by the values resolving the keys looked up on
+// them by themselves, such as wrapper types, lazy proxies or ORM models,
+// instead of having their fields or keys resolved by reflection. LookupKey
+// returns the value of key, which the rest of the path is looked up on, or an
+// error with the NotFound code if key isn't found, so it's treated as any
+// missing key, e.g. by Exists or PreservePositions. A nil value is missing
+// too. Indexes, e.g. "items[0]", are resolved on the value returned for the
+// key "items".
+type PathResolver interface {
+	LookupKey(key string) (interface{}, error)
+}
+
+var pathResolverType = reflect.TypeOf((*PathResolver)(nil)).Elem()
+
+// resolverKind tells how the values of a type implement PathResolver.
+type resolverKind uint8
+
+const (
+	notResolver resolverKind = iota
+	valueResolver
+	pointerResolver
+)
+
+// resolverKinds caches the resolverKind of the types, as checking their method
+// sets is slow.
+var resolverKinds sync.Map // map[reflect.Type]resolverKind
+
+// resolverKindOf returns how the values of the type t implement
+// PathResolver.
+func resolverKindOf(t reflect.Type) resolverKind {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Ptr, reflect.Interface:
+	default:
+		if t.Name() == "" {
+			// Only named types have methods, besides the structs embedding
+			// them, and the pointers and interfaces.
+			return notResolver
+		}
+	}
+
+	if kind, ok := resolverKinds.Load(t); ok {
+		return kind.(resolverKind)
+	}
+	kind := notResolver
+	switch {
+	case t.Implements(pathResolverType):
+		kind = valueResolver
+	case reflect.PtrTo(t).Implements(pathResolverType):
+		kind = pointerResolver
+	}
+	resolverKinds.Store(t, kind)
+	return kind
+}
+
+// asPathResolver returns v as a PathResolver, if it implements it, by itself
+// or through its address. A nil pointer or interface doesn't.
+func asPathResolver(v reflect.Value) (PathResolver, bool) {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil, false
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, false
+		}
+	}
+
+	switch resolverKindOf(v.Type()) {
+	case valueResolver:
+		return v.Interface().(PathResolver), true
+	case pointerResolver:
+		if v.CanAddr() {
+			return v.Addr().Interface().(PathResolver), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,94 @@
+package lookup
+
+import (
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+// lazyRecord resolves its keys from its attributes, with upper-cased keys.
+type lazyRecord struct {
+	Name  string
+	attrs map[string]interface{}
+	calls int
+}
+
+func (r *lazyRecord) LookupKey(key string) (interface{}, error) {
+	r.calls++
+	if key == "fail" {
+		return nil, status.Errorf(codes.PermissionDenied, "denied")
+	}
+	value, ok := r.attrs[strings.ToUpper(key)]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "attribute %q not found", key)
+	}
+	return value, nil
+}
+
+// upperMap resolves its keys upper-cased.
+type upperMap map[string]int
+
+func (m upperMap) LookupKey(key string) (interface{}, error) {
+	return m[strings.ToUpper(key)], nil
+}
+
+func (s *S) TestLookup_PathResolver(c *C) {
+	record := &lazyRecord{Name: "foo", attrs: map[string]interface{}{
+		"ID":    42,
+		"TAGS":  []string{"a", "b"},
+		"OWNER": map[string]interface{}{"name": "bar"},
+		"NIL":   nil,
+	}}
+	fixture := struct {
+		Record  *lazyRecord
+		Records []*lazyRecord
+		Value   lazyRecord
+		Upper   upperMap
+	}{
+		Record:  record,
+		Records: []*lazyRecord{record, {attrs: map[string]interface{}{"ID": 1}}},
+		Value:   lazyRecord{attrs: map[string]interface{}{"ID": 2}},
+		Upper:   upperMap{"FOO": 3},
+	}
+
+	tests := []struct {
+		path string
+		want interface{}
+	}{
+		{"Record.id", 42},
+		{"Record.tags[1]", "b"},
+		{"Record.owner.name", "bar"},
+		{"Records.id", []int{42, 1}},
+		{"Upper.foo", 3},
+	}
+	for _, test := range tests {
+		value, err := Lookup(fixture, test.path)
+		c.Assert(err, IsNil, Commentf("path %q", test.path))
+		c.Assert(value, DeepEquals, test.want, Commentf("path %q", test.path))
+	}
+
+	// The fields of a resolver aren't resolved by reflection.
+	_, err := Lookup(fixture, "Record.Name")
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+	_, err = Lookup(fixture, "Record.nil")
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+	_, err = Lookup(fixture, "Record.fail")
+	c.Assert(status.Code(err), Equals, codes.PermissionDenied)
+	c.Assert(Exists(fixture, "Record.missing"), Equals, false)
+
+	// Only addressable values implement the methods of their pointer.
+	value, err := Lookup(&fixture, "Value.id")
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 2)
+	value, err = Lookup(fixture, "Value.Name")
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "")
+
+	calls := record.calls
+	value, err = Lookup(record, "id")
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 42)
+	c.Assert(record.calls, Equals, calls+1)
+}
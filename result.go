@@ -0,0 +1,101 @@
+package lookup
+
+import (
+	"time"
+)
+
+// LookupResult is the result of a lookup made with Get. Its accessors return
+// the zero value of their type when the lookup failed or the value can't be
+// converted, so optional values can be read without handling errors.
+type LookupResult struct {
+	value interface{}
+	err   error
+	opts  Options
+}
+
+// Get performs a lookup like Lookup, and returns its result as a LookupResult.
+func Get(i interface{}, path string, options ...Option) LookupResult {
+	opts := NewOptions(options...)
+	value, err := lookupNotNil(i, path, opts)
+	return LookupResult{value: value, err: err, opts: opts}
+}
+
+// Get looks path up on the value of r, with the options of the lookup of r
+// followed by options. The result of a failed lookup fails the same way.
+func (r LookupResult) Get(path string, options ...Option) LookupResult {
+	if r.err != nil {
+		return r
+	}
+	return Get(r.value, path, append([]Option{r.opts}, options...)...)
+}
+
+// Value returns the value found, or nil.
+func (r LookupResult) Value() interface{} {
+	return r.value
+}
+
+// Err returns the error of the lookup, with the NotFound code if nothing was
+// found.
+func (r LookupResult) Err() error {
+	return r.err
+}
+
+// Exists returns whether a value was found.
+func (r LookupResult) Exists() bool {
+	return r.err == nil
+}
+
+// String returns the value as a string. Booleans, numbers and fmt.Stringers
+// are formatted.
+func (r LookupResult) String() string {
+	s, _ := toString(r.value)
+	return s
+}
+
+// Int returns the value as an int64. Floats without fractional part,
+// booleans and strings holding numbers are converted.
+func (r LookupResult) Int() int64 {
+	i, _ := toInt64(r.value)
+	return i
+}
+
+// Float returns the value as a float64. Booleans and strings holding numbers
+// are converted.
+func (r LookupResult) Float() float64 {
+	f, _ := toFloat64(r.value)
+	return f
+}
+
+// Bool returns the value as a bool. Numbers are true if not 0, and strings
+// are parsed by strconv.ParseBool.
+func (r LookupResult) Bool() bool {
+	b, _ := toBool(r.value)
+	return b
+}
+
+// Time returns the value as a time.Time. Strings are parsed with the
+// TimeLayouts of the options of the lookup, and numbers are seconds since the
+// Unix epoch.
+func (r LookupResult) Time() time.Time {
+	t, _ := toTime(r.value, r.opts)
+	return t
+}
+
+// Slice returns the elements of the value if it's a slice or an array, or a
+// slice holding the value otherwise.
+func (r LookupResult) Slice() []interface{} {
+	if r.err != nil {
+		return nil
+	}
+	if s, err := toSlice(r.value); err == nil {
+		return s
+	}
+	return []interface{}{r.value}
+}
+
+// Map returns the value as a map if it's a map or a struct, converted
+// field-wise.
+func (r LookupResult) Map() map[string]interface{} {
+	m, _ := toStringMap(r.value)
+	return m
+}
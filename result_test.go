@@ -0,0 +1,52 @@
+package lookup
+
+import (
+	"encoding/json"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestGet(c *C) {
+	fixture := map[string]interface{}{
+		"name":    "foo",
+		"count":   float64(3),
+		"ratio":   "0.5",
+		"enabled": "true",
+		"id":      json.Number("42"),
+		"created": "2022-02-11T17:18:37Z",
+		"tags":    []interface{}{"a", "b"},
+		"nested":  structFixture,
+	}
+	opts := Options{}
+
+	c.Assert(Get(fixture, "name", opts).String(), Equals, "foo")
+	c.Assert(Get(fixture, "count", opts).Int(), Equals, int64(3))
+	c.Assert(Get(fixture, "count", opts).String(), Equals, "3")
+	c.Assert(Get(fixture, "count", opts).Bool(), Equals, true)
+	c.Assert(Get(fixture, "ratio", opts).Float(), Equals, 0.5)
+	c.Assert(Get(fixture, "ratio", opts).Int(), Equals, int64(0))
+	c.Assert(Get(fixture, "enabled", opts).Bool(), Equals, true)
+	c.Assert(Get(fixture, "id", opts).Int(), Equals, int64(42))
+	c.Assert(Get(fixture, "created", opts).Time(), Equals, time.Date(2022, 2, 11, 17, 18, 37, 0, time.UTC))
+	c.Assert(Get(fixture, "tags", opts).Slice(), DeepEquals, []interface{}{"a", "b"})
+	c.Assert(Get(fixture, "name", opts).Slice(), DeepEquals, []interface{}{"foo"})
+	c.Assert(Get(fixture, "nested", opts).Map()["String"], Equals, "foo")
+	c.Assert(Get(fixture, "nested", opts).Get("Map.foo", opts).Int(), Equals, int64(42))
+	// The options of the lookup are kept, and completed or replaced by those
+	// given.
+	c.Assert(Get(fixture, "nested", WithSplitToken("/")).Get("Map/foo").Int(), Equals, int64(42))
+	c.Assert(Get(fixture, "nested", WithSplitToken("/")).Get("map/FOO", CaseInsensitive()).Int(), Equals, int64(42))
+	c.Assert(Get(fixture, "nested", WithSplitToken("/")).Get("Map/foo", Options{}).Exists(), Equals, false)
+
+	missing := Get(fixture, "missing", opts)
+	c.Assert(missing.Exists(), Equals, false)
+	c.Assert(status.Code(missing.Err()), Equals, codes.NotFound)
+	c.Assert(missing.String(), Equals, "")
+	c.Assert(missing.Slice(), IsNil)
+	c.Assert(missing.Get("foo").Err(), Equals, missing.Err())
+
+	c.Assert(Get(fixture, "nested.Nested", opts).Exists(), Equals, false)
+}
@@ -0,0 +1,84 @@
+package lookup
+
+import (
+	"reflect"
+)
+
+// jsonSchemaDialect is the JSON Schema dialect of the schemas of JSONSchema.
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema returns a JSON Schema, ready to be marshaled, describing the
+// values reachable by lookup paths from the type of sample: structs are
+// objects with a property per field, named as in Paths, maps are objects with
+// additionalProperties, and slices and arrays are arrays with items. Byte
+// slices and times are strings, as encoded by encoding/json. The values of
+// interfaces, and the types already being described in a recursive type,
+// accept anything.
+func JSONSchema(sample interface{}, options ...Option) map[string]interface{} {
+	opts := NewOptions(options...)
+	schema := map[string]interface{}{}
+	if sample != nil {
+		schema = typeSchema(reflect.TypeOf(sample), opts, make(map[reflect.Type]bool))
+	}
+	schema["$schema"] = jsonSchemaDialect
+	return schema
+}
+
+func typeSchema(ty reflect.Type, opts Options, seen map[reflect.Type]bool) map[string]interface{} {
+	ty = derefType(ty)
+	if seen[ty] {
+		return map[string]interface{}{}
+	}
+
+	switch ty.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Struct:
+		if ty == timeType {
+			return map[string]interface{}{"type": "string", "format": "date-time"}
+		}
+		seen[ty] = true
+		defer delete(seen, ty)
+
+		properties := make(map[string]interface{})
+		for _, f := range walkFields(ty, opts) {
+			properties[walkFieldName(f, opts)] = typeSchema(f.Type, opts, seen)
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	case reflect.Map:
+		seen[ty] = true
+		defer delete(seen, ty)
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": typeSchema(ty.Elem(), opts, seen),
+		}
+	case reflect.Slice, reflect.Array:
+		if ty.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		seen[ty] = true
+		defer delete(seen, ty)
+
+		schema := map[string]interface{}{
+			"type":  "array",
+			"items": typeSchema(ty.Elem(), opts, seen),
+		}
+		if ty.Kind() == reflect.Array {
+			schema["minItems"] = ty.Len()
+			schema["maxItems"] = ty.Len()
+		}
+		return schema
+	}
+	return map[string]interface{}{}
+}
@@ -0,0 +1,45 @@
+package lookup
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SchemaFixture struct {
+	Name     string `json:"name"`
+	Count    int
+	Ratio    float64
+	Tags     []string
+	Data     []byte
+	Pair     [2]bool
+	Created  time.Time
+	Labels   map[string]*SchemaFixture
+	Anything interface{}
+	hidden   string
+}
+
+func (s *S) TestJSONSchema(c *C) {
+	schema := JSONSchema(&SchemaFixture{}, Options{TagKey: "json"})
+	c.Assert(schema, DeepEquals, map[string]interface{}{
+		"$schema": jsonSchemaDialect,
+		"type":    "object",
+		"properties": map[string]interface{}{
+			"name":    map[string]interface{}{"type": "string"},
+			"Count":   map[string]interface{}{"type": "integer"},
+			"Ratio":   map[string]interface{}{"type": "number"},
+			"Tags":    map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			"Data":    map[string]interface{}{"type": "string", "contentEncoding": "base64"},
+			"Pair":    map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "boolean"}, "minItems": 2, "maxItems": 2},
+			"Created": map[string]interface{}{"type": "string", "format": "date-time"},
+			"Labels": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": map[string]interface{}{},
+			},
+			"Anything": map[string]interface{}{},
+		},
+		"additionalProperties": false,
+	})
+
+	c.Assert(JSONSchema(nil, Options{}), DeepEquals, map[string]interface{}{"$schema": jsonSchemaDialect})
+}
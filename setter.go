@@ -0,0 +1,60 @@
+package lookup
+
+import (
+	"context"
+	"reflect"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LookupSetter performs a lookup like LookupValue, and returns a function
+// setting the value found in place, e.g. to update a field of the object i
+// points to. The values which LookupValue returns addressable can be set, as
+// can the values of maps, which are set with their key in the map. The value
+// given to the setter is stored as by LookupInto: assigned, converted between
+// numeric types, or decoded through its JSON encoding. As by LookupValue, the
+// indexes of the keys looked up on pointers and interfaces are honored, so
+// "Friends[1]" only sets the second friend. Other values, e.g. aggregated ones,
+// or found on i which isn't a pointer, fail with FailedPrecondition.
+func LookupSetter(i interface{}, path string, options ...Option) (func(value interface{}) error, error) {
+	opts := NewOptions(options...)
+	parts := splitPath(path, &opts)
+	t := newTraversal(context.Background(), opts)
+	t.addressable = true
+	v, err := t.lookup(reflect.ValueOf(i), parts, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	if v.CanSet() {
+		return func(value interface{}) error {
+			return assign(v, value)
+		}, nil
+	}
+
+	// A map value, set through its map.
+	if n := len(parts); n > 0 {
+		parent, err := t.lookup(reflect.ValueOf(i), parts[:n-1], nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		m := getRealValue(parent)
+		if key, index, err := parseIndex(parts[n-1]); err == nil && index == noIndex && m.Kind() == reflect.Map {
+			// The map key matched, with its name, e.g. under
+			// CaseInsensitive.
+			if _, err := t.getValueByName(m, key); err == nil {
+				if k, ok := mapKey(m.Type().Key(), t.match); ok && m.MapIndex(k).IsValid() {
+					return func(value interface{}) error {
+						elem := reflect.New(m.Type().Elem()).Elem()
+						if err := assign(elem, value); err != nil {
+							return err
+						}
+						m.SetMapIndex(k, elem)
+						return nil
+					}, nil
+				}
+			}
+		}
+	}
+	return nil, status.Errorf(codes.FailedPrecondition, "value at path %q can't be set", path)
+}
@@ -0,0 +1,59 @@
+package lookup
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestLookupSetter(c *C) {
+	fixture := MyStruct{
+		Nested: &MyStruct{StructSlice: []*MyStruct{{String: "foo"}}},
+		Map:    map[string]int{"foo": 1},
+	}
+
+	set, err := LookupSetter(&fixture, "Nested.String")
+	c.Assert(err, IsNil)
+	c.Assert(set("bar"), IsNil)
+	c.Assert(fixture.Nested.String, Equals, "bar")
+
+	set, err = LookupSetter(&fixture, "Nested.StructSlice[0].String")
+	c.Assert(err, IsNil)
+	c.Assert(set("baz"), IsNil)
+	c.Assert(fixture.Nested.StructSlice[0].String, Equals, "baz")
+
+	// Map values, with conversion.
+	set, err = LookupSetter(&fixture, "map.FOO", CaseInsensitive())
+	c.Assert(err, IsNil)
+	c.Assert(set(int64(2)), IsNil)
+	c.Assert(fixture.Map, DeepEquals, map[string]int{"foo": 2})
+	c.Assert(status.Code(set("x")), Equals, codes.InvalidArgument)
+
+	// Missing, aggregated and not addressable values.
+	_, err = LookupSetter(&fixture, "Map.bar")
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+	_, err = LookupSetter(&fixture, "Nested.StructSlice.String")
+	c.Assert(status.Code(err), Equals, codes.FailedPrecondition)
+	_, err = LookupSetter(fixture, "String")
+	c.Assert(status.Code(err), Equals, codes.FailedPrecondition)
+
+	// The indexes of keys looked up on pointers address single elements.
+	friends := MyStruct{StructSlice: []*MyStruct{{String: "bob"}, {String: "carol"}}}
+	set, err = LookupSetter(&friends, "StructSlice[1]")
+	c.Assert(err, IsNil)
+	c.Assert(set(&MyStruct{String: "dave"}), IsNil)
+	c.Assert(friends.StructSlice, HasLen, 2)
+	c.Assert(friends.StructSlice[0].String, Equals, "bob")
+	c.Assert(friends.StructSlice[1].String, Equals, "dave")
+	set, err = LookupSetter(&friends, "StructSlice[0].String")
+	c.Assert(err, IsNil)
+	c.Assert(set("alice"), IsNil)
+	c.Assert(friends.StructSlice[0].String, Equals, "alice")
+	c.Assert(friends.StructSlice[1].String, Equals, "dave")
+
+	i := map[string]interface{}{"users": []interface{}{map[string]interface{}{"name": "bob"}}}
+	set, err = LookupSetter(i, "users[0].name")
+	c.Assert(err, IsNil)
+	c.Assert(set("alice"), IsNil)
+	c.Assert(i["users"].([]interface{})[0], DeepEquals, map[string]interface{}{"name": "alice"})
+}
@@ -0,0 +1,49 @@
+package lookup
+
+import (
+	"reflect"
+)
+
+// Stats walks i as Walk does with default options and returns its depth, the
+// number of its nodes and of its leaves, and the number of nodes of each kind,
+// Invalid for the missing values, e.g. under nil pointers. i itself is a node
+// at depth 0, and the depth of the other nodes is the number of segments and
+// indexes of their path, as counted for Options.MaxDepth, so the stats of a
+// payload tell the limits needed to query it.
+func Stats(i interface{}) (depth, nodeCount, leafCount int, byKind map[reflect.Kind]int) {
+	byKind = make(map[reflect.Kind]int)
+	opts := Options{}
+	// Walk only fails with the errors of fn, the context or the expanders,
+	// none of which apply here.
+	_ = Walk(i, func(path string, v reflect.Value) (bool, error) {
+		if d := pathDepth(path, opts); d > depth {
+			depth = d
+		}
+		nodeCount++
+		if isLeaf(v, splitPath(path, &opts), opts) {
+			leafCount++
+		}
+		byKind[v.Kind()]++
+		return true, nil
+	}, opts)
+	return depth, nodeCount, leafCount, byKind
+}
+
+// pathDepth returns the number of steps of path: its keys and its indexes.
+func pathDepth(path string, opts Options) int {
+	if path == "" {
+		return 0
+	}
+	depth := 0
+	s := newPathScanner(path, &opts)
+	for s.scan() {
+		key, index, err := parseIndex(s.part)
+		if err != nil || key != "" {
+			depth++
+		}
+		if err == nil && index != noIndex {
+			depth++
+		}
+	}
+	return depth
+}
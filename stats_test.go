@@ -0,0 +1,34 @@
+package lookup
+
+import (
+	"reflect"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestStats(c *C) {
+	var missing *MyStruct
+	depth, nodes, leaves, byKind := Stats(map[string]interface{}{
+		"a": "foo",
+		"b": []interface{}{1, map[string]interface{}{"c": 2.5}},
+		"d": missing,
+		"e": map[string]int{},
+	})
+	c.Assert(depth, Equals, 3)
+	c.Assert(nodes, Equals, 8)
+	c.Assert(leaves, Equals, 5)
+	c.Assert(byKind, DeepEquals, map[reflect.Kind]int{
+		reflect.Map:     3,
+		reflect.String:  1,
+		reflect.Slice:   1,
+		reflect.Int:     1,
+		reflect.Float64: 1,
+		reflect.Invalid: 1,
+	})
+
+	depth, nodes, leaves, byKind = Stats(nil)
+	c.Assert(depth, Equals, 0)
+	c.Assert(nodes, Equals, 1)
+	c.Assert(leaves, Equals, 1)
+	c.Assert(byKind, DeepEquals, map[reflect.Kind]int{reflect.Invalid: 1})
+}
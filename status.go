@@ -0,0 +1,59 @@
+package lookup
+
+import (
+	"context"
+	"reflect"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PathStatus tells whether a path is found on a value, and what it holds.
+type PathStatus int
+
+const (
+	// PathMissing is the status of a path with a key which isn't found,
+	// including the keys looked up on nil pointers.
+	PathMissing PathStatus = iota
+	// PathNil is the status of a path found with a nil value: a nil pointer,
+	// interface, map or slice, e.g. a map key holding nil.
+	PathNil
+	// PathZero is the status of a path found with the zero value of another
+	// type, e.g. "" or 0, pointers being dereferenced.
+	PathZero
+	// PathSet is the status of a path found with a value which isn't zero.
+	PathSet
+)
+
+// Status returns the status of path on i, telling a key which doesn't exist
+// apart from one holding nil or the zero value, which Lookup can't, e.g. to
+// merge configurations. Only the errors other than NotFound are returned,
+// Options.NilPointersAsAbsent being ignored.
+func Status(i interface{}, path string, options ...Option) (PathStatus, error) {
+	opts := NewOptions(options...)
+	opts.NilPointersAsAbsent = false
+
+	t := newTraversal(context.Background(), opts)
+	v, err := t.lookup(reflect.ValueOf(i), splitPath(path, &opts), nil, 0)
+	switch {
+	case status.Code(err) == codes.NotFound:
+		return PathMissing, nil
+	case err != nil:
+		return PathMissing, err
+	case !v.IsValid() || isNil(v):
+		return PathNil, nil
+	case v.IsZero():
+		return PathZero, nil
+	}
+	return PathSet, nil
+}
+
+// isNil returns whether v is a nil pointer, interface, map, slice, func or
+// channel.
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
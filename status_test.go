@@ -0,0 +1,49 @@
+package lookup
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestStatus(c *C) {
+	config := map[string]interface{}{
+		"name":    "",
+		"port":    8080,
+		"tls":     nil,
+		"labels":  map[string]string(nil),
+		"servers": []interface{}{map[string]interface{}{"host": "a"}, map[string]interface{}{"host": "b"}},
+	}
+	for path, want := range map[string]PathStatus{
+		"name":         PathZero,
+		"port":         PathSet,
+		"tls":          PathNil,
+		"tls.cert":     PathMissing,
+		"labels":       PathNil,
+		"labels.app":   PathMissing,
+		"missing":      PathMissing,
+		"servers.host": PathSet,
+		"servers.port": PathMissing,
+	} {
+		got, err := Status(config, path)
+		c.Assert(err, IsNil, Commentf("%s", path))
+		c.Assert(got, Equals, want, Commentf("%s", path))
+	}
+
+	fixture := MyStruct{Nested: &MyStruct{}}
+	for path, want := range map[string]PathStatus{
+		"Nested":               PathZero,
+		"Nested.Nested":        PathNil,
+		"Nested.Nested.String": PathMissing,
+		"Nested.String":        PathZero,
+		"Interface":            PathNil,
+		"StructSlice":          PathNil,
+	} {
+		got, err := Status(fixture, path, NilPointersAsAbsent())
+		c.Assert(err, IsNil, Commentf("%s", path))
+		c.Assert(got, Equals, want, Commentf("%s", path))
+	}
+
+	_, err := Status(fixture, "String[x]")
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+}
@@ -0,0 +1,68 @@
+package lookup
+
+import (
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+)
+
+var (
+	ipType       = reflect.TypeOf(net.IP(nil))
+	addrType     = reflect.TypeOf(netip.Addr{})
+	prefixType   = reflect.TypeOf(netip.Prefix{})
+	urlType      = reflect.TypeOf(url.URL{})
+	userinfoType = reflect.TypeOf(url.Userinfo{})
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+	bigRatType   = reflect.TypeOf(big.Rat{})
+)
+
+// hasMethodKeys returns whether the keys looked up on the values of type t
+// address their methods, as getMethodValue does, e.g. "Created.Year" on a
+// time.Time or "Addr.IsPrivate" on a net.IP. These standard types hide their
+// data in unexported fields, or aren't structs. The exported fields of the
+// structs, e.g. "Endpoint.Host" on a url.URL, come first.
+func hasMethodKeys(t reflect.Type) bool {
+	switch t {
+	case timeType, durationType, ipType, addrType, prefixType, urlType, userinfoType, bigIntType, bigFloatType, bigRatType:
+		return true
+	}
+	return false
+}
+
+// fromBig returns the big.Int, big.Float or big.Rat v, or its pointer, as an
+// int64, or as its string if it overflows, or as a float64, so it's
+// converted as the other numbers are. Other values are returned as is.
+func fromBig(v interface{}) interface{} {
+	switch n := v.(type) {
+	case big.Int:
+		return fromBig(&n)
+	case big.Float:
+		return fromBig(&n)
+	case big.Rat:
+		return fromBig(&n)
+	case *big.Int:
+		if n == nil {
+			return v
+		}
+		if n.IsInt64() {
+			return n.Int64()
+		}
+		return n.String()
+	case *big.Float:
+		if n == nil {
+			return v
+		}
+		f, _ := n.Float64()
+		return f
+	case *big.Rat:
+		if n == nil {
+			return v
+		}
+		f, _ := n.Float64()
+		return f
+	}
+	return v
+}
@@ -0,0 +1,83 @@
+package lookup
+
+import (
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+type Endpoint struct {
+	Created time.Time
+	Timeout time.Duration
+	Addr    net.IP
+	Peer    netip.Addr
+	URL     *url.URL
+	Amount  *big.Int
+	Ratio   big.Rat
+	IPs     []net.IP
+}
+
+func (s *S) TestLookup_StdlibTypes(c *C) {
+	u, err := url.Parse("https://bob@example.com:8443/api?q=go&q=lookup")
+	c.Assert(err, IsNil)
+	amount, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	e := Endpoint{
+		Created: time.Date(2021, time.March, 4, 5, 6, 7, 0, time.UTC),
+		Timeout: 90 * time.Second,
+		Addr:    net.ParseIP("10.0.0.1"),
+		Peer:    netip.MustParseAddr("::1"),
+		URL:     u,
+		Amount:  amount,
+		Ratio:   *big.NewRat(1, 4),
+		IPs:     []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("8.8.8.8")},
+	}
+	for path, want := range map[string]interface{}{
+		"Created.Year":      2021,
+		"Created.month":     time.March,
+		"Timeout.Seconds":   90.0,
+		"Addr.String":       "10.0.0.1",
+		"Addr.IsPrivate":    true,
+		"Peer.IsLoopback":   true,
+		"URL.Host":          "example.com:8443",
+		"URL.Hostname":      "example.com",
+		"URL.Port":          "8443",
+		"URL.Query.q":       []string{"go", "lookup"},
+		"URL.User.Username": "bob",
+		"Amount.Sign":       1,
+		"Amount.String":     "123456789012345678901234567890",
+		"Ratio.String":      "1/4",
+		"IPs.IsLoopback":    []bool{true, false},
+	} {
+		value, err := Lookup(e, path, CaseInsensitive())
+		c.Assert(err, IsNil, Commentf("%s", path))
+		c.Assert(value, DeepEquals, want, Commentf("%s", path))
+	}
+
+	_, err = Lookup(e, "Amount.Missing")
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+	// Only the methods without arguments are addressed.
+	_, err = Lookup(e, "Amount.Add")
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	// The leaves are converted by their string forms or their values.
+	str, err := LookupString(e, "Amount")
+	c.Assert(err, IsNil)
+	c.Assert(str, Equals, "123456789012345678901234567890")
+	str, err = LookupString(e, "URL")
+	c.Assert(err, IsNil)
+	c.Assert(str, Equals, "https://bob@example.com:8443/api?q=go&q=lookup")
+	f, err := LookupFloat(e, "Ratio")
+	c.Assert(err, IsNil)
+	c.Assert(f, Equals, 0.25)
+	_, err = LookupInt(e, "Amount")
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+	n, err := LookupInt(Endpoint{Amount: big.NewInt(42)}, "Amount")
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(42))
+}
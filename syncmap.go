@@ -0,0 +1,63 @@
+package lookup
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var syncMapType = reflect.TypeOf((*sync.Map)(nil)).Elem()
+
+// getSyncMapValue returns the value stored under key in the sync.Map v. If
+// there is none, the keys are matched through their string form as with map
+// keys.
+func getSyncMapValue(v reflect.Value, key string, opts Options) (reflect.Value, error) {
+	m := pointerTo(v).(*sync.Map)
+	if value, ok := m.Load(key); ok {
+		return getRealValue(reflect.ValueOf(value)), nil
+	}
+
+	if len(opts.MatchFunctions) > 0 {
+		var keys []interface{}
+		m.Range(func(k, _ interface{}) bool {
+			keys = append(keys, k)
+			return true
+		})
+		// Sort the keys so the matching key doesn't depend on the map
+		// iteration order.
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		i, err := matchName(len(keys), func(i int) []string {
+			return []string{fmt.Sprint(keys[i])}
+		}, key, opts)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if i != -1 {
+			if value, ok := m.Load(keys[i]); ok {
+				return getRealValue(reflect.ValueOf(value)), nil
+			}
+		}
+	}
+
+	return reflect.Value{}, status.Errorf(codes.NotFound, "key %q not found", key)
+}
+
+// syncMapSnapshot returns a map[interface{}]interface{} holding the entries of
+// v if v is, or points to, a sync.Map, so it can be aggregated over.
+func syncMapSnapshot(v reflect.Value) (reflect.Value, bool) {
+	v = getRealValue(v)
+	if !v.IsValid() || v.Type() != syncMapType {
+		return reflect.Value{}, false
+	}
+
+	snapshot := make(map[interface{}]interface{})
+	pointerTo(v).(*sync.Map).Range(func(k, value interface{}) bool {
+		snapshot[k] = value
+		return true
+	})
+	return reflect.ValueOf(snapshot), true
+}
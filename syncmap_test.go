@@ -0,0 +1,39 @@
+package lookup
+
+import (
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestLookup_SyncMap(c *C) {
+	type Registry struct {
+		Servers *sync.Map
+	}
+	servers := &sync.Map{}
+	servers.Store("Alpha", &MyStruct{String: "foo"})
+	servers.Store("beta", &MyStruct{String: "bar"})
+	fixture := Registry{Servers: servers}
+
+	value, err := Lookup(fixture, "Servers.Alpha.String", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "foo")
+
+	value, err = Lookup(fixture, "servers.alpha.string", Options{MatchFunctions: []MatchFunc{strings.ToLower}})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "foo")
+
+	value, err = Lookup(fixture, "Servers.String", Options{KeepMapKeys: true})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, map[interface{}]string{"Alpha": "foo", "beta": "bar"})
+
+	value, err = Lookup(fixture, "Servers[*].String", Options{KeepMapKeys: true})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, map[interface{}]string{"Alpha": "foo", "beta": "bar"})
+
+	_, err = Lookup(fixture, "Servers.gamma.String", Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+}
@@ -0,0 +1,40 @@
+package lookup
+
+import (
+	"text/template"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TemplateFuncs returns the functions to look up paths from templates, with
+// the semantics of Lookup and options:
+//
+//	lookup VALUE PATH           the value at PATH, failing the template if not found
+//	lookupOr VALUE PATH DEFAULT the value at PATH, or DEFAULT if not found
+//	exists VALUE PATH           whether PATH is found
+//
+// e.g. {{ lookup . "Spec.Replicas" }}. The returned map can be converted into
+// an html/template.FuncMap.
+func TemplateFuncs(options ...Option) template.FuncMap {
+	opts := NewOptions(options...)
+	return template.FuncMap{
+		"lookup": func(i interface{}, path string) (interface{}, error) {
+			return Lookup(i, path, opts)
+		},
+		"lookupOr": func(i interface{}, path string, def interface{}) (interface{}, error) {
+			value, err := Lookup(i, path, opts)
+			if status.Code(err) == codes.NotFound || (err == nil && value == nil) {
+				return def, nil
+			}
+			return value, err
+		},
+		"exists": func(i interface{}, path string) (bool, error) {
+			value, err := Lookup(i, path, opts)
+			if status.Code(err) == codes.NotFound {
+				return false, nil
+			}
+			return value != nil, err
+		},
+	}
+}
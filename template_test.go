@@ -0,0 +1,25 @@
+package lookup
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestTemplateFuncs(c *C) {
+	funcs := TemplateFuncs(Options{MatchFunctions: []MatchFunc{strings.ToLower}, ExpandStringAsJSON: true})
+	tmpl, err := template.New("").Funcs(funcs).Parse(
+		`{{ lookup . "string" }} {{ lookup . "JSONString.struct.substring" }} {{ lookupOr . "Missing" "none" }} {{ exists . "Map.foo" }} {{ exists . "Map.bar" }}`)
+	c.Assert(err, IsNil)
+
+	var out bytes.Buffer
+	err = tmpl.Execute(&out, structFixture)
+	c.Assert(err, IsNil)
+	c.Assert(out.String(), Equals, "foo Abcd none true false")
+
+	tmpl, err = template.New("").Funcs(funcs).Parse(`{{ lookup . "Missing" }}`)
+	c.Assert(err, IsNil)
+	c.Assert(tmpl.Execute(&out, structFixture), NotNil)
+}
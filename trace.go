@@ -0,0 +1,35 @@
+package lookup
+
+import (
+	"context"
+	"reflect"
+)
+
+// LookupTrace is like Lookup, but also returns the trace of the resolution:
+// the event reported after each segment is resolved, in order, telling the
+// kind of the value it was resolved on or to, the field or key it matched,
+// and whether the value was expanded or aggregated. The trace ends with the
+// segment the lookup failed on, if any. Options.OnSegment, if set, is still
+// called. Options.Parallelism is ignored, so the trace is in order.
+func LookupTrace(i interface{}, path string, options ...Option) (interface{}, []SegmentEvent, error) {
+	opts := NewOptions(options...)
+	opts.Parallelism = 0
+	var trace []SegmentEvent
+	onSegment := opts.OnSegment
+	opts.OnSegment = func(e SegmentEvent) error {
+		if !e.Before {
+			trace = append(trace, e)
+		}
+		if onSegment != nil {
+			return onSegment(e)
+		}
+		return nil
+	}
+
+	t := newTraversal(context.Background(), opts)
+	v, err := t.lookup(reflect.ValueOf(i), splitPath(path, &opts), nil, 0)
+	if err != nil || !v.IsValid() {
+		return nil, trace, err
+	}
+	return resultInterface(v, &opts), trace, nil
+}
@@ -0,0 +1,50 @@
+package lookup
+
+import (
+	"reflect"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestLookupTrace(c *C) {
+	value, trace, err := LookupTrace(structFixture, "map.FOO", CaseInsensitive())
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 42)
+	c.Assert(trace, DeepEquals, []SegmentEvent{
+		{Prefix: "", Segment: "map", Kind: reflect.Map, Match: "Map"},
+		{Prefix: "map", Segment: "FOO", Kind: reflect.Int, Match: "foo"},
+	})
+	c.Assert(trace[0].String(), Equals, `after map on "" (map): matched "Map"`)
+}
+
+func (s *S) TestLookupTrace_Expanded(c *C) {
+	value, trace, err := LookupTrace(structFixture, "JSONString.Struct.Substring", ExpandJSON())
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "Abcd")
+	c.Assert(trace, HasLen, 3)
+	c.Assert(trace[0].Expanded, Equals, false)
+	c.Assert(trace[1].Expanded, Equals, true)
+	c.Assert(trace[1].Match, Equals, "Struct")
+}
+
+func (s *S) TestLookupTrace_Aggregated(c *C) {
+	var before int
+	opts := Options{Parallelism: 2, OnSegment: func(e SegmentEvent) error {
+		if e.Before {
+			before++
+		}
+		return nil
+	}}
+	_, trace, err := LookupTrace(structFixture, "StructSlice.String", opts)
+	c.Assert(err, IsNil)
+	c.Assert(trace[1], DeepEquals, SegmentEvent{Prefix: "StructSlice", Segment: "String", Kind: reflect.Slice, Aggregated: true})
+	c.Assert(trace[1].String(), Equals, `after String on "StructSlice" (slice): aggregated`)
+	c.Assert(before, Equals, len(trace))
+
+	_, trace, err = LookupTrace(structFixture, "Missing")
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+	c.Assert(trace, HasLen, 1)
+	c.Assert(trace[0].Err, Equals, err)
+}
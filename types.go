@@ -0,0 +1,230 @@
+package lookup
+
+import (
+	"reflect"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TypeOf returns the type of the value Lookup would return for path on a
+// value of type ty, resolving the segments of path with options as Lookup does.
+// As Lookup dereferences the values it finds, the returned type is never a
+// pointer. Keys looked up on maps are assumed to exist, and the type of the
+// values found in interfaces, or expanded from strings, is only known at
+// runtime, so TypeOf returns their interface type. As with Lookup, arrays
+// can't be indexed nor aggregated over.
+func TypeOf(ty reflect.Type, path string, options ...Option) (reflect.Type, error) {
+	opts := NewOptions(options...)
+	return typeOfPath(ty, splitPath(path, &opts), 0, opts)
+}
+
+// typeOfPath returns the type of the value found at path on a value of type
+// ty, e.g. the type of the elements of an aggregation. pos is the position of
+// path in the path of TypeOf, for the errors.
+func typeOfPath(ty reflect.Type, path []string, pos int, opts Options) (reflect.Type, error) {
+	return typeOfPathSeen(ty, path, pos, opts, nil)
+}
+
+// ValidatePath returns an error if path can't be resolved on the type of
+// sample, e.g. if a field doesn't exist or an index is applied to something
+// else than a slice or a map, arrays included, as TypeOf does. The error tells the position of the
+// faulty segment, so user-configured paths can be rejected upfront.
+func ValidatePath(sample interface{}, path string, options ...Option) error {
+	opts := NewOptions(options...)
+	if sample == nil {
+		return status.Errorf(codes.InvalidArgument, "no sample to validate path %q against", path)
+	}
+	_, err := TypeOf(reflect.TypeOf(sample), path, opts)
+	return err
+}
+
+// typeOfPathSeen is typeOfPath tracking the lists aggregated over since the
+// last consumed segment, so recursive types like `type T []T` don't recurse
+// forever.
+func typeOfPathSeen(ty reflect.Type, path []string, pos int, opts Options, seen map[reflect.Type]bool) (reflect.Type, error) {
+	for i, part := range path {
+		key, index, err := parseIndex(part)
+		if err != nil {
+			return nil, segmentError(pos+i, err)
+		}
+
+		ty = derefType(ty)
+		switch ty.Kind() {
+		case reflect.Interface:
+			return ty, nil
+		case reflect.Struct:
+			f, ok, err := structField(ty, key, opts)
+			if err != nil {
+				return nil, segmentError(pos+i, err)
+			}
+			if !ok {
+				return nil, segmentError(pos+i, status.Errorf(codes.NotFound, "field %q not found in %s", key, ty))
+			}
+			ty = f.Type
+		case reflect.Map:
+			ty = ty.Elem()
+		case reflect.Slice:
+			if opts.Strict {
+				return nil, segmentError(pos+i, status.Errorf(codes.NotFound, "key %q not found; use %s[*] to aggregate over a %s", key, key, ty.Kind()))
+			}
+			if i > 0 {
+				seen = nil
+			}
+			if seen[ty] {
+				return nil, segmentError(pos+i, status.Errorf(codes.NotFound, "key %q not found in recursive %s", key, ty))
+			}
+			elem, err := typeOfPathSeen(ty.Elem(), path[i:], pos+i, opts, markSeen(seen, ty))
+			if err != nil {
+				return nil, err
+			}
+			return aggregatedType(elem, opts), nil
+		case reflect.String:
+			if canExpandType(opts) {
+				return interfaceType, nil
+			}
+			fallthrough
+		default:
+			return nil, segmentError(pos+i, status.Errorf(codes.NotFound, "key %q can't be looked up on a %s", key, ty))
+		}
+
+		if index == noIndex {
+			continue
+		}
+		list := derefType(ty)
+		if list.Kind() == reflect.Interface || (list.Kind() == reflect.String && canExpandType(opts)) {
+			return interfaceType, nil
+		}
+		if index == filterIndex && list.Kind() != reflect.Map {
+			return nil, segmentError(pos+i, status.Errorf(codes.InvalidArgument, "key %q is not a map", key))
+		}
+		if index == wildcardIndex || index == filterIndex {
+			if list.Kind() != reflect.Slice && list.Kind() != reflect.Map {
+				return nil, segmentError(pos+i, status.Errorf(codes.InvalidArgument, "key %q is not a list or a map", key))
+			}
+			elem, err := typeOfPath(list.Elem(), path[i+1:], pos+i+1, opts)
+			if err != nil {
+				return nil, err
+			}
+			if list.Kind() == reflect.Map && opts.KeepMapKeys {
+				return reflect.MapOf(list.Key(), elem), nil
+			}
+			return aggregatedType(elem, opts), nil
+		}
+		if list.Kind() != reflect.Slice {
+			return nil, segmentError(pos+i, status.Errorf(codes.InvalidArgument, "key %q is not a list", key))
+		}
+		ty = list.Elem()
+	}
+	return derefType(ty), nil
+}
+
+// aggregatedType returns the type of the aggregation of values of type elem,
+// as merged by mergeValue.
+func aggregatedType(elem reflect.Type, opts Options) reflect.Type {
+	if elem.Kind() == reflect.Slice && !opts.PreserveNesting {
+		return elem
+	}
+	return reflect.SliceOf(elem)
+}
+
+// canExpandType returns whether strings may be expanded into values of types
+// only known at runtime.
+func canExpandType(opts Options) bool {
+	return opts.ExpandStringAsJSON || opts.ExpandBase64JSON || opts.ExpandStringAsXML || len(opts.Expanders) > 0
+}
+
+func derefType(ty reflect.Type) reflect.Type {
+	// A recursive pointer type, e.g. `type P *P`, is left as is.
+	for ty.Kind() == reflect.Ptr && ty.Elem() != ty {
+		ty = ty.Elem()
+	}
+	return ty
+}
+
+// segmentError returns err, a status error, prefixed with the position of the
+// segment it's about.
+func segmentError(pos int, err error) error {
+	s := status.Convert(err)
+	return status.Errorf(s.Code(), "segment %d: %s", pos, s.Message())
+}
+
+// mapKeyPlaceholder stands for the keys of maps in the paths returned by
+// Paths.
+const mapKeyPlaceholder = "{}"
+
+// Paths returns the paths of all the leaves reachable from the type of
+// sample, in the order of the fields, with key[*] for the elements of slices
+// and {} for the keys of maps, e.g. "StructSlice[*].Map.{}". The {} must be
+// replaced by a key to look a path up; ValidatePath accepts it, as any key of
+// a map. The elements of a root slice, or of a slice in a slice, are
+// aggregated over without index, e.g. "Name" for a []Item, and arrays are
+// leaves, as Lookup indexes neither. Fields are named by Options.TagKey, if
+// any, or by their Go name, and embedded structs are only listed through their
+// promoted fields, unless Options.NoPromotedFields is set. The values of
+// interfaces, and the types already being enumerated in a recursive type, are
+// leaves.
+func Paths(sample interface{}, options ...Option) []string {
+	opts := NewOptions(options...)
+	if sample == nil {
+		return nil
+	}
+	var paths []string
+	appendPaths(&paths, reflect.TypeOf(sample), nil, opts, make(map[reflect.Type]bool))
+	return paths
+}
+
+func appendPaths(paths *[]string, ty reflect.Type, path []string, opts Options, seen map[reflect.Type]bool) {
+	ty = derefType(ty)
+	leaf := func() {
+		if len(path) > 0 {
+			*paths = append(*paths, joinSegments(path, opts))
+		}
+	}
+	if seen[ty] {
+		leaf()
+		return
+	}
+
+	switch ty.Kind() {
+	case reflect.Struct:
+		seen[ty] = true
+		defer delete(seen, ty)
+
+		fields := walkFields(ty, opts)
+		for _, f := range fields {
+			appendPaths(paths, f.Type, append(path[:len(path):len(path)], walkFieldName(f, opts)), opts, seen)
+		}
+		if len(fields) == 0 {
+			leaf()
+		}
+	case reflect.Slice:
+		if ty.Elem().Kind() == reflect.Uint8 {
+			leaf()
+			return
+		}
+		seen[ty] = true
+		defer delete(seen, ty)
+
+		// A segment has a single index, so the elements of the root slice and
+		// of the indexed slices are aggregated over by the next segment.
+		if len(path) == 0 || strings.HasSuffix(path[len(path)-1], indexCloseChar) {
+			if opts.Strict {
+				leaf()
+				return
+			}
+			appendPaths(paths, ty.Elem(), path, opts, seen)
+			return
+		}
+		last := indexOpenChar + wildcardChar + indexCloseChar
+		path = append(path[:len(path)-1:len(path)-1], path[len(path)-1]+last)
+		appendPaths(paths, ty.Elem(), path, opts, seen)
+	case reflect.Map:
+		seen[ty] = true
+		defer delete(seen, ty)
+		appendPaths(paths, ty.Elem(), append(path[:len(path):len(path)], mapKeyPlaceholder), opts, seen)
+	default:
+		leaf()
+	}
+}
@@ -0,0 +1,163 @@
+package lookup
+
+import (
+	"reflect"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestTypeOf(c *C) {
+	ty := reflect.TypeOf(MyStruct{})
+	tests := map[string]reflect.Type{
+		"String":                         reflect.TypeOf(""),
+		"Map":                            reflect.TypeOf(map[string]int{}),
+		"Map.foo":                        reflect.TypeOf(0),
+		"Nested":                         ty,
+		"Nested.String":                  reflect.TypeOf(""),
+		"StructSlice":                    reflect.TypeOf([]*MyStruct{}),
+		"StructSlice[0]":                 ty,
+		"StructSlice.String":             reflect.TypeOf([]string{}),
+		"StructSlice[*].StructSlice":     reflect.TypeOf([]*MyStruct{}),
+		"StructSlice.StructSlice.String": reflect.TypeOf([]string{}),
+		"StructSlice[0].Map.foo":         reflect.TypeOf(0),
+		"Interface":                      interfaceType,
+		"Interface.foo":                  interfaceType,
+	}
+	for path, want := range tests {
+		got, err := TypeOf(ty, path, Options{})
+		c.Assert(err, IsNil, Commentf("path %q", path))
+		c.Assert(got, Equals, want, Commentf("path %q", path))
+	}
+
+	got, err := TypeOf(reflect.TypeOf(&MyStruct{}), "nested.string", Options{MatchFunctions: []MatchFunc{strings.ToLower}})
+	c.Assert(err, IsNil)
+	c.Assert(got, Equals, reflect.TypeOf(""))
+
+	got, err = TypeOf(ty, "JSONString.foo", Options{ExpandStringAsJSON: true})
+	c.Assert(err, IsNil)
+	c.Assert(got, Equals, interfaceType)
+
+	_, err = TypeOf(ty, "Nested.Missing", Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+	c.Assert(err, ErrorMatches, ".*segment 1: .*Missing.*")
+
+	_, err = TypeOf(ty, "String[0]", Options{})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+
+	_, err = TypeOf(ty, "String.foo", Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	_, err = TypeOf(ty, "StructSlice.String", Options{Strict: true})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	// Arrays are neither indexed nor aggregated over, as by Lookup.
+	type Point struct{ X int }
+	arrays := struct {
+		Points [1]Point
+		Map    map[string][1]Point
+	}{Map: map[string][1]Point{"a": {}}}
+	for _, path := range []string{"Points", "Points.X", "Points[0]", "Points[*].X", "Map.a[0]", "Map[*].X"} {
+		_, wantErr := Lookup(arrays, path, Options{})
+		_, err = TypeOf(reflect.TypeOf(arrays), path, Options{})
+		c.Assert(status.Code(err), Equals, status.Code(wantErr), Commentf("path %q", path))
+	}
+
+	type Recursive []Recursive
+	_, err = TypeOf(reflect.TypeOf(Recursive{}), "foo", Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+	type Pointer *Pointer
+	_, err = TypeOf(reflect.TypeOf(Pointer(nil)), "foo", Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	// The empty aggregations have the type TypeOf resolves.
+	empty := MyStruct{StructSlice: []*MyStruct{}}
+	for path, options := range map[string]Options{
+		"StructSlice.StructSlice.String": {},
+		"StructSlice.Map.foo":            {},
+		"StructSlice.JSONString.foo":     {ExpandStringAsJSON: true},
+		"StructSlice.StructSlice":        {PreserveNesting: true},
+	} {
+		want, err := TypeOf(ty, path, options)
+		c.Assert(err, IsNil, Commentf("path %q", path))
+		value, err := Lookup(empty, path, options)
+		c.Assert(err, IsNil, Commentf("path %q", path))
+		c.Assert(reflect.TypeOf(value), Equals, want, Commentf("path %q", path))
+	}
+}
+
+func (s *S) TestValidatePath(c *C) {
+	c.Assert(ValidatePath(MyStruct{}, "StructSlice[0].Nested.Map.foo", Options{}), IsNil)
+	c.Assert(ValidatePath(&MyStruct{}, "StructSlice.String", Options{}), IsNil)
+
+	err := ValidatePath(MyStruct{}, "StructSlice[0].Nested.Strin", Options{})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+	c.Assert(err, ErrorMatches, `.*segment 2: field "Strin" not found.*`)
+
+	err = ValidatePath(MyStruct{}, "Map[0]", Options{})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+
+	// The paths Lookup fails on with arrays are rejected.
+	sample := struct{ Array [2]MyStruct }{}
+	c.Assert(ValidatePath(sample, "Array", Options{}), IsNil)
+	for _, path := range []string{"Array[0]", "Array[*].String", "Array.String"} {
+		_, wantErr := Lookup(sample, path, Options{})
+		c.Assert(wantErr, NotNil, Commentf("path %q", path))
+		err = ValidatePath(sample, path, Options{})
+		c.Assert(status.Code(err), Equals, status.Code(wantErr), Commentf("path %q", path))
+	}
+
+	err = ValidatePath(nil, "String", Options{})
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+}
+
+func (s *S) TestPaths(c *C) {
+	type Base struct {
+		ID string `json:"id"`
+	}
+	type Item struct {
+		Base
+		Tags   []string          `json:"tags"`
+		Labels map[string]string `json:"labels"`
+		Data   []byte            `json:"data"`
+		Parent *Item             `json:"parent"`
+		Any    interface{}       `json:"any"`
+		hidden string
+	}
+
+	c.Assert(Paths(Item{}, Options{}), DeepEquals, []string{
+		"Tags[*]", "Labels.{}", "Data", "Parent", "Any", "ID",
+	})
+	c.Assert(Paths([]*Item{}, Options{TagKey: "json"}), DeepEquals, []string{
+		"tags[*]", "labels.{}", "data", "parent", "any", "id",
+	})
+	c.Assert(Paths(Item{}, Options{NoPromotedFields: true}), DeepEquals, []string{
+		"Base.ID", "Tags[*]", "Labels.{}", "Data", "Parent", "Any",
+	})
+
+	type Nested struct {
+		Name    string
+		Matrix  [][]Item
+		Numbers [][]int
+		Array   [2]Item
+		Lists   map[string][]string
+		Maps    []map[string]int
+	}
+	c.Assert(Paths(Nested{}, Options{}), DeepEquals, []string{
+		"Name", "Matrix[*].Tags[*]", "Matrix[*].Labels.{}", "Matrix[*].Data", "Matrix[*].Parent", "Matrix[*].Any", "Matrix[*].ID",
+		"Numbers[*]", "Array", "Lists.{}[*]", "Maps[*].{}",
+	})
+	c.Assert(Paths([][]Nested{}, Options{Strict: true}), HasLen, 0)
+
+	// Every path is valid, once the key placeholders are replaced.
+	for _, sample := range []interface{}{Item{}, []*Item{}, Nested{}, []Nested{}, [][]Nested{}, map[string]Nested{}} {
+		for _, options := range []Options{{}, {TagKey: "json"}, {NoPromotedFields: true}} {
+			for _, path := range Paths(sample, options) {
+				c.Assert(ValidatePath(sample, path, options), IsNil, Commentf("path %q of %T", path, sample))
+				c.Assert(ValidatePath(sample, strings.ReplaceAll(path, "{}", "key"), options), IsNil, Commentf("path %q of %T", path, sample))
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package lookup
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	valuerType   = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+// unwrapValue returns the value wrapped by v, or pointed to by v, if it's a
+// wrapper type unwrapped according to opts, or v itself.
+func unwrapValue(v reflect.Value, opts Options) (reflect.Value, error) {
+	r := getRealValue(v)
+	if !r.IsValid() {
+		return v, nil
+	}
+	if opts.UnwrapWrappers {
+		if w, ok := fromWrapperspb(r); ok {
+			return w, nil
+		}
+	}
+	if opts.ConvertTimes {
+		if t, ok := fromTimepb(r); ok {
+			return t, nil
+		}
+	}
+	if opts.UnwrapSQLNull {
+		if n, ok := fromSQLNull(r); ok {
+			return n, nil
+		}
+	}
+	if opts.UnwrapValuers {
+		return fromValuer(v, r)
+	}
+	return v, nil
+}
+
+// fromValuer returns the result of the Value method of r if it implements
+// driver.Valuer, or v.
+func fromValuer(v, r reflect.Value) (reflect.Value, error) {
+	var valuer driver.Valuer
+	switch {
+	case !r.CanInterface():
+		return v, nil
+	case r.Type().Implements(valuerType):
+		valuer = r.Interface().(driver.Valuer)
+	case reflect.PtrTo(r.Type()).Implements(valuerType):
+		valuer = pointerTo(r).(driver.Valuer)
+	default:
+		return v, nil
+	}
+
+	out, err := valuer.Value()
+	if err != nil {
+		return reflect.Value{}, status.Errorf(codes.Internal, "cannot get the value of %s: %v", r.Type(), err)
+	}
+	if out == nil {
+		return reflect.Zero(interfaceType), nil
+	}
+	return reflect.ValueOf(out), nil
+}
+
+// fromSQLNull returns the value held by the database/sql Null type v, such as
+// sql.NullString, or a nil interface if it's not valid. It returns false if v
+// isn't a Null type.
+func fromSQLNull(v reflect.Value) (reflect.Value, bool) {
+	t := v.Type()
+	if t.PkgPath() != "database/sql" || !strings.HasPrefix(t.Name(), "Null") || t.NumField() != 2 {
+		return reflect.Value{}, false
+	}
+	valid := v.Field(1)
+	if t.Field(1).Name != "Valid" || valid.Kind() != reflect.Bool {
+		return reflect.Value{}, false
+	}
+	if !valid.Bool() {
+		return reflect.Zero(interfaceType), true
+	}
+	return v.Field(0), true
+}
+
+// getMethodValue returns the result of calling the method named key, which
+// must take no argument and return a single value, of v. It's used to address
+// the properties of values such as time.Time, e.g. "Created.Unix". The
+// methods of *v are included, e.g. "Amount.Int64" on a big.Int, called on a
+// copy of v if it isn't addressable. The method name is matched like a field
+// name.
+func getMethodValue(v reflect.Value, key string, opts Options) (reflect.Value, bool, error) {
+	if v.CanAddr() {
+		v = v.Addr()
+	} else {
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		v = p
+	}
+	t := v.Type()
+	var methods []int
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i).Type
+		// The receiver is the first argument.
+		if m.NumIn() == 1 && m.NumOut() == 1 {
+			methods = append(methods, i)
+		}
+	}
+
+	i, err := matchName(len(methods), func(i int) []string {
+		return []string{t.Method(methods[i]).Name}
+	}, key, opts)
+	if i == -1 || err != nil {
+		return reflect.Value{}, false, err
+	}
+	return v.Method(methods[i]).Call(nil)[0], true, nil
+}
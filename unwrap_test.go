@@ -0,0 +1,84 @@
+package lookup
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestUnwrapSQLNull(c *C) {
+	type Row struct {
+		Name    sql.NullString
+		Age     sql.NullInt64
+		Deleted *sql.NullTime
+	}
+	deleted := time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)
+	fixture := Row{
+		Name:    sql.NullString{String: "foo", Valid: true},
+		Age:     sql.NullInt64{},
+		Deleted: &sql.NullTime{Time: deleted, Valid: true},
+	}
+	opts := Options{UnwrapSQLNull: true}
+
+	value, err := Lookup(fixture, "Name", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "foo")
+
+	value, err = Lookup(fixture, "Age", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, IsNil)
+
+	value, err = Lookup(fixture, "Deleted.Year", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, 2021)
+
+	value, err = Lookup([]Row{fixture, fixture}, "Name", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, []string{"foo", "foo"})
+
+	value, err = Lookup(fixture, "Name", Options{})
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, fixture.Name)
+}
+
+type money struct {
+	cents int64
+}
+
+func (m money) Value() (driver.Value, error) {
+	if m.cents < 0 {
+		return nil, errors.New("negative amount")
+	}
+	return fmt.Sprintf(`{"units": %d, "cents": %d}`, m.cents/100, m.cents%100), nil
+}
+
+func (s *S) TestUnwrapValuers(c *C) {
+	type Order struct {
+		Total money
+		Note  sql.NullString
+	}
+	fixture := Order{Total: money{cents: 1234}}
+	opts := Options{UnwrapValuers: true, ExpandStringAsJSON: true}
+
+	value, err := Lookup(fixture, "Total", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, `{"units": 12, "cents": 34}`)
+
+	value, err = Lookup(fixture, "Total.cents", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, float64(34))
+
+	value, err = Lookup(fixture, "Note", opts)
+	c.Assert(err, IsNil)
+	c.Assert(value, IsNil)
+
+	fixture.Total.cents = -1
+	_, err = Lookup(fixture, "Total", opts)
+	c.Assert(status.Code(err), Equals, codes.Internal)
+}
@@ -0,0 +1,227 @@
+package lookup
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WalkFunc is called by Walk for every node, with its lookup path and its
+// value, which is invalid for the missing values, e.g. under nil pointers. It
+// returns whether the children of the node must be walked. An error stops the
+// walk, and is returned by Walk.
+type WalkFunc func(path string, v reflect.Value) (descend bool, err error)
+
+// Walk visits i and the values reachable from it, depth-first, calling fn with
+// their lookup path, "" for i itself. The values are dereferenced, unwrapped
+// and expanded according to options, as by Lookup. The fields of structs are
+// visited in order, the keys of maps in sorted order, and the elements of
+// slices by index, except for the slices held by slices, whose elements can't
+// be addressed by a path. The values of a cycle are only walked once.
+func Walk(i interface{}, fn WalkFunc, options ...Option) error {
+	return WalkContext(context.Background(), i, fn, options...)
+}
+
+// WalkContext is like Walk, but stops with the error of ctx, converted to a
+// status error, as soon as ctx is done.
+func WalkContext(ctx context.Context, i interface{}, fn WalkFunc, options ...Option) error {
+	opts := NewOptions(options...)
+	t := newTraversal(ctx, opts)
+	return t.walk(reflect.ValueOf(i), nil, func(path []string, v reflect.Value) (bool, error) {
+		return fn(joinSegments(path, opts), v)
+	})
+}
+
+// walkFunc is called by walk for every node, with its path and its value. It
+// returns whether the children of the node must be walked.
+type walkFunc func(path []string, v reflect.Value) (bool, error)
+
+// walk visits v and the values reachable from it, depth-first, calling fn
+// with their lookup path, prefixed by path. The values are dereferenced,
+// unwrapped and expanded as by lookup. The fields of structs are visited in
+// order, named as in Paths, the keys of maps in sorted order, and the elements
+// of slices by index, except for the slices held by slices, see isLeaf. The
+// values already being visited, e.g. in a cyclic list, are visited but not
+// walked again.
+func (t *traversal) walk(v reflect.Value, path []string, fn walkFunc) error {
+	if err := t.checkContext(); err != nil {
+		return err
+	}
+
+	var ptr visit
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		ptr = visit{ptr: v.Pointer(), typ: v.Type()}
+	}
+	v, err := t.node(v, path)
+	if err != nil {
+		return err
+	}
+
+	// The maps and slices may hold themselves through interfaces, e.g.
+	// m["self"] = m. The slices sharing an array are told apart by their
+	// type and length.
+	var list visit
+	if (v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && !v.IsNil() {
+		list = visit{ptr: v.Pointer(), typ: v.Type()}
+		if v.Kind() == reflect.Slice {
+			list.len = v.Len()
+		}
+	}
+
+	descend, err := fn(path, v)
+	if err != nil || !descend || !v.IsValid() || t.visiting[ptr] || t.visiting[list] {
+		return err
+	}
+	for _, key := range []visit{ptr, list} {
+		if key.typ != nil {
+			t.visiting[key] = true
+			defer delete(t.visiting, key)
+		}
+	}
+
+	return t.walkChildren(v, path, fn)
+}
+
+// walkChildren walks the children of the node v at path.
+func (t *traversal) walkChildren(v reflect.Value, path []string, fn walkFunc) error {
+	child := func(segment string) []string {
+		return append(path[:len(path):len(path)], segment)
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		for _, f := range walkFields(v.Type(), t.opts) {
+			value := fieldByIndex(v, f.Index)
+			if !value.IsValid() {
+				continue
+			}
+			if err := t.walk(value, child(walkFieldName(f, t.opts)), fn); err != nil {
+				return err
+			}
+		}
+		// The fields of the dynamic values of embedded interfaces are
+		// promoted, as by lookup.
+		for _, f := range structFields(v.Type(), t.opts) {
+			if !f.Anonymous || f.PkgPath != "" || f.Type.Kind() != reflect.Interface || t.opts.NoPromotedFields {
+				continue
+			}
+			field := fieldByIndex(v, f.Index)
+			e, err := t.node(field, path)
+			if err != nil {
+				return err
+			}
+			if e.Kind() != reflect.Struct {
+				continue
+			}
+			ptr, ok := t.enterInterface(field)
+			if !ok {
+				continue
+			}
+			err = t.walkChildren(e, path, fn)
+			delete(t.visiting, ptr)
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		names := make([][]string, len(keys))
+		for i, k := range keys {
+			names[i] = []string{keyName(k)}
+		}
+		sort.Sort(byNames{keys, names})
+		for i, k := range keys {
+			if err := t.walk(v.MapIndex(k), child(names[i][0]), fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		if isLeaf(v, path, t.opts) {
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := t.walk(v.Index(i), indexPath(path, i), fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// node returns the value of the node v at path, dereferenced, unwrapped and
+// expanded.
+func (t *traversal) node(v reflect.Value, path []string) (reflect.Value, error) {
+	v, err := unwrapValue(getRealValue(v), t.opts)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if v, err = t.expand(v, nil, path); err != nil {
+		return reflect.Value{}, err
+	}
+	return getRealValue(v), nil
+}
+
+// isLeaf returns whether the node v at path has no children to walk: if it's
+// invalid, a scalar, an array, a []byte, or a struct, a map or a slice
+// without children. The elements of arrays, and of a slice which is itself an
+// element, can't be addressed by a path, so they are leaves too.
+func isLeaf(v reflect.Value, path []string, opts Options) bool {
+	switch v.Kind() {
+	case reflect.Struct:
+		return len(walkFields(v.Type(), opts)) == 0
+	case reflect.Map:
+		return v.Len() == 0
+	case reflect.Slice:
+		indexed := len(path) > 0 && strings.HasSuffix(path[len(path)-1], indexCloseChar)
+		return v.Len() == 0 || v.Type().Elem().Kind() == reflect.Uint8 || indexed
+	}
+	return true
+}
+
+// walkFields returns the exported fields of the struct type ty walked by
+// walk. Embedded structs are only walked through their promoted fields,
+// unless opts.NoPromotedFields is set.
+func walkFields(ty reflect.Type, opts Options) []reflect.StructField {
+	var fields []reflect.StructField
+	for _, f := range structFields(ty, opts) {
+		if f.PkgPath != "" || (f.Anonymous && !opts.NoPromotedFields) {
+			continue
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// walkFieldName returns the name of f in the paths of walk: its name for
+// opts.TagKey, if any, or its Go name.
+func walkFieldName(f reflect.StructField, opts Options) string {
+	if name, ok := tagName(f, opts.TagKey); ok {
+		return name
+	}
+	return f.Name
+}
+
+// keyName returns the map key k as a path segment, quoted if it holds
+// brackets.
+func keyName(k reflect.Value) string {
+	if s, err := toString(k.Interface()); err == nil {
+		return quoteKey(s)
+	}
+	return quoteKey(fmt.Sprint(k.Interface()))
+}
+
+// indexPath returns path with its last segment indexed with i.
+func indexPath(path []string, i int) []string {
+	index := indexOpenChar + strconv.Itoa(i) + indexCloseChar
+	if len(path) == 0 {
+		return []string{index}
+	}
+	return append(path[:len(path)-1:len(path)-1], path[len(path)-1]+index)
+}
+
+// joinSegments joins the segments of path with the split token of opts.
+func joinSegments(path []string, opts Options) string {
+	return strings.Join(path, getSplitToken(&opts))
+}
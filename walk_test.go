@@ -0,0 +1,107 @@
+package lookup
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func (s *S) TestWalk(c *C) {
+	i := map[string]interface{}{
+		"b": []interface{}{1, map[string]interface{}{"c": "x"}},
+		"a": "foo",
+	}
+
+	var paths []string
+	err := Walk(i, func(path string, v reflect.Value) (bool, error) {
+		paths = append(paths, path)
+		return true, nil
+	}, Options{})
+	c.Assert(err, IsNil)
+	c.Assert(paths, DeepEquals, []string{"", "a", "b", "b[0]", "b[1]", "b[1].c"})
+
+	paths = nil
+	err = Walk(i, func(path string, v reflect.Value) (bool, error) {
+		paths = append(paths, path)
+		return path != "b", nil
+	}, Options{})
+	c.Assert(err, IsNil)
+	c.Assert(paths, DeepEquals, []string{"", "a", "b"})
+
+	boom := errors.New("boom")
+	err = Walk(i, func(path string, v reflect.Value) (bool, error) {
+		if path == "b[0]" {
+			return false, boom
+		}
+		return true, nil
+	}, Options{})
+	c.Assert(err, Equals, boom)
+
+	type List []interface{}
+	cyclic := List{nil, 42}
+	cyclic[0] = &cyclic
+	paths = nil
+	err = Walk(&cyclic, func(path string, v reflect.Value) (bool, error) {
+		paths = append(paths, path)
+		return true, nil
+	}, Options{})
+	c.Assert(err, IsNil)
+	c.Assert(paths, DeepEquals, []string{"", "[0]", "[1]"})
+
+	self := map[string]interface{}{"a": 1}
+	self["self"] = self
+	list := []interface{}{nil, "x"}
+	list[0] = list
+	self["list"] = list
+	paths = nil
+	err = Walk(self, func(path string, v reflect.Value) (bool, error) {
+		paths = append(paths, path)
+		return true, nil
+	}, Options{})
+	c.Assert(err, IsNil)
+	c.Assert(paths, DeepEquals, []string{"", "a", "list", "list[0]", "list[1]", "self"})
+
+	flat, err := Flatten(self)
+	c.Assert(err, IsNil)
+	c.Assert(flat["a"], Equals, 1)
+	_, err = Diff(self, self)
+	c.Assert(err, IsNil)
+	_, err = FindPaths(self, func(interface{}) bool { return true })
+	c.Assert(err, IsNil)
+	_, err = Grep(self, "x")
+	c.Assert(err, IsNil)
+	_, nodes, _, _ := Stats(self)
+	c.Assert(nodes, Equals, 6)
+
+	// Every path walked is looked up to the value walked. Arrays are leaves,
+	// since Lookup doesn't index them.
+	type Point struct{ X int }
+	withArrays := struct {
+		Coords [2]int
+		Points [1]Point
+		Slice  []Point
+	}{Coords: [2]int{1, 2}, Points: [1]Point{{X: 3}}, Slice: []Point{{X: 4}}}
+	paths = nil
+	err = Walk(withArrays, func(path string, v reflect.Value) (bool, error) {
+		paths = append(paths, path)
+		if path != "" {
+			value, err := Lookup(withArrays, path)
+			c.Assert(err, IsNil, Commentf("path %q", path))
+			c.Assert(value, DeepEquals, v.Interface(), Commentf("path %q", path))
+		}
+		return true, nil
+	}, Options{})
+	c.Assert(err, IsNil)
+	c.Assert(paths, DeepEquals, []string{"", "Coords", "Points", "Slice", "Slice[0]", "Slice[0].X"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = WalkContext(ctx, i, func(path string, v reflect.Value) (bool, error) {
+		return true, nil
+	}, Options{})
+	c.Assert(status.Code(err), Equals, codes.Canceled)
+}